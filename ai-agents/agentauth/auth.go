@@ -0,0 +1,282 @@
+// Package agentauth is the authentication middleware shared by the agent web servers.
+//
+// A request is authenticated either by the identity headers of an
+// oauth2-proxy style reverse proxy, trusted only when the request comes from
+// a configured proxy address, or by a local API token sent as
+// "Authorization: Bearer <token>".
+package agentauth
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// AnonymousUser is the identity of every request when authentication is disabled
+const AnonymousUser = "anonymous"
+
+// Authentication methods reported in Identity.Method
+const (
+	MethodNone  = "none"
+	MethodProxy = "proxy"
+	MethodToken = "token"
+)
+
+// Config configures the Authenticator.
+type Config struct {
+	// Enabled turns on authentication. When false every request is anonymous.
+	Enabled bool `yaml:"enabled"`
+	// TrustedProxies lists the CIDRs or IPs whose forwarded identity headers are trusted.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// UserHeader and EmailHeader are the headers set by the auth proxy.
+	UserHeader  string `yaml:"user_header"`
+	EmailHeader string `yaml:"email_header"`
+	// TokensFile lists local API tokens, one "<user> <sha256-of-token>" per line.
+	TokensFile string `yaml:"tokens_file"`
+	// RequestsPerMinute is the per-user rate limit. Zero means no limit.
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+}
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	User   string `json:"user"`
+	Email  string `json:"email,omitempty"`
+	Method string `json:"method"`
+}
+
+// DefaultConfig returns a disabled config with the oauth2-proxy header names.
+func DefaultConfig() Config {
+	return Config{
+		UserHeader:  "X-Forwarded-User",
+		EmailHeader: "X-Forwarded-Email",
+	}
+}
+
+// FromEnv reads the config from <prefix>AUTH_* environment variables on top of DefaultConfig.
+func FromEnv(prefix string) (Config, error) {
+	cfg := DefaultConfig()
+	var errs []error
+
+	if v, ok := os.LookupEnv(prefix + "AUTH_ENABLED"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%sAUTH_ENABLED: %q is not a boolean", prefix, v))
+		}
+		cfg.Enabled = b
+	}
+	if v, ok := os.LookupEnv(prefix + "AUTH_TRUSTED_PROXIES"); ok {
+		cfg.TrustedProxies = splitList(v)
+	}
+	if v, ok := os.LookupEnv(prefix + "AUTH_USER_HEADER"); ok {
+		cfg.UserHeader = v
+	}
+	if v, ok := os.LookupEnv(prefix + "AUTH_EMAIL_HEADER"); ok {
+		cfg.EmailHeader = v
+	}
+	if v, ok := os.LookupEnv(prefix + "AUTH_TOKENS_FILE"); ok {
+		cfg.TokensFile = v
+	}
+	if v, ok := os.LookupEnv(prefix + "AUTH_REQUESTS_PER_MINUTE"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%sAUTH_REQUESTS_PER_MINUTE: %q is not a number", prefix, v))
+		}
+		cfg.RequestsPerMinute = n
+	}
+
+	return cfg, errors.Join(errs...)
+}
+
+// Validate reports every problem in the config at once.
+func (c Config) Validate() error {
+	var errs []error
+	if c.Enabled {
+		if len(c.TrustedProxies) == 0 && c.TokensFile == "" {
+			errs = append(errs, errors.New("enabled but neither trusted_proxies nor tokens_file is set"))
+		}
+		if len(c.TrustedProxies) > 0 && c.UserHeader == "" {
+			errs = append(errs, errors.New("user_header is required when trusted_proxies is set"))
+		}
+	}
+	if _, err := parseNetworks(c.TrustedProxies); err != nil {
+		errs = append(errs, err)
+	}
+	if c.TokensFile != "" {
+		if _, err := loadTokens(c.TokensFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.RequestsPerMinute < 0 {
+		errs = append(errs, errors.New("requests_per_minute must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// Authenticator resolves the Identity of incoming requests.
+type Authenticator struct {
+	cfg     Config
+	proxies []*net.IPNet
+	// tokens maps the hex SHA-256 of a token to its user
+	tokens map[string]string
+}
+
+// New creates an Authenticator from a validated config.
+func New(cfg Config) (*Authenticator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	a := &Authenticator{cfg: cfg, tokens: map[string]string{}}
+	a.proxies, _ = parseNetworks(cfg.TrustedProxies)
+	if cfg.TokensFile != "" {
+		a.tokens, _ = loadTokens(cfg.TokensFile)
+	}
+	return a, nil
+}
+
+// Authenticate returns the identity of r, or false if it carries none.
+func (a *Authenticator) Authenticate(r *http.Request) (Identity, bool) {
+	if !a.cfg.Enabled {
+		return Identity{User: AnonymousUser, Method: MethodNone}, true
+	}
+
+	// Forwarded headers, only from a trusted proxy
+	if a.fromTrustedProxy(r) {
+		if user := strings.TrimSpace(r.Header.Get(a.cfg.UserHeader)); user != "" {
+			email := ""
+			if a.cfg.EmailHeader != "" {
+				email = strings.TrimSpace(r.Header.Get(a.cfg.EmailHeader))
+			}
+			return Identity{User: user, Email: email, Method: MethodProxy}, true
+		}
+	}
+
+	// Local API token
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && len(a.tokens) > 0 {
+		sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+		if user, ok := a.tokens[hex.EncodeToString(sum[:])]; ok {
+			return Identity{User: user, Method: MethodToken}, true
+		}
+	}
+
+	return Identity{}, false
+}
+
+// Middleware rejects unauthenticated requests and stores the identity in the request context.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := a.Authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="agents"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), id)))
+	})
+}
+
+func (a *Authenticator) fromTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range a.proxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying id.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity stored by the middleware.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// parseNetworks parses IPs and CIDRs into networks
+func parseNetworks(list []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	var errs []error
+	for _, s := range list {
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				errs = append(errs, fmt.Errorf("trusted_proxies: %q is not an IP or CIDR", s))
+				continue
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("trusted_proxies: %q is not an IP or CIDR", s))
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets, errors.Join(errs...)
+}
+
+// loadTokens reads a tokens file. Blank lines and lines starting with # are ignored.
+func loadTokens(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("tokens_file: %v", err)
+	}
+	defer f.Close()
+
+	tokens := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("tokens_file %s:%d: expected \"<user> <sha256-of-token>\"", path, lineNo)
+		}
+		hash := strings.ToLower(fields[1])
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("tokens_file %s:%d: %q is not a hex SHA-256", path, lineNo, fields[1])
+		}
+		tokens[hash] = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("tokens_file: %v", err)
+	}
+	return tokens, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
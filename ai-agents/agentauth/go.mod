@@ -0,0 +1,5 @@
+module agentauth
+
+go 1.24.4
+
+require golang.org/x/time v0.14.0
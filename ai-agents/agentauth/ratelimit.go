@@ -0,0 +1,71 @@
+package agentauth
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimiter applies a token bucket per user.
+type RateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// NewRateLimiter allows each user requestsPerMinute requests, with bursts of the same size.
+// It returns nil when requestsPerMinute is zero; a nil RateLimiter allows everything.
+func NewRateLimiter(requestsPerMinute int) *RateLimiter {
+	if requestsPerMinute <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		limit:    rate.Limit(float64(requestsPerMinute) / 60),
+		burst:    requestsPerMinute,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// Reserve takes a token for user. It returns zero if the request may proceed,
+// or how long the user has to wait otherwise.
+func (l *RateLimiter) Reserve(user string) time.Duration {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	lim, ok := l.limiters[user]
+	if !ok {
+		lim = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[user] = lim
+	}
+	l.mu.Unlock()
+
+	r := lim.Reserve()
+	delay := r.Delay()
+	if delay > 0 {
+		// Give the token back, the request is rejected
+		r.Cancel()
+	}
+	return delay
+}
+
+// Middleware rejects requests over the caller's limit with 429.
+// It must run after Authenticator.Middleware.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := FromContext(r.Context())
+		if delay := l.Reserve(id.User); delay > 0 {
+			w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(delay.Seconds()))))
+			http.Error(w, "Rate limit exceeded, please try again later", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"james-agent/main/tracker"
+)
+
+// actionItemActions is the allowed action of an ActionItem
+var actionItemActions = []string{"create", "update", "close", "comment"}
+
+// timestampPattern matches an HH:MM:SS source timestamp
+var timestampPattern = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}$`)
+
+// actionsOutPath is where the validated action items are written for audit,
+// unless the run gives its own path
+var actionsOutPath = "action_items.json"
+
+// currentActionsPath is the audit file of the run in progress
+var currentActionsPath string
+
+// RejectedItem is an action item that failed validation
+type RejectedItem struct {
+	Item     ActionItem `json:"item"`
+	Problems []string   `json:"problems"`
+}
+
+// itemValidator checks action items against their repositories, caching the issues it looks up
+type itemValidator struct {
+	// repo is the default repository, repos every repository items may be filed in
+	repo  string
+	repos []string
+
+	mu     sync.Mutex
+	issues map[issueRef]*tracker.Issue
+	errs   map[issueRef]error
+}
+
+// issueRef identifies an issue across repositories
+type issueRef struct {
+	repo   string
+	number int
+}
+
+func newItemValidator(repo string, repos []string) *itemValidator {
+	return &itemValidator{repo: repo, repos: repos, issues: map[issueRef]*tracker.Issue{}, errs: map[issueRef]error{}}
+}
+
+// validateAll splits items into valid ones and rejected ones with their problems
+func (v *itemValidator) validateAll(ctx context.Context, items []ActionItem) ([]ActionItem, []RejectedItem) {
+	var valid []ActionItem
+	var rejected []RejectedItem
+	for _, item := range items {
+		if item.Repo = strings.TrimSpace(item.Repo); item.Repo == "" {
+			item.Repo = v.repo
+		}
+		item.Title = strings.TrimSpace(item.Title)
+		item.Description = strings.TrimSpace(item.Description)
+		for i, ts := range item.SourceTimestamps {
+			item.SourceTimestamps[i] = strings.Trim(strings.TrimSpace(ts), "[]")
+		}
+		if problems := v.validate(ctx, item); len(problems) > 0 {
+			rejected = append(rejected, RejectedItem{Item: item, Problems: problems})
+		} else {
+			valid = append(valid, item)
+		}
+	}
+	return valid, rejected
+}
+
+// validate returns the problems of one item
+func (v *itemValidator) validate(ctx context.Context, item ActionItem) []string {
+	var problems []string
+	if item.Title == "" {
+		problems = append(problems, "title is required")
+	}
+	if item.Description == "" {
+		problems = append(problems, "description is required")
+	}
+	for _, ts := range item.SourceTimestamps {
+		if !timestampPattern.MatchString(ts) {
+			problems = append(problems, fmt.Sprintf("source timestamp %q is not HH:MM:SS", ts))
+		}
+	}
+	if !slices.Contains(v.repos, item.Repo) {
+		return append(problems, fmt.Sprintf("repository %q is not one of %s", item.Repo, strings.Join(v.repos, ", ")))
+	}
+
+	switch item.Action {
+	case "create":
+		if item.IssueNumber != 0 {
+			problems = append(problems, "create must not have an issueNumber; use update or comment for an existing issue")
+		}
+	case "update", "close", "comment":
+		if item.IssueNumber <= 0 {
+			problems = append(problems, fmt.Sprintf("%s needs the issueNumber of an existing issue", item.Action))
+			break
+		}
+		issue, err := v.issue(ctx, item.Repo, item.IssueNumber)
+		switch {
+		case tracker.IsNotFound(err):
+			problems = append(problems, fmt.Sprintf("issue %s#%d does not exist", item.Repo, item.IssueNumber))
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("issue %s#%d could not be checked: %v", item.Repo, item.IssueNumber, err))
+		case issue.PullRequest:
+			problems = append(problems, fmt.Sprintf("%s#%d is a pull request, not an issue", item.Repo, item.IssueNumber))
+		case item.Action == "close" && issue.State == "closed":
+			problems = append(problems, fmt.Sprintf("issue %s#%d is already closed", item.Repo, item.IssueNumber))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("action %q is not one of %s", item.Action, strings.Join(actionItemActions, ", ")))
+	}
+	return problems
+}
+
+// issue looks up an issue once per extraction
+func (v *itemValidator) issue(ctx context.Context, repo string, number int) (*tracker.Issue, error) {
+	ref := issueRef{repo, number}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if issue, ok := v.issues[ref]; ok {
+		return issue, nil
+	}
+	if err, ok := v.errs[ref]; ok {
+		return nil, err
+	}
+	if issueTracker == nil {
+		return nil, errors.New(trackerMissing)
+	}
+
+	issue, err := issueTracker.GetIssue(ctx, repo, number)
+	if err != nil {
+		v.errs[ref] = err
+		return nil, err
+	}
+	v.issues[ref] = issue
+	return issue, nil
+}
+
+// repromptMessage tells the model what was wrong with its items
+func repromptMessage(rejected []RejectedItem) string {
+	var b strings.Builder
+	b.WriteString("Some action items are invalid:\n")
+	for _, r := range rejected {
+		fmt.Fprintf(&b, "- %q: %s\n", r.Item.Title, strings.Join(r.Problems, "; "))
+	}
+	b.WriteString("Fix these items, or drop them if they cannot be fixed from the transcript, and return the complete result again as JSON matching the schema.")
+	return b.String()
+}
+
+// recentOpenIssues lists the open issues of every repository the extraction prompt may refer to
+func recentOpenIssues(ctx context.Context, repos []string) ([]IssueSummary, error) {
+	if issueTracker == nil {
+		return nil, nil
+	}
+	var issues []IssueSummary
+	for _, repo := range repos {
+		list, err := issueTracker.ListIssues(ctx, repo, tracker.ListOptions{State: "open", Max: 100})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list open issues of %s: %v", repo, err)
+		}
+		for _, issue := range list.Issues {
+			issues = append(issues, IssueSummary{Repo: repo, Number: issue.Number, Title: issue.Title})
+		}
+	}
+	return issues, nil
+}
+
+// actionAudit is the audit record of an extraction
+type actionAudit struct {
+	Transcript    string         `json:"transcript"`
+	Repo          string         `json:"repo"`
+	PromptVersion string         `json:"promptVersion"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	Summaries     []ChunkSummary `json:"summaries"`
+	ActionItems   []ActionItem   `json:"actionItems"`
+	Rejected      []RejectedItem `json:"rejected"`
+}
+
+// writeActionAudit writes the final action item list to the run's audit file
+func writeActionAudit(transcriptPath, repo, promptVersion string, ex *MeetingExtraction) error {
+	audit := actionAudit{
+		Transcript:    transcriptPath,
+		Repo:          repo,
+		PromptVersion: promptVersion,
+		CreatedAt:     time.Now(),
+		Summaries:     ex.Summaries,
+		ActionItems:   ex.ActionItems,
+		Rejected:      ex.Rejected,
+	}
+	if audit.ActionItems == nil {
+		audit.ActionItems = []ActionItem{}
+	}
+	if audit.Rejected == nil {
+		audit.Rejected = []RejectedItem{}
+	}
+	data, err := json.MarshalIndent(audit, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal action items: %v", err)
+	}
+	path := currentActionsPath
+	if path == "" {
+		path = actionsOutPath
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write action items: %v", err)
+	}
+	fmt.Printf("Action items written to %s\n", path)
+	return nil
+}
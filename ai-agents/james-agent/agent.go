@@ -1,153 +1,195 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"james-agent/main/mermaid"
+	"james-agent/main/tracker"
+	"james-agent/main/transcript"
+	"promptlib"
 
-	adkagent "google.golang.org/adk/agent"
-	"google.golang.org/adk/agent/llmagent"
-	"google.golang.org/adk/model/gemini"
-	"google.golang.org/adk/runner"
-	"google.golang.org/adk/session"
 	"google.golang.org/adk/tool"
-	"google.golang.org/adk/tool/functiontool"
 	"google.golang.org/genai"
 )
 
 func main() {
 	ctx := context.Background()
 
-	// Check required environment variables
-	if os.Getenv("GOOGLE_API_KEY") == "" {
-		log.Fatalf("GOOGLE_API_KEY environment variable is required")
-	}
-	if os.Getenv("GITHUB_TOKEN") == "" {
-		log.Println("WARNING: GITHUB_TOKEN environment variable not set. GitHub operations will fail.")
+	dryRun := flag.Bool("dry-run", false, "Record the GitHub actions in a plan instead of performing them")
+	planPath := flag.String("plan", "plan.json", "Where -dry-run writes the plan; a Markdown summary is written next to it")
+	applyPath := flag.String("apply", "", "Perform the actions of a reviewed plan file and exit")
+	interactive := flag.Bool("interactive", false, "Ask on the terminal before performing each GitHub action")
+	peoplePath := flag.String("people", "", "People directory mapping speaker names to GitHub logins (default $JAMES_PEOPLE_FILE or people.yaml if present)")
+	flag.StringVar(&actionsOutPath, "actions-out", actionsOutPath, "Where the validated action items are written for audit")
+	meetingURL := flag.String("meeting-url", "", "Link to the meeting recording or notes, referenced in issue updates")
+	meetingDate := flag.String("meeting-date", "", "Date of the meeting (YYYY-MM-DD), defaults to the transcript's modification date")
+	configPath := flag.String("config", "", "Configuration file selecting the issue tracker and MCP servers (default $JAMES_CONFIG or james.yaml if present)")
+	daemonMode := flag.Bool("daemon", false, "Run as a daemon processing transcripts from the watch folder, uploads and webhooks set in the config's daemon section")
+	ledgerPath := flag.String("ledger", envOr("JAMES_LEDGER", ".james-ledger.json"), "Run ledger recording the actions taken per transcript, so re-runs do not repeat them; empty disables it")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage:\n  %s [-dry-run [-plan plan.json] | -interactive] <transcript-file-path> <repository-name>\n  (the repository is optional with a repos catalogue in the config; it is the default for items no repository matches)\n  %s -apply plan.json [-interactive]\n  %s -daemon [-dry-run] [-config james.yaml]\n\nFlags:\n", os.Args[0], os.Args[0], os.Args[0])
+		flag.PrintDefaults()
 	}
+	flag.Parse()
 
-	if len(os.Args) < 3 {
-		log.Fatalf("Usage: %s <transcript-file-path> <repository-name>", os.Args[0])
+	if *dryRun && *interactive {
+		log.Fatalf("-dry-run and -interactive cannot be combined")
 	}
-	transcriptFilePath := os.Args[1]
-	// NEW: Get repository name from command line
-	repoName := os.Args[2]
-
-	// Verify file exists
-	if _, err := os.Stat(transcriptFilePath); os.IsNotExist(err) {
-		log.Fatalf("File not found: %s", transcriptFilePath)
+	if *daemonMode && (*interactive || *applyPath != "") {
+		log.Fatalf("-daemon cannot be combined with -interactive or -apply")
 	}
 
-	// Create model
-	model, err := gemini.NewModel(ctx, "gemini-2.0-flash", &genai.ClientConfig{
-		APIKey: os.Getenv("GOOGLE_API_KEY"),
-	})
+	configFile, configRequired := *configPath, *configPath != ""
+	if !configRequired {
+		configFile = envOr("JAMES_CONFIG", "james.yaml")
+		configRequired = os.Getenv("JAMES_CONFIG") != ""
+	}
+	config, err := loadConfig(configFile, configRequired)
 	if err != nil {
-		log.Fatalf("Failed to create model: %v", err)
+		log.Fatalf("Failed to load config: %v", err)
 	}
-
-	transcriptTool, err := functiontool.New(functiontool.Config{
-		Name:        "GenerateSystemPromptFromTranscript",
-		Description: "Reads a meeting transcript file and generates a system prompt from its contents with GitHub action suggestions",
-	}, GenerateSystemPromptFromTranscript)
+	issueTracker, trackerMissing, err = newIssueTracker(config.Tracker)
 	if err != nil {
-		log.Fatalf("Failed to create GenerateSystemPromptFromTranscript tool: %v", err)
+		log.Fatalf("Failed to configure the issue tracker: %v", err)
+	}
+	if issueTracker == nil {
+		log.Printf("WARNING: %s Issue tracker operations will fail.", trackerMissing)
 	}
 
-	githubActionTool, err := functiontool.New(functiontool.Config{
-		Name:        "GitHubMCPServerAction",
-		Description: "Tool for interacting with GitHub via REST API to create, update, or close issues",
-	}, GitHubMCPServerAction)
+	peopleFile, peopleRequired := *peoplePath, *peoplePath != ""
+	if !peopleRequired {
+		peopleFile = envOr("JAMES_PEOPLE_FILE", "people.yaml")
+		peopleRequired = os.Getenv("JAMES_PEOPLE_FILE") != ""
+	}
+	dir, err := loadPeople(peopleFile, peopleRequired)
 	if err != nil {
-		log.Fatalf("Failed to create GitHubMCPServerAction tool: %v", err)
+		log.Fatalf("Failed to load people directory: %v", err)
 	}
+	people = dir
 
-	githubListTool, err := functiontool.New(functiontool.Config{
-		Name:        "GitHubMCPServerListIssues",
-		Description: "Lists existing GitHub issues for a repository. Use this to check for duplicates before creating new issues.",
-	}, GitHubMCPServerListIssues)
-	if err != nil {
-		log.Fatalf("Failed to create GitHubMCPServerListIssues tool: %v", err)
-	}
-
-	agentInstruction := fmt.Sprintf(`You are a helpful agent that processes meeting transcripts and manages GitHub issues for the **%s** repository.
-1. **Always** start by using **GenerateSystemPromptFromTranscript** to get the meeting content.
-2. **Pre-check for updates**: Analyze the summary from the transcript. If the summary contains mentions of specific, existing tasks, issues, or ticket numbers (e.g., "Issue #12 discussed," "We need to clarify the scope of the dashboard ticket"), or if the discussion is clearly an elaboration on a prior topic, then proceed to step 3. Otherwise, if the points are entirely new, skip to step 5 (create new issues).
-3. **If an update is suspected**: Use **GitHubMCPServerListIssues** to retrieve a list of existing **open** issues in '%s'.
-4. Analyze the transcript summary and the list of existing issues.
-5. **Crucially**: If a key point already corresponds to an open issue (check issue titles/bodies for similarity), use **GitHubMCPServerAction** with the **'update'** action to add more context or a mermaid diagram to the existing issue.
-6. If a key point is entirely new and does not have an open issue, use **GitHubMCPServerAction** with the **'create'** action. Always create issues with a proper description and mermaid diagrams when applicable.
-7. The repository name is always '%s'. Do not ask for confirmation; directly perform the necessary action.`, repoName, repoName, repoName)
-
-	// Create agent
-	agent, err := llmagent.New(llmagent.Config{
-		Name:        "james_agent",
-		Model:       model,
-		Description: "Agent that processes meeting transcripts, generates actionable prompts, and interacts with GitHub MCP server to manage issues based on meeting discussions.",
-		Instruction: agentInstruction,
-		Tools:       []tool.Tool{transcriptTool, githubActionTool, githubListTool},
+	if *ledgerPath != "" {
+		ledger, err = openLedger(*ledgerPath)
+		if err != nil {
+			log.Fatalf("Failed to open ledger: %v", err)
+		}
+	}
+
+	// Applied plans are checked against the same close policy and catalogue as runs
+	if err := config.Minutes.validate(); err != nil {
+		log.Fatalf("Invalid minutes configuration: %v", err)
+	}
+	minutesConfig = config.Minutes
+	closePolicy = config.Close
+	if err := validateCatalogue(config.Repos); err != nil {
+		log.Fatalf("Invalid repository catalogue: %v", err)
+	}
+	repoCatalogue = config.Repos
+
+	// Applying a reviewed plan does not need the model
+	if *applyPath != "" {
+		if err := applyPlan(ctx, *applyPath, *interactive); err != nil {
+			log.Fatalf("Failed to apply plan: %v", err)
+		}
+		fmt.Println("Plan applied!")
+		return
+	}
+
+	// Check required environment variables
+	if os.Getenv("GOOGLE_API_KEY") == "" {
+		log.Fatalf("GOOGLE_API_KEY environment variable is required")
+	}
+
+	// The extraction step calls the model directly for structured output
+	genaiClient, err = genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  os.Getenv("GOOGLE_API_KEY"),
+		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatalf("Failed to create agent: %v", err)
+		log.Fatalf("Failed to create model client: %v", err)
 	}
 
-	// Create session service
-	sessionService := session.InMemoryService()
+	// Load the agent and extraction prompts
+	prompts, err = promptlib.Open(envOr("JAMES_PROMPTS_DIR", "prompts"), promptlib.Options{Dev: os.Getenv("JAMES_PROMPTS_DEV") == "true"})
+	if err != nil {
+		log.Fatalf("Failed to load prompts: %v", err)
+	}
+	if err := prompts.Validate(extractPromptName, ExtractPromptVars{}); err != nil {
+		log.Fatalf("Invalid extraction prompt: %v", err)
+	}
 
-	// Create runner
-	agentRunner, err := runner.New(runner.Config{
-		Agent:          agent,
-		AppName:        "james_agent",
-		SessionService: sessionService,
-	})
+	mode := modeExecute
+	switch {
+	case *dryRun, *daemonMode && config.Daemon.DryRun:
+		mode = modeDryRun
+	case *interactive:
+		mode = modeInteractive
+	}
+	mcpToolsets, err = newMCPToolsets(config.MCPServers, mode)
 	if err != nil {
-		log.Fatalf("Failed to create runner: %v", err)
+		log.Fatalf("Failed to configure MCP servers: %v", err)
 	}
 
-	// Get file path from command line arguments
+	if *daemonMode {
+		if err := runDaemon(ctx, config.Daemon, mode); err != nil {
+			log.Fatalf("Daemon failed: %v", err)
+		}
+		return
+	}
 
-	// Create session
-	userID := "user123"
-	appName := "james_agent"
-	sessResp, err := sessionService.Create(ctx, &session.CreateRequest{
-		AppName: appName,
-		UserID:  userID,
+	// With a catalogue the repository is optional, the first one is the default
+	repo := flag.Arg(1)
+	if repo == "" && len(repoCatalogue) > 0 {
+		repo = repoCatalogue[0].Name
+	}
+	if flag.NArg() < 1 || repo == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	_, err = runTranscript(ctx, RunOptions{
+		Transcript:  flag.Arg(0),
+		Repo:        repo,
+		MeetingURL:  *meetingURL,
+		MeetingDate: *meetingDate,
+		Mode:        mode,
+		PlanPath:    *planPath,
 	})
 	if err != nil {
-		fmt.Printf("Error creating session: %v\n", err)
-		return
+		log.Fatalf("Failed to process transcript: %v", err)
 	}
+}
 
-	// Create message to process the transcript file
-	userMessage := fmt.Sprintf("Please process the meeting transcript from file '%s'. Follow your instructions to check for existing issues before creating any new ones in the %s repository.", transcriptFilePath, repoName)
+// modelName is the Gemini model used by the agent and the extraction step
+const modelName = "gemini-2.0-flash"
 
-	// Run agent
-	msg := &genai.Content{
-		Role: "user",
-		Parts: []*genai.Part{
-			{Text: userMessage},
-		},
-	}
-	events := agentRunner.Run(ctx, userID, sessResp.Session.ID(), msg, adkagent.RunConfig{})
+// targetRepo is the repository given on the command line
+var targetRepo string
 
-	// Process events and display results
-	fmt.Printf("Processing transcript file: %s\n", transcriptFilePath)
-	fmt.Println("Agent is working...")
+// prompts holds the agent and extraction prompts
+var prompts *promptlib.Store
 
-	for _, err := range events {
-		if err != nil {
-			// These are actually normal events, not errors
-			fmt.Printf("Error in event stream: %+v\n", err)
-		}
-	}
+// jamesPromptName is the prompt in the prompt store used as the agent instruction
+const jamesPromptName = "james_agent"
 
-	fmt.Println("Agent processing completed!")
+// JamesPromptVars are the variables available to the james_agent prompt templates
+type JamesPromptVars struct {
+	RepoName string
+	// Repos is the repository catalogue, empty when items all go to RepoName
+	Repos []RepoEntry
+}
 
+// envOr returns the environment variable or a default
+func envOr(name, defaultValue string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return defaultValue
 }
 
 // GenerateSystemPromptFromTranscript tool structs and function
@@ -156,9 +198,12 @@ type GenerateSystemPromptParams struct {
 }
 
 type GenerateSystemPromptResult struct {
-	Status       string `json:"status"`
-	Prompt       string `json:"prompt,omitempty"`
-	ErrorMessage string `json:"errorMessage,omitempty"`
+	Status       string   `json:"status"`
+	Format       string   `json:"format,omitempty"`
+	Speakers     []string `json:"speakers,omitempty"`
+	Chunks       int      `json:"chunks,omitempty"`
+	Prompt       string   `json:"prompt,omitempty"`
+	ErrorMessage string   `json:"errorMessage,omitempty"`
 }
 
 func GenerateSystemPromptFromTranscript(ctx tool.Context, args GenerateSystemPromptParams) GenerateSystemPromptResult {
@@ -170,214 +215,495 @@ func GenerateSystemPromptFromTranscript(ctx tool.Context, args GenerateSystemPro
 			ErrorMessage: fmt.Sprintf("File not found: %s", args.FilePath),
 		}
 	}
-	transcript, err := os.ReadFile(args.FilePath)
+	t, err := transcript.ParseFile(args.FilePath)
 	if err != nil {
 		return GenerateSystemPromptResult{
 			Status:       "error",
 			ErrorMessage: err.Error(),
 		}
 	}
-	prompt := fmt.Sprintf("System prompt generated from meeting transcript:\n%s\nSummarize the key points and suggest relevant GitHub actions (create/update/close issues) based on the discussion.", string(transcript))
+
+	// Long meetings are extracted chunk by chunk, so the whole transcript never has to fit in one prompt
+	chunks := t.Chunks(transcript.ChunkOptions{})
+	extraction, err := extractMeeting(ctx, args.FilePath, targetRepo, t, chunks)
+	if err != nil {
+		return GenerateSystemPromptResult{
+			Status:       "error",
+			ErrorMessage: fmt.Sprintf("Failed to extract action items: %v", err),
+		}
+	}
+
+	citation := "the speaker"
+	if t.Timed {
+		citation = "the speaker and the [HH:MM:SS] timestamps"
+	}
+	var prompt string
+	if len(chunks) == 1 {
+		prompt = fmt.Sprintf("System prompt generated from meeting transcript (%s, %d utterances):\n%s\n%s\nUse the action items above to suggest relevant GitHub actions (create/update/close issues). When an issue is based on something said in the meeting, cite %s.", t.Format, len(t.Utterances), t.Text(), renderExtraction(extraction), citation)
+	} else {
+		prompt = fmt.Sprintf("System prompt generated from a long meeting transcript (%s, %d utterances), processed in %d parts:\n%s\nUse the action items above to suggest relevant GitHub actions (create/update/close issues). When an issue is based on something said in the meeting, cite %s.", t.Format, len(t.Utterances), len(chunks), renderExtraction(extraction), citation)
+	}
+
+	history := describeHistory(previousRun)
+	if history == "" {
+		// Without the ledger, the markers in the tracker tell what earlier runs did
+		marked, err := markedIssues(ctx, currentMeeting, catalogueRepos())
+		if err != nil {
+			fmt.Printf("Could not check for earlier issues: %v\n", err)
+		}
+		history = describeMarkedIssues(marked)
+	}
+	if history != "" {
+		prompt += "\n\n" + history + "Only act on items that are new or changed since then."
+	}
 
 	fmt.Println("Prompt generated: ", prompt)
 
 	return GenerateSystemPromptResult{
-		Status: "success",
-		Prompt: prompt,
+		Status:   "success",
+		Format:   string(t.Format),
+		Speakers: t.Speakers(),
+		Chunks:   len(chunks),
+		Prompt:   prompt,
 	}
 }
 
-// GitHubMCPServerAction tool structs and function (Unchanged, but now used conditionally by LLM)
+// GitHubMCPServerAction tool structs and function
+type GitHubIssueData struct {
+	Repo   string `json:"repo" jsonschema:"The repository (owner/repo), GitLab project path or Jira project key"`
+	Number int    `json:"number,omitempty" jsonschema:"The issue number (for Jira the number of PROJ-123), required for update and close"`
+	Title  string `json:"title,omitempty" jsonschema:"The issue title, required for create"`
+	Body   string `json:"body,omitempty" jsonschema:"The issue body in Markdown. For update, the new notes from this meeting only"`
+	Mode   string `json:"mode,omitempty" jsonschema:"For update: 'comment' (default) adds the notes as a comment linking the meeting; 'append_body' appends a dated Meeting notes section to the description. The original description is always kept."`
+
+	Labels    []string `json:"labels,omitempty" jsonschema:"Existing repository labels to add, e.g. priority labels"`
+	Assignees []string `json:"assignees,omitempty" jsonschema:"Tracker logins or speaker names from the transcript to assign"`
+	Milestone string   `json:"milestone,omitempty" jsonschema:"Milestone title or number"`
+	Type      string   `json:"type,omitempty" jsonschema:"Issue type, e.g. Bug, Feature or Task, if the repository has issue types"`
+
+	Evidence    string `json:"evidence,omitempty" jsonschema:"Required for close: the exact words from the transcript saying the issue is done or dropped. Posted in the closing comment."`
+	StateReason string `json:"stateReason,omitempty" jsonschema:"For close: 'completed' (default) when the work is done, 'not_planned' when it was dropped"`
+}
+
 type GitHubActionParams struct {
-	Action    string                 `json:"action" jsonschema:"The action to perform: create, update, or close"`
-	IssueData map[string]interface{} `json:"issueData" jsonschema:"Issue data containing repo, title, body, number, etc."`
+	Action    string          `json:"action" jsonschema:"The action to perform: create, update, comment, or close"`
+	IssueData GitHubIssueData `json:"issueData" jsonschema:"Issue data containing repo, title, body and number"`
+}
+
+// IssueSummary is the part of an issue returned to the model
+type IssueSummary struct {
+	// Repo is set where issues of several repositories are listed together
+	Repo    string   `json:"repo,omitempty"`
+	Number  int      `json:"number"`
+	Title   string   `json:"title"`
+	State   string   `json:"state,omitempty"`
+	URL     string   `json:"url,omitempty"`
+	Labels  []string `json:"labels,omitempty"`
+	Snippet string   `json:"snippet,omitempty"`
 }
 
 type GitHubActionResult struct {
-	Status       string                 `json:"status"`
-	Result       map[string]interface{} `json:"result,omitempty"`
-	ErrorMessage string                 `json:"errorMessage,omitempty"`
-	Code         int                    `json:"code,omitempty"`
+	Status       string        `json:"status"`
+	Issue        *IssueSummary `json:"issue,omitempty"`
+	Message      string        `json:"message,omitempty"`
+	CommentURL   string        `json:"commentUrl,omitempty"`
+	ErrorMessage string        `json:"errorMessage,omitempty"`
+	Code         int           `json:"code,omitempty"`
 }
 
 func GitHubMCPServerAction(ctx tool.Context, args GitHubActionParams) GitHubActionResult {
-	// Get GitHub token from environment
-	fmt.Println("Getting GitHub token : ")
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		return GitHubActionResult{Status: "error", ErrorMessage: "GitHub token not found in environment variable GITHUB_TOKEN."}
+	// Invalid calls go back to the agent for repair and are not part of the run's actions
+	if err := prepareAction(ctx, &args); err != nil {
+		return GitHubActionResult{Status: "error", ErrorMessage: err.Error()}
+	}
+	result := githubAction(ctx, args)
+	actionLog.record(args, result)
+	return result
+}
+
+// prepareAction validates an action, resolves its names and checks the close
+// policy. It is the one check of the agent's calls and of applied plans.
+func prepareAction(ctx context.Context, args *GitHubActionParams) error {
+	if err := validateAction(*args); err != nil {
+		return err
 	}
-	repo, ok := args.IssueData["repo"].(string)
-	if !ok || repo == "" {
-		return GitHubActionResult{Status: "error", ErrorMessage: "Missing 'repo' in issue_data."}
+	// Resolve names before planning, so a plan holds the exact labels and logins
+	if err := resolveIssueData(ctx, &args.IssueData); err != nil {
+		return err
 	}
+	return checkClosePolicy(ctx, *args)
+}
 
-	var url string
-	var payload map[string]interface{}
-	var method string
+// githubAction plans, confirms or performs a prepared action as the mode says
+func githubAction(ctx tool.Context, args GitHubActionParams) GitHubActionResult {
+	switch actionMode {
+	case modeDryRun:
+		n := actionPlan.add(args)
+		fmt.Printf("Planned action %d: %s\n", n, describeAction(args))
+		return GitHubActionResult{Status: "planned", Message: "Dry run: the action was recorded in the plan for review and not performed. Continue with the remaining actions."}
+	case modeInteractive:
+		if !confirmAction(args) {
+			return GitHubActionResult{Status: "skipped", Message: "The user declined this action. Do not retry it, continue with the remaining actions."}
+		}
+	}
+	return executeAction(ctx, args)
+}
 
+// validateAction checks an action before it is planned or performed
+func validateAction(args GitHubActionParams) error {
+	data := args.IssueData
+	if data.Repo == "" {
+		return fmt.Errorf("Missing 'repo' in issue_data.")
+	}
+	if !catalogued(data.Repo) {
+		return fmt.Errorf("Repository %s is not in the catalogue. Use RouteActionItem, or one of: %s.", data.Repo, catalogueNames())
+	}
 	switch args.Action {
 	case "create":
-		url = fmt.Sprintf("https://api.github.com/repos/%s/issues", repo)
-		method = "POST"
-		payload = map[string]interface{}{
-			"title": getStringFromMap(args.IssueData, "title", "No title"),
-			"body":  getStringFromMap(args.IssueData, "body", ""),
+	case "update", "close", "comment":
+		if data.Number == 0 {
+			return fmt.Errorf("Missing 'number' for %s action.", args.Action)
 		}
-	case "update":
-		issueNumber := getStringFromMap(args.IssueData, "number", "")
-		if issueNumber == "" {
-			return GitHubActionResult{Status: "error", ErrorMessage: "Missing 'number' for update action."}
+		if args.Action == "update" && data.Mode != "" && data.Mode != updateModeComment && data.Mode != updateModeAppendBody {
+			return fmt.Errorf("Unknown update mode %q, use %q or %q.", data.Mode, updateModeComment, updateModeAppendBody)
 		}
-		url = fmt.Sprintf("https://api.github.com/repos/%s/issues/%s", repo, issueNumber)
-		method = "PATCH"
-		payload = make(map[string]interface{})
-		if title, exists := args.IssueData["title"]; exists {
-			payload["title"] = title
+		if args.Action == "comment" && data.Body == "" {
+			return fmt.Errorf("Missing 'body' for comment action.")
 		}
-		if body, exists := args.IssueData["body"]; exists {
-			payload["body"] = body
+		if args.Action == "close" {
+			if err := validateClose(data); err != nil {
+				return err
+			}
 		}
-	case "close":
-		issueNumber := getStringFromMap(args.IssueData, "number", "")
-		if issueNumber == "" {
-			return GitHubActionResult{Status: "error", ErrorMessage: "Missing 'number' for close action."}
+		if args.Action == "update" && data.Title == "" && data.Body == "" && len(data.Labels) == 0 &&
+			len(data.Assignees) == 0 && data.Milestone == "" && data.Type == "" {
+			return fmt.Errorf("Nothing to update: give a new title, notes in 'body', labels, assignees, milestone or type.")
 		}
-		url = fmt.Sprintf("https://api.github.com/repos/%s/issues/%s", repo, issueNumber)
-		method = "PATCH"
-		payload = map[string]interface{}{"state": "closed"}
 	default:
-		return GitHubActionResult{Status: "error", ErrorMessage: fmt.Sprintf("Unknown action: %s", args.Action)}
+		return fmt.Errorf("Unknown action: %s", args.Action)
 	}
-
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return GitHubActionResult{Status: "error", ErrorMessage: fmt.Sprintf("Failed to marshal payload: %v", err)}
+	// A broken diagram renders as an error on the issue, so it is sent back for repair
+	if errs := mermaid.ValidateMarkdown(data.Body); len(errs) > 0 {
+		var b strings.Builder
+		b.WriteString("The mermaid diagrams in 'body' have syntax errors. Fix them, or remove a diagram you cannot fix, and call again:")
+		for i, e := range errs {
+			if i == maxDiagramErrors {
+				fmt.Fprintf(&b, "\n- and %d more", len(errs)-i)
+				break
+			}
+			fmt.Fprintf(&b, "\n- %v", e)
+		}
+		return fmt.Errorf("%s", b.String())
 	}
+	return nil
+}
 
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(payloadBytes))
-	if err != nil {
-		return GitHubActionResult{Status: "error", ErrorMessage: fmt.Sprintf("Failed to create request: %v", err)}
-	}
+// maxDiagramErrors bounds the diagram errors returned to the agent at once
+const maxDiagramErrors = 10
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Content-Type", "application/json")
+// executeAction performs a validated action on the issue tracker, unless an earlier run
+// on the same transcript already did it
+func executeAction(ctx context.Context, args GitHubActionParams) GitHubActionResult {
+	if issueTracker == nil {
+		return GitHubActionResult{Status: "error", ErrorMessage: trackerMissing}
+	}
+	key := actionKey(args)
+	if result, done := reconcileAction(ctx, args, key); done {
+		fmt.Printf("Skipping %s: %s\n", describeAction(args), result.Message)
+		return result
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return GitHubActionResult{Status: "error", ErrorMessage: fmt.Sprintf("Request failed: %v", err)}
+	result := performAction(ctx, args, key)
+	if result.Status == "success" {
+		recordAction(args, key, result)
 	}
-	defer resp.Body.Close()
+	return result
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return GitHubActionResult{Status: "error", ErrorMessage: fmt.Sprintf("Failed to read response: %v", err)}
+// reconcileAction reports an action that was already performed, found in the
+// ledger or by the marker james-agent leaves in the tracker
+func reconcileAction(ctx context.Context, args GitHubActionParams, key string) (GitHubActionResult, bool) {
+	data := args.IssueData
+	if e, ok := ledger.find(currentMeeting.Hash, key); ok {
+		return GitHubActionResult{
+			Status:  "already_done",
+			Issue:   &IssueSummary{Number: e.Number, Title: e.Title, URL: e.URL},
+			Message: fmt.Sprintf("This %s was already performed on %s for this transcript. Do not repeat it, continue with the remaining actions.", args.Action, e.At.Format("2006-01-02 15:04")),
+		}, true
 	}
 
-	if resp.StatusCode == 200 || resp.StatusCode == 201 {
-		var result map[string]interface{}
-		if err := json.Unmarshal(body, &result); err != nil {
-			return GitHubActionResult{Status: "error", ErrorMessage: fmt.Sprintf("Failed to parse response: %v", err)}
+	switch args.Action {
+	case "create":
+		existing, err := reconcileCreate(ctx, currentMeeting, data, key)
+		if err != nil {
+			// Searching is best effort; the ledger still guards the common case
+			fmt.Printf("Could not check for earlier issues: %v\n", err)
+			return GitHubActionResult{}, false
 		}
-		return GitHubActionResult{Status: "success", Result: result}
-	} else {
-		return GitHubActionResult{Status: "error", ErrorMessage: string(body), Code: resp.StatusCode}
+		if existing != nil {
+			recordAction(args, key, GitHubActionResult{Issue: existing})
+			return GitHubActionResult{
+				Status:  "exists",
+				Issue:   existing,
+				Message: fmt.Sprintf("Issue #%d was already created from this transcript. Use it instead of creating a new one.", existing.Number),
+			}, true
+		}
+	case "update", "comment":
+		if data.Body == "" {
+			return GitHubActionResult{}, false
+		}
+		found, err := issueHasMarker(ctx, data.Repo, data.Number, currentMeeting, key)
+		if err != nil || !found {
+			return GitHubActionResult{}, false
+		}
+		recordAction(args, key, GitHubActionResult{})
+		return GitHubActionResult{
+			Status:  "already_done",
+			Message: fmt.Sprintf("These meeting notes were already added to #%d. Do not repeat them, continue with the remaining actions.", data.Number),
+		}, true
+	case "close":
+		issue, err := issueTracker.GetIssue(ctx, data.Repo, data.Number)
+		if err != nil || issue.State != "closed" {
+			return GitHubActionResult{}, false
+		}
+		return GitHubActionResult{
+			Status:  "already_done",
+			Issue:   summarizeIssue(issue),
+			Message: fmt.Sprintf("Issue #%d is already closed.", data.Number),
+		}, true
 	}
+	return GitHubActionResult{}, false
 }
 
-// NEW: GitHubMCPServerListIssues tool structs and function
-type GitHubListIssuesParams struct {
-	Repo  string `json:"repo" jsonschema:"The repository name (e.g., owner/repo)"`
-	State string `json:"state,omitempty" jsonschema:"The state of the issues (open, closed, or all). Use 'open' to check for duplicates."`
-}
+// performAction makes the issue tracker calls of an action. key is written into the hidden marker.
+func performAction(ctx context.Context, args GitHubActionParams, key string) GitHubActionResult {
+	data := args.IssueData
 
-func GitHubMCPServerListIssues(ctx tool.Context, args GitHubListIssuesParams) GitHubActionResult {
-	fmt.Println("Getting GitHub token for listing issues: ")
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		return GitHubActionResult{
-			Status:       "error",
-			ErrorMessage: "GitHub token not found in environment variable GITHUB_TOKEN.",
+	var issue *tracker.Issue
+	var err error
+	switch args.Action {
+	case "create":
+		title := data.Title
+		if title == "" {
+			title = "No title"
+		}
+		req := tracker.IssueRequest{
+			Title: tracker.String(title),
+			// The marker lets later runs find this issue even without the ledger
+			Body: tracker.String(withMarker(data.Body, currentMeeting, key)),
+		}
+		if len(data.Labels) > 0 {
+			req.Labels = &data.Labels
+		}
+		if len(data.Assignees) > 0 {
+			req.Assignees = &data.Assignees
 		}
+		setMilestoneAndType(&req, data)
+		issue, err = issueTracker.CreateIssue(ctx, data.Repo, req)
+	case "update":
+		return updateIssue(ctx, data, key)
+	case "comment":
+		// A comment only adds the meeting notes, never changes the issue
+		return updateIssue(ctx, GitHubIssueData{Repo: data.Repo, Number: data.Number, Body: data.Body, Mode: updateModeComment}, key)
+	case "close":
+		// The closing comment carries the evidence, so it is written first
+		return closeIssue(ctx, data, key)
 	}
-
-	state := args.State
-	if state == "" {
-		state = "open"
+	if err != nil {
+		msg, code := trackerError(err)
+		return GitHubActionResult{Status: "error", ErrorMessage: msg, Code: code}
 	}
+	invalidateIssueIndex(data.Repo)
 
-	// Build the URL for listing issues
-	url := fmt.Sprintf("https://api.github.com/repos/%s/issues?state=%s", args.Repo, state)
+	return GitHubActionResult{Status: "success", Issue: summarizeIssue(issue)}
+}
 
-	// Create HTTP GET request
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return GitHubActionResult{Status: "error", ErrorMessage: fmt.Sprintf("Failed to create request: %v", err)}
+// updateIssue adds meeting notes to an issue without losing its description,
+// as a comment or as a section appended to the body
+func updateIssue(ctx context.Context, data GitHubIssueData, key string) GitHubActionResult {
+	req := tracker.IssueRequest{}
+	if data.Title != "" {
+		req.Title = tracker.String(data.Title)
+	}
+	setMilestoneAndType(&req, data)
+
+	var comment *tracker.Comment
+	var err error
+	if data.Mode == updateModeAppendBody {
+		if data.Body != "" {
+			// Re-read the description right before editing so nothing is lost
+			current, err := issueTracker.GetIssue(ctx, data.Repo, data.Number)
+			if err != nil {
+				msg, code := trackerError(err)
+				return GitHubActionResult{Status: "error", ErrorMessage: msg, Code: code}
+			}
+			req.Body = tracker.String(appendMeetingNotes(current.Body, currentMeeting, withMarker(data.Body, currentMeeting, key)))
+		}
+	} else if data.Body != "" {
+		comment, err = issueTracker.AddComment(ctx, data.Repo, data.Number, meetingComment(currentMeeting, withMarker(data.Body, currentMeeting, key)))
+		if err != nil {
+			msg, code := trackerError(err)
+			return GitHubActionResult{Status: "error", ErrorMessage: msg, Code: code}
+		}
 	}
 
-	// Set headers
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Set("Accept", "application/vnd.github+json")
+	// Labels and assignees are added to the existing ones
+	if len(data.Labels) > 0 {
+		if err := issueTracker.AddLabels(ctx, data.Repo, data.Number, data.Labels); err != nil {
+			msg, code := trackerError(err)
+			return GitHubActionResult{Status: "error", ErrorMessage: msg, Code: code}
+		}
+	}
+	if len(data.Assignees) > 0 {
+		if err := issueTracker.AddAssignees(ctx, data.Repo, data.Number, data.Assignees); err != nil {
+			msg, code := trackerError(err)
+			return GitHubActionResult{Status: "error", ErrorMessage: msg, Code: code}
+		}
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	var issue *tracker.Issue
+	if req.Title != nil || req.Body != nil || req.Milestone != nil || req.Type != nil {
+		issue, err = issueTracker.UpdateIssue(ctx, data.Repo, data.Number, req)
+	} else {
+		issue, err = issueTracker.GetIssue(ctx, data.Repo, data.Number)
+	}
 	if err != nil {
-		return GitHubActionResult{Status: "error", ErrorMessage: fmt.Sprintf("Request failed: %v", err)}
+		msg, code := trackerError(err)
+		return GitHubActionResult{Status: "error", ErrorMessage: msg, Code: code}
 	}
-	defer resp.Body.Close()
+	invalidateIssueIndex(data.Repo)
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return GitHubActionResult{Status: "error", ErrorMessage: fmt.Sprintf("Failed to read response body: %v", err)}
+	result := GitHubActionResult{Status: "success", Issue: summarizeIssue(issue)}
+	if comment != nil {
+		result.CommentURL = comment.URL
 	}
+	return result
+}
 
-	// Check response status
-	if resp.StatusCode == 200 {
-		var issues []map[string]interface{}
-		if err := json.Unmarshal(body, &issues); err != nil {
-			return GitHubActionResult{Status: "error", ErrorMessage: fmt.Sprintf("Failed to parse response body: %v", err)}
-		}
+// setMilestoneAndType copies the resolved milestone number and issue type into req
+func setMilestoneAndType(req *tracker.IssueRequest, data GitHubIssueData) {
+	if n, err := strconv.Atoi(data.Milestone); err == nil {
+		req.Milestone = &n
+	}
+	if data.Type != "" {
+		req.Type = tracker.String(data.Type)
+	}
+}
 
-		// Prepare lightweight issue list with only title and number
-		var lightweightIssues []map[string]interface{}
+// GitHubMCPServerListIssues tool structs and function
+type GitHubListIssuesParams struct {
+	Repo        string   `json:"repo" jsonschema:"The repository name (e.g., owner/repo)"`
+	State       string   `json:"state,omitempty" jsonschema:"The state of the issues (open, closed, or all). Use 'open' to check for duplicates."`
+	Labels      []string `json:"labels,omitempty" jsonschema:"Only issues with all of these labels"`
+	Assignee    string   `json:"assignee,omitempty" jsonschema:"Only issues assigned to this login ('none' for unassigned, '*' for any)"`
+	Milestone   string   `json:"milestone,omitempty" jsonschema:"Only issues in this milestone, its number on GitHub and its title elsewhere ('none' or '*' are also accepted on GitHub)"`
+	Since       string   `json:"since,omitempty" jsonschema:"Only issues updated since this date (YYYY-MM-DD or RFC 3339)"`
+	IncludeBody bool     `json:"includeBody,omitempty" jsonschema:"Include the start of each issue body"`
+	Limit       int      `json:"limit,omitempty" jsonschema:"Maximum number of issues to return (default 200, at most 1000)"`
+}
 
-		for _, issue := range issues {
-			title, titleOk := issue["title"].(string)
-			numberFloat, numberOk := issue["number"].(float64)
+type GitHubListIssuesResult struct {
+	Status       string         `json:"status"`
+	Message      string         `json:"message,omitempty"`
+	Issues       []IssueSummary `json:"issues,omitempty"`
+	Truncated    bool           `json:"truncated,omitempty"`
+	ErrorMessage string         `json:"errorMessage,omitempty"`
+	Code         int            `json:"code,omitempty"`
+}
 
-			if titleOk && numberOk {
-				lightweightIssues = append(lightweightIssues, map[string]interface{}{
-					"title":  title,
-					"number": int(numberFloat),
-				})
-			}
+// Limits of GitHubMCPServerListIssues, to keep the result within the model's context
+const (
+	defaultListLimit = 200
+	maxListLimit     = 1000
+	snippetLength    = 300
+)
+
+func GitHubMCPServerListIssues(ctx tool.Context, args GitHubListIssuesParams) GitHubListIssuesResult {
+	if issueTracker == nil {
+		return GitHubListIssuesResult{
+			Status:       "error",
+			ErrorMessage: trackerMissing,
 		}
+	}
 
-		return GitHubActionResult{
-			Status: "success",
-			Result: map[string]interface{}{
-				"message": "List of open issue titles and numbers for duplicate checking:",
-				"issues":  lightweightIssues,
-			},
+	opts := tracker.ListOptions{
+		State:     args.State,
+		Labels:    args.Labels,
+		Assignee:  args.Assignee,
+		Milestone: args.Milestone,
+		Max:       args.Limit,
+	}
+	if opts.Max <= 0 {
+		opts.Max = defaultListLimit
+	}
+	if opts.Max > maxListLimit {
+		opts.Max = maxListLimit
+	}
+	if args.Since != "" {
+		since, err := parseSince(args.Since)
+		if err != nil {
+			return GitHubListIssuesResult{Status: "error", ErrorMessage: err.Error()}
 		}
-	} else {
-		return GitHubActionResult{
-			Status:       "error",
-			ErrorMessage: string(body),
-			Code:         resp.StatusCode,
+		opts.Since = since
+	}
+
+	list, err := issueTracker.ListIssues(ctx, args.Repo, opts)
+	if err != nil {
+		msg, code := trackerError(err)
+		return GitHubListIssuesResult{Status: "error", ErrorMessage: msg, Code: code}
+	}
+
+	// Prepare lightweight issue list, pull requests are already excluded
+	summaries := []IssueSummary{}
+	for i := range list.Issues {
+		issue := &list.Issues[i]
+		summary := IssueSummary{Number: issue.Number, Title: issue.Title}
+		summary.Labels = issue.Labels
+		if args.IncludeBody {
+			summary.Snippet = snippet(issue.Body, snippetLength)
 		}
+		summaries = append(summaries, summary)
+	}
+
+	message := fmt.Sprintf("%d issues for duplicate checking:", len(summaries))
+	if list.Truncated {
+		message = fmt.Sprintf("Only the %d most recently created matching issues are listed, more exist. Narrow the search with labels, assignee, milestone or since if the issue you are looking for is missing.", len(summaries))
+	}
+	return GitHubListIssuesResult{
+		Status:    "success",
+		Message:   message,
+		Issues:    summaries,
+		Truncated: list.Truncated,
 	}
 }
 
-// Helper function to safely get string values from map (Unchanged)
-func getStringFromMap(m map[string]interface{}, key, defaultValue string) string {
-	if val, ok := m[key]; ok {
-		if str, ok := val.(string); ok {
-			return str
-		}
+// parseSince accepts a date or an RFC 3339 timestamp
+func parseSince(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
 	}
-	return defaultValue
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid 'since' %q, use YYYY-MM-DD or RFC 3339", s)
+}
+
+// snippet returns the first n runes of s on one line
+func snippet(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
+// summarizeIssue keeps the fields the model needs
+func summarizeIssue(issue *tracker.Issue) *IssueSummary {
+	return &IssueSummary{Number: issue.Number, Title: issue.Title, State: issue.State, URL: issue.URL}
+}
+
+// trackerError returns the message and HTTP status code of an issue tracker error
+func trackerError(err error) (string, int) {
+	return err.Error(), tracker.StatusCode(err)
 }
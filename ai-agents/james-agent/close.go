@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"james-agent/main/tracker"
+	"james-agent/main/transcript"
+)
+
+// ClosePolicy limits which issues the close action may close
+type ClosePolicy struct {
+	// Disabled forbids every close; the agent can still comment
+	Disabled bool `yaml:"disabled"`
+	// ProtectedLabels are labels of issues that are never closed
+	ProtectedLabels []string `yaml:"protected_labels"`
+	// Authors, when set, only allows closing issues opened by these logins.
+	// "attendees" stands for the logins of the meeting's speakers in the people
+	// directory; list james-agent's own login to let it close what it created.
+	Authors []string `yaml:"authors"`
+}
+
+// closePolicy is set from the configuration file
+var closePolicy ClosePolicy
+
+// currentTranscript is the transcript being processed, for checking the
+// evidence of closes. It is nil when a plan's transcript is not available.
+var currentTranscript *transcript.Transcript
+
+// validateClose checks the reason and that the evidence is quoted from the transcript
+func validateClose(data GitHubIssueData) error {
+	if data.StateReason != "" && data.StateReason != tracker.ReasonCompleted && data.StateReason != tracker.ReasonNotPlanned {
+		return fmt.Errorf("Unknown stateReason %q, use %q or %q.", data.StateReason, tracker.ReasonCompleted, tracker.ReasonNotPlanned)
+	}
+	if strings.TrimSpace(data.Evidence) == "" {
+		return fmt.Errorf("Missing 'evidence' for close action: quote the words from the transcript saying the issue is done or dropped.")
+	}
+	if currentTranscript != nil && !quoteFound(data.Evidence, currentTranscript) {
+		return fmt.Errorf("The evidence %q is not in the transcript. Quote the words said in the meeting exactly, or do not close the issue.", data.Evidence)
+	}
+	return nil
+}
+
+// quoteFound reports whether a quote occurs in the transcript, ignoring case,
+// punctuation and spacing, with or without the speaker and timestamps
+func quoteFound(quote string, t *transcript.Transcript) bool {
+	q := normalizeQuote(quote)
+	if q == "" {
+		return false
+	}
+	var spoken strings.Builder
+	for _, u := range t.Utterances {
+		spoken.WriteString(u.Text + " ")
+	}
+	return strings.Contains(normalizeQuote(spoken.String()), q) || strings.Contains(normalizeQuote(t.Text()), q)
+}
+
+func normalizeQuote(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// checkClosePolicy refuses closes the policy forbids. Without a tracker only
+// Disabled is checked; the rest is checked again when a plan is applied.
+func checkClosePolicy(ctx context.Context, args GitHubActionParams) error {
+	if args.Action != "close" {
+		return nil
+	}
+	data := args.IssueData
+	if closePolicy.Disabled {
+		return fmt.Errorf("Closing issues is disabled by the close policy. Comment on #%d with the meeting notes instead.", data.Number)
+	}
+	if issueTracker == nil || (len(closePolicy.ProtectedLabels) == 0 && len(closePolicy.Authors) == 0) {
+		return nil
+	}
+
+	issue, err := issueTracker.GetIssue(ctx, data.Repo, data.Number)
+	if err != nil {
+		msg, _ := trackerError(err)
+		return fmt.Errorf("failed to check the close policy: %s", msg)
+	}
+	for _, l := range issue.Labels {
+		if slices.ContainsFunc(closePolicy.ProtectedLabels, func(p string) bool { return strings.EqualFold(p, l) }) {
+			return fmt.Errorf("Issue #%d has the protected label %q and must not be closed. Comment on it instead.", data.Number, l)
+		}
+	}
+	if len(closePolicy.Authors) > 0 && !closeAuthorAllowed(issue) {
+		return fmt.Errorf("Issue #%d was opened by %s, whose issues the close policy does not allow closing. Comment on it instead.", data.Number, issue.Author)
+	}
+	return nil
+}
+
+// closeAuthorAllowed reports whether the issue was opened by an allowed author
+func closeAuthorAllowed(issue *tracker.Issue) bool {
+	for _, a := range closePolicy.Authors {
+		if a == "attendees" {
+			if currentTranscript == nil {
+				continue
+			}
+			for _, speaker := range currentTranscript.Speakers() {
+				if login, ok := people.lookup(speaker); ok && strings.EqualFold(login, issue.Author) {
+					return true
+				}
+			}
+		} else if strings.EqualFold(a, issue.Author) {
+			return true
+		}
+	}
+	return false
+}
+
+// closingComment explains a close with the evidence from the meeting
+func closingComment(m Meeting, data GitHubIssueData) string {
+	reason := "completed"
+	if data.StateReason == tracker.ReasonNotPlanned {
+		reason = "not planned"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Closed as %s in the meeting of %s** (from %s)\n\n", reason, m.Date, m.reference())
+	for _, line := range strings.Split(strings.TrimSpace(data.Evidence), "\n") {
+		b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+	}
+	if notes := strings.TrimSpace(data.Body); notes != "" {
+		b.WriteString("\n" + notes + "\n")
+	}
+	return b.String()
+}
+
+// closeIssue posts the closing comment, unless an earlier attempt already did,
+// then closes the issue with its reason
+func closeIssue(ctx context.Context, data GitHubIssueData, key string) GitHubActionResult {
+	var commentURL string
+	found, err := issueHasMarker(ctx, data.Repo, data.Number, currentMeeting, key)
+	if err != nil {
+		msg, code := trackerError(err)
+		return GitHubActionResult{Status: "error", ErrorMessage: msg, Code: code}
+	}
+	if !found {
+		comment, err := issueTracker.AddComment(ctx, data.Repo, data.Number, withMarker(closingComment(currentMeeting, data), currentMeeting, key))
+		if err != nil {
+			msg, code := trackerError(err)
+			return GitHubActionResult{Status: "error", ErrorMessage: msg, Code: code}
+		}
+		commentURL = comment.URL
+	}
+
+	reason := data.StateReason
+	if reason == "" {
+		reason = tracker.ReasonCompleted
+	}
+	issue, err := issueTracker.CloseIssue(ctx, data.Repo, data.Number, reason)
+	if err != nil {
+		msg, code := trackerError(err)
+		return GitHubActionResult{Status: "error", ErrorMessage: msg, Code: code}
+	}
+	invalidateIssueIndex(data.Repo)
+	return GitHubActionResult{Status: "success", Issue: summarizeIssue(issue), CommentURL: commentURL}
+}
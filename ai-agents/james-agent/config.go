@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"james-agent/main/tracker"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JamesConfig is the optional james-agent configuration file
+type JamesConfig struct {
+	Tracker TrackerConfig `yaml:"tracker"`
+	// MCPServers add the tools of MCP servers to the agent
+	MCPServers []MCPServerConfig `yaml:"mcp_servers"`
+	// Daemon configures -daemon
+	Daemon DaemonConfig `yaml:"daemon"`
+	// Minutes configures where the meeting minutes go
+	Minutes MinutesConfig `yaml:"minutes"`
+	// Repos is the catalogue of repositories action items are routed to
+	Repos []RepoEntry `yaml:"repos"`
+	// Close limits which issues the agent may close
+	Close ClosePolicy `yaml:"close"`
+}
+
+// TrackerConfig selects the issue tracker. The token is read from the environment.
+type TrackerConfig struct {
+	tracker.Config `yaml:",inline"`
+	// TokenEnv names the variable holding the token, by default GITHUB_TOKEN,
+	// GITLAB_TOKEN, GITEA_TOKEN or JIRA_API_TOKEN
+	TokenEnv string `yaml:"token_env"`
+}
+
+// defaultTokenEnv is the token variable of each tracker kind
+var defaultTokenEnv = map[string]string{
+	tracker.KindGitHub: "GITHUB_TOKEN",
+	tracker.KindGitLab: "GITLAB_TOKEN",
+	tracker.KindGitea:  "GITEA_TOKEN",
+	tracker.KindJira:   "JIRA_API_TOKEN",
+}
+
+var (
+	// issueTracker is shared by the issue tools, nil when no token is set
+	issueTracker tracker.IssueTracker
+	// trackerMissing explains why issueTracker is nil
+	trackerMissing string
+)
+
+// loadConfig reads a configuration file. A missing file is only an error when required.
+func loadConfig(path string, required bool) (*JamesConfig, error) {
+	cfg := &JamesConfig{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !required {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	// Unknown keys are errors, so a misspelled setting is not silently ignored
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// newIssueTracker connects to the configured tracker. Without a token it
+// returns nil and the reason, so commands that do not need it still work.
+func newIssueTracker(cfg TrackerConfig) (tracker.IssueTracker, string, error) {
+	if cfg.Kind == "" {
+		cfg.Kind = tracker.KindGitHub
+	}
+	env, ok := defaultTokenEnv[cfg.Kind]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown tracker kind %q, use github, gitlab, gitea or jira", cfg.Kind)
+	}
+	if cfg.TokenEnv != "" {
+		env = cfg.TokenEnv
+	}
+	// GITHUB_API_URL keeps working for GitHub Enterprise without a config file
+	if cfg.Kind == tracker.KindGitHub && cfg.URL == "" {
+		cfg.URL = os.Getenv("GITHUB_API_URL")
+	}
+
+	cfg.Token = os.Getenv(env)
+	if cfg.Token == "" {
+		return nil, fmt.Sprintf("%s token not found in environment variable %s.", cfg.Kind, env), nil
+	}
+	tr, err := tracker.New(cfg.Config)
+	if err != nil {
+		return nil, "", err
+	}
+	return tr, "", nil
+}
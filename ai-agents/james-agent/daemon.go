@@ -0,0 +1,714 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DaemonConfig configures -daemon: a watch folder, an HTTP endpoint for
+// uploads and meeting platform webhooks, and a JSON status API
+type DaemonConfig struct {
+	// Listen is the HTTP address, e.g. 127.0.0.1:8080; empty disables HTTP
+	Listen string `yaml:"listen"`
+	// WatchDir is polled for new transcripts; empty disables watching
+	WatchDir     string        `yaml:"watch_dir"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// WorkDir holds uploaded transcripts, dry run plans and the action item audit of every run
+	WorkDir string `yaml:"work_dir"`
+	// DryRun plans the actions of every run instead of performing them
+	DryRun    bool `yaml:"dry_run"`
+	QueueSize int  `yaml:"queue_size"`
+	// TokenEnv names the variable holding the bearer token of the upload and status API
+	TokenEnv string `yaml:"token_env"`
+	// WebhookSecretEnv names the variable holding the webhook signing secret
+	WebhookSecretEnv string `yaml:"webhook_secret_env"`
+	MaxUploadBytes   int64  `yaml:"max_upload_bytes"`
+	// Repos route transcripts to repositories, first match wins
+	Repos []RepoRoute `yaml:"repos"`
+	// DefaultRepo is used when no route matches; empty rejects such transcripts
+	DefaultRepo string `yaml:"default_repo"`
+}
+
+// RepoRoute sends transcripts to a repository. Match is a case-insensitive
+// glob on the watch subfolder, the file name or the meeting topic.
+type RepoRoute struct {
+	Match string `yaml:"match"`
+	Repo  string `yaml:"repo"`
+}
+
+// Run states
+const (
+	runQueued  = "queued"
+	runRunning = "running"
+	runDone    = "done"
+	runFailed  = "failed"
+)
+
+// Run is one queued transcript and, once processed, its result
+type Run struct {
+	ID          string     `json:"id"`
+	Source      string     `json:"source"`
+	Transcript  string     `json:"transcript"`
+	Repo        string     `json:"repo"`
+	Topic       string     `json:"topic,omitempty"`
+	MeetingURL  string     `json:"meetingUrl,omitempty"`
+	MeetingDate string     `json:"meetingDate,omitempty"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	QueuedAt    time.Time  `json:"queuedAt"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	Result      *RunResult `json:"result,omitempty"`
+}
+
+// maxRunHistory is how many finished runs the status API keeps
+const maxRunHistory = 500
+
+// daemon queues transcripts from every source and processes them one at a time
+type daemon struct {
+	cfg           DaemonConfig
+	mode          string
+	token         string
+	webhookSecret string
+	queue         chan *Run
+
+	mu   sync.Mutex
+	runs []*Run
+	seq  int
+	// watched is the size and modification time of each watched file when last queued or seen
+	watched map[string]fileState
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+	queued  bool
+}
+
+// withDaemonDefaults fills in the unset daemon settings
+func withDaemonDefaults(cfg DaemonConfig) DaemonConfig {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 10 * time.Second
+	}
+	if cfg.WorkDir == "" {
+		cfg.WorkDir = ".james-daemon"
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.TokenEnv == "" {
+		cfg.TokenEnv = "JAMES_DAEMON_TOKEN"
+	}
+	if cfg.WebhookSecretEnv == "" {
+		cfg.WebhookSecretEnv = "JAMES_WEBHOOK_SECRET"
+	}
+	if cfg.MaxUploadBytes <= 0 {
+		cfg.MaxUploadBytes = 10 << 20
+	}
+	return cfg
+}
+
+// runDaemon processes transcripts from the watch folder and the HTTP endpoint until interrupted
+func runDaemon(ctx context.Context, cfg DaemonConfig, mode string) error {
+	cfg = withDaemonDefaults(cfg)
+	if cfg.Listen == "" && cfg.WatchDir == "" {
+		return fmt.Errorf("set daemon.listen, daemon.watch_dir or both in the config")
+	}
+	for _, r := range cfg.Repos {
+		if _, err := path.Match(r.Match, ""); err != nil || r.Match == "" || r.Repo == "" {
+			return fmt.Errorf("invalid route %q -> %q: needs a valid glob and a repo", r.Match, r.Repo)
+		}
+	}
+	// Uploads act on real trackers, never accept them from the network unauthenticated
+	if host, _, _ := net.SplitHostPort(cfg.Listen); cfg.Listen != "" && os.Getenv(cfg.TokenEnv) == "" && !isLoopback(host) {
+		return fmt.Errorf("%s is not set: set it or listen on a loopback address such as 127.0.0.1 instead of %s", cfg.TokenEnv, cfg.Listen)
+	}
+	if err := os.MkdirAll(filepath.Join(cfg.WorkDir, "uploads"), 0755); err != nil {
+		return fmt.Errorf("failed to create work dir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(cfg.WorkDir, "plans"), 0755); err != nil {
+		return fmt.Errorf("failed to create work dir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(cfg.WorkDir, "actions"), 0755); err != nil {
+		return fmt.Errorf("failed to create work dir: %v", err)
+	}
+
+	d := &daemon{
+		cfg:           cfg,
+		mode:          mode,
+		token:         os.Getenv(cfg.TokenEnv),
+		webhookSecret: os.Getenv(cfg.WebhookSecretEnv),
+		queue:         make(chan *Run, cfg.QueueSize),
+		watched:       map[string]fileState{},
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.WatchDir != "" {
+		go d.watch(ctx)
+		fmt.Printf("Watching %s for transcripts every %s\n", cfg.WatchDir, cfg.PollInterval)
+	}
+	var server *http.Server
+	if cfg.Listen != "" {
+		server = &http.Server{Addr: cfg.Listen, Handler: d.routes(), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("HTTP server stopped: %v", err)
+				stop()
+			}
+		}()
+		fmt.Printf("Listening on %s\n", cfg.Listen)
+	}
+
+	// One worker: runs share the package state of the tools
+	for {
+		select {
+		case <-ctx.Done():
+			if server != nil {
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				server.Shutdown(shutdownCtx)
+				cancel()
+			}
+			fmt.Printf("Stopped with %d transcripts still queued\n", len(d.queue))
+			return nil
+		case run := <-d.queue:
+			// A run in progress finishes even when stopping, so its ledger is complete
+			d.process(context.WithoutCancel(ctx), run)
+		}
+	}
+}
+
+// enqueue adds a transcript to the queue, failing when the queue is full
+func (d *daemon) enqueue(run *Run) (*Run, error) {
+	d.mu.Lock()
+	d.seq++
+	run.ID = fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405"), d.seq)
+	run.Status = runQueued
+	run.QueuedAt = time.Now()
+	d.runs = append(d.runs, run)
+	if len(d.runs) > maxRunHistory {
+		d.runs = d.runs[len(d.runs)-maxRunHistory:]
+	}
+	d.mu.Unlock()
+
+	select {
+	case d.queue <- run:
+	default:
+		d.mu.Lock()
+		d.runs = slices.DeleteFunc(d.runs, func(r *Run) bool { return r == run })
+		d.mu.Unlock()
+		return nil, fmt.Errorf("queue is full (%d transcripts)", d.cfg.QueueSize)
+	}
+	fmt.Printf("Queued %s: %s for %s (%s)\n", run.ID, run.Transcript, run.Repo, run.Source)
+	return d.snapshot(run), nil
+}
+
+// process runs the agent on a queued transcript and records the outcome
+func (d *daemon) process(ctx context.Context, run *Run) {
+	d.update(run, func(r *Run) {
+		now := time.Now()
+		r.Status, r.StartedAt = runRunning, &now
+	})
+
+	opts := RunOptions{
+		Transcript:  run.Transcript,
+		Repo:        run.Repo,
+		MeetingURL:  run.MeetingURL,
+		MeetingDate: run.MeetingDate,
+		Mode:        d.mode,
+		PlanPath:    filepath.Join(d.cfg.WorkDir, "plans", run.ID+".json"),
+		ActionsPath: filepath.Join(d.cfg.WorkDir, "actions", run.ID+".json"),
+	}
+	result, err := runTranscript(ctx, opts)
+
+	d.update(run, func(r *Run) {
+		now := time.Now()
+		r.FinishedAt, r.Result, r.Status = &now, result, runDone
+		if err != nil {
+			r.Status, r.Error = runFailed, err.Error()
+		}
+	})
+	if err != nil {
+		fmt.Printf("Run %s failed: %v\n", run.ID, err)
+	}
+}
+
+func (d *daemon) update(run *Run, fn func(*Run)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(run)
+}
+
+// snapshot copies a run so it can be encoded while the worker changes it
+func (d *daemon) snapshot(run *Run) *Run {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c := *run
+	return &c
+}
+
+// route picks the repository for the given names: watch subfolder, file name or topic
+func (d *daemon) route(names ...string) (string, error) {
+	names = slices.DeleteFunc(names, func(name string) bool { return name == "" })
+	for _, r := range d.cfg.Repos {
+		for _, name := range names {
+			if ok, _ := path.Match(strings.ToLower(r.Match), strings.ToLower(name)); ok {
+				return r.Repo, nil
+			}
+		}
+	}
+	if d.cfg.DefaultRepo != "" {
+		return d.cfg.DefaultRepo, nil
+	}
+	return "", fmt.Errorf("no repository route matches %s and there is no default_repo", strings.Join(names, ", "))
+}
+
+// knownRepo reports whether a repository given by a caller is one of the configured ones
+func (d *daemon) knownRepo(repo string) bool {
+	if repo == d.cfg.DefaultRepo {
+		return true
+	}
+	for _, r := range d.cfg.Repos {
+		if r.Repo == repo {
+			return true
+		}
+	}
+	return false
+}
+
+// watch polls the watch folder. A file is queued once its size and
+// modification time are unchanged between two polls, so half-written files wait.
+func (d *daemon) watch(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		d.scan()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *daemon) scan() {
+	err := filepath.WalkDir(d.cfg.WatchDir, func(p string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if p != d.cfg.WatchDir && strings.HasPrefix(name, ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !entry.Type().IsRegular() || isPartialFile(name) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+
+		d.mu.Lock()
+		prev, seen := d.watched[p]
+		stable := seen && prev.size == info.Size() && prev.modTime.Equal(info.ModTime())
+		if stable && prev.queued {
+			d.mu.Unlock()
+			return nil
+		}
+		d.watched[p] = fileState{size: info.Size(), modTime: info.ModTime(), queued: stable}
+		d.mu.Unlock()
+		if !stable {
+			return nil
+		}
+
+		// Transcripts already in the ledger were processed before the daemon started
+		if hash, err := hashFile(p); err == nil && ledger.history(hash) != nil {
+			fmt.Printf("Skipping %s: already processed\n", p)
+			return nil
+		}
+		rel, _ := filepath.Rel(d.cfg.WatchDir, p)
+		folder, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+		if folder == filepath.ToSlash(rel) {
+			folder = ""
+		}
+		repo, err := d.route(folder, name)
+		if err != nil {
+			fmt.Printf("Skipping %s: %v\n", p, err)
+			return nil
+		}
+		if _, err := d.enqueue(&Run{Source: "watch", Transcript: p, Repo: repo}); err != nil {
+			// Try again on the next poll
+			d.mu.Lock()
+			delete(d.watched, p)
+			d.mu.Unlock()
+			fmt.Printf("Could not queue %s: %v\n", p, err)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Failed to scan %s: %v\n", d.cfg.WatchDir, err)
+	}
+}
+
+// isPartialFile matches the temporary names of downloads and editors
+func isPartialFile(name string) bool {
+	for _, suffix := range []string{".tmp", ".part", ".partial", ".crdownload", ".download", "~"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *daemon) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /transcripts", d.authorized(d.handleUpload))
+	mux.HandleFunc("GET /runs", d.authorized(d.handleListRuns))
+	mux.HandleFunc("GET /runs/{id}", d.authorized(d.handleGetRun))
+	mux.HandleFunc("POST /webhooks/generic", d.handleGenericWebhook)
+	mux.HandleFunc("POST /webhooks/zoom", d.handleZoomWebhook)
+	return mux
+}
+
+// authorized requires the bearer token, when one is configured
+func (d *daemon) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(d.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// handleUpload queues a transcript sent as the request body or as the multipart field "file".
+// Query parameters: repo, name (the file name), topic, meeting_url and meeting_date.
+func (d *daemon) handleUpload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, d.cfg.MaxUploadBytes)
+	q := r.URL.Query()
+	name := q.Get("name")
+
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("missing multipart field file: %v", err))
+			return
+		}
+		defer file.Close()
+		body = file
+		if name == "" {
+			name = header.Filename
+		}
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("failed to read transcript: %v", err))
+		return
+	}
+
+	d.queueTranscript(w, &Run{
+		Source:      "upload",
+		Topic:       q.Get("topic"),
+		MeetingURL:  q.Get("meeting_url"),
+		MeetingDate: q.Get("meeting_date"),
+	}, name, q.Get("repo"), data)
+}
+
+// GenericWebhook is the payload of /webhooks/generic, for platforms and
+// scripts that can post the transcript text
+type GenericWebhook struct {
+	Topic      string `json:"topic"`
+	Filename   string `json:"filename"`
+	Transcript string `json:"transcript"`
+	MeetingURL string `json:"meeting_url"`
+	Date       string `json:"date"`
+	Repo       string `json:"repo"`
+}
+
+// handleGenericWebhook accepts a GenericWebhook signed with the webhook secret
+// in X-James-Signature: sha256=<hex HMAC of the body>
+func (d *daemon) handleGenericWebhook(w http.ResponseWriter, r *http.Request) {
+	body, ok := d.readWebhook(w, r)
+	if !ok {
+		return
+	}
+	got, _ := strings.CutPrefix(r.Header.Get("X-James-Signature"), "sha256=")
+	if !hmac.Equal([]byte(got), []byte(hmacHex(d.webhookSecret, body))) {
+		writeError(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+
+	var hook GenericWebhook
+	if err := json.Unmarshal(body, &hook); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+		return
+	}
+	if strings.TrimSpace(hook.Transcript) == "" {
+		writeError(w, http.StatusBadRequest, "missing transcript")
+		return
+	}
+	d.queueTranscript(w, &Run{
+		Source:      "webhook",
+		Topic:       hook.Topic,
+		MeetingURL:  hook.MeetingURL,
+		MeetingDate: hook.Date,
+	}, hook.Filename, hook.Repo, []byte(hook.Transcript))
+}
+
+// zoomWebhook is the part of Zoom's recording.transcript_completed and
+// endpoint.url_validation events james-agent reads
+type zoomWebhook struct {
+	Event   string `json:"event"`
+	Payload struct {
+		PlainToken    string `json:"plainToken"`
+		DownloadToken string `json:"download_token"`
+		Object        struct {
+			Topic          string `json:"topic"`
+			StartTime      string `json:"start_time"`
+			ShareURL       string `json:"share_url"`
+			RecordingFiles []struct {
+				FileType    string `json:"file_type"`
+				DownloadURL string `json:"download_url"`
+			} `json:"recording_files"`
+		} `json:"object"`
+	} `json:"payload"`
+}
+
+// handleZoomWebhook answers Zoom's endpoint validation and downloads the
+// transcript of recording.transcript_completed events. The webhook secret is
+// the secret token of the Zoom app.
+func (d *daemon) handleZoomWebhook(w http.ResponseWriter, r *http.Request) {
+	body, ok := d.readWebhook(w, r)
+	if !ok {
+		return
+	}
+	timestamp := r.Header.Get("X-Zm-Request-Timestamp")
+	want := "v0=" + hmacHex(d.webhookSecret, []byte("v0:"+timestamp+":"+string(body)))
+	if !hmac.Equal([]byte(r.Header.Get("X-Zm-Signature")), []byte(want)) {
+		writeError(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+	if ts, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(ts, 0)).Abs() > 5*time.Minute {
+		writeError(w, http.StatusUnauthorized, "stale request timestamp")
+		return
+	}
+
+	var hook zoomWebhook
+	if err := json.Unmarshal(body, &hook); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+		return
+	}
+	switch hook.Event {
+	case "endpoint.url_validation":
+		writeJSON(w, http.StatusOK, map[string]string{
+			"plainToken":     hook.Payload.PlainToken,
+			"encryptedToken": hmacHex(d.webhookSecret, []byte(hook.Payload.PlainToken)),
+		})
+		return
+	case "recording.transcript_completed":
+	default:
+		// Other events are acknowledged so Zoom does not retry them
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+		return
+	}
+
+	obj := hook.Payload.Object
+	var downloadURL string
+	for _, f := range obj.RecordingFiles {
+		if f.FileType == "TRANSCRIPT" {
+			downloadURL = f.DownloadURL
+		}
+	}
+	if downloadURL == "" {
+		writeError(w, http.StatusBadRequest, "no TRANSCRIPT recording file in the event")
+		return
+	}
+	data, err := d.downloadZoom(r.Context(), downloadURL, hook.Payload.DownloadToken)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	date := ""
+	if t, err := time.Parse(time.RFC3339, obj.StartTime); err == nil {
+		date = t.Format("2006-01-02")
+	}
+	d.queueTranscript(w, &Run{
+		Source:      "zoom",
+		Topic:       obj.Topic,
+		MeetingURL:  obj.ShareURL,
+		MeetingDate: date,
+	}, obj.Topic+".vtt", "", data)
+}
+
+// downloadZoom fetches a transcript file, only from Zoom over HTTPS
+func (d *daemon) downloadZoom(ctx context.Context, rawURL, token string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || (u.Hostname() != "zoom.us" && !strings.HasSuffix(u.Hostname(), ".zoom.us")) {
+		return nil, fmt.Errorf("refusing to download the transcript from %q", rawURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the transcript: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download the transcript: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, d.cfg.MaxUploadBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the transcript: %v", err)
+	}
+	if int64(len(data)) > d.cfg.MaxUploadBytes {
+		return nil, fmt.Errorf("transcript is larger than %d bytes", d.cfg.MaxUploadBytes)
+	}
+	return data, nil
+}
+
+// readWebhook reads a webhook body; webhooks are disabled without a secret
+func (d *daemon) readWebhook(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if d.webhookSecret == "" {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("webhooks are disabled, set %s", d.cfg.WebhookSecretEnv))
+		return nil, false
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, d.cfg.MaxUploadBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("failed to read body: %v", err))
+		return nil, false
+	}
+	return body, true
+}
+
+// unsafeName matches the characters not kept in stored file names
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// queueTranscript stores a received transcript in the work dir and queues it.
+// repo, when given, must be one of the configured repositories.
+func (d *daemon) queueTranscript(w http.ResponseWriter, run *Run, name, repo string, data []byte) {
+	if len(data) == 0 {
+		writeError(w, http.StatusBadRequest, "empty transcript")
+		return
+	}
+	if repo != "" && !d.knownRepo(repo) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("repository %s is not in the daemon configuration", repo))
+		return
+	}
+	if repo == "" {
+		var err error
+		if repo, err = d.route(name, run.Topic); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	run.Repo = repo
+
+	// The file name keeps its extension, which helps detecting the transcript format
+	name = strings.Trim(unsafeName.ReplaceAllString(filepath.Base(name), "_"), "._")
+	if name == "" {
+		name = "transcript.txt"
+	}
+	path := filepath.Join(d.cfg.WorkDir, "uploads", fmt.Sprintf("%d-%s", time.Now().UnixNano(), name))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to store transcript: %v", err))
+		return
+	}
+	run.Transcript = path
+
+	queued, err := d.enqueue(run)
+	if err != nil {
+		os.Remove(path)
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, queued)
+}
+
+// handleListRuns lists runs newest first, optionally filtered by ?status=
+func (d *daemon) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	d.mu.Lock()
+	runs := []Run{}
+	for _, run := range d.runs {
+		if status == "" || run.Status == status {
+			runs = append(runs, *run)
+		}
+	}
+	d.mu.Unlock()
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].QueuedAt.After(runs[j].QueuedAt) })
+	writeJSON(w, http.StatusOK, map[string]any{"queued": len(d.queue), "runs": runs})
+}
+
+func (d *daemon) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, run := range d.runs {
+		if run.ID == id {
+			writeJSON(w, http.StatusOK, run)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "no run "+id)
+}
+
+func hmacHex(secret string, data []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"james-agent/main/similarity"
+	"james-agent/main/transcript"
+
+	"google.golang.org/genai"
+)
+
+// extractPromptName is the prompt used to extract action items from one chunk
+const extractPromptName = "james_extract"
+
+// Extraction limits
+const (
+	// extractWorkers is the number of chunks extracted concurrently
+	extractWorkers = 4
+	// duplicateThreshold is the similarity above which two action items are merged
+	duplicateThreshold = 0.6
+	// maxExtractAttempts bounds the re-prompts for invalid output
+	maxExtractAttempts = 3
+)
+
+// ExtractPromptVars are the variables available to the james_extract prompt templates
+type ExtractPromptVars struct {
+	ChunkNumber int
+	ChunkCount  int
+	Span        string
+	Speakers    []string
+	// Repos are the repositories an item may be filed in, the default first
+	Repos []string
+	// OpenIssues are recent open issues of every repository the items may refer to
+	OpenIssues []IssueSummary
+	Transcript string
+}
+
+// ActionItem is a task agreed in the meeting and what to do about it on GitHub
+type ActionItem struct {
+	// Action is one of create, update, close or comment
+	Action string `json:"action"`
+	// Repo is the repository of the issue, one of the catalogue
+	Repo string `json:"repo"`
+	// IssueNumber is the existing issue an update, close or comment refers to
+	IssueNumber int    `json:"issueNumber,omitempty"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Owner       string `json:"owner,omitempty"`
+	DueDate     string `json:"dueDate,omitempty"`
+	// SourceTimestamps are the [HH:MM:SS] offsets the item was discussed at
+	SourceTimestamps []string `json:"sourceTimestamps,omitempty"`
+}
+
+// ChunkSummary summarises one part of the meeting
+type ChunkSummary struct {
+	Span    string `json:"span,omitempty"`
+	Summary string `json:"summary"`
+}
+
+// chunkExtraction is the structured output of one chunk
+type chunkExtraction struct {
+	Summary       string       `json:"summary"`
+	ActionItems   []ActionItem `json:"actionItems"`
+	Decisions     []string     `json:"decisions,omitempty"`
+	OpenQuestions []string     `json:"openQuestions,omitempty"`
+
+	rejected []RejectedItem
+}
+
+// MeetingExtraction is the merged result over all chunks
+type MeetingExtraction struct {
+	Summaries   []ChunkSummary `json:"summaries"`
+	ActionItems []ActionItem   `json:"actionItems"`
+	// Attendees are the speakers of the transcript
+	Attendees     []string `json:"attendees,omitempty"`
+	Decisions     []string `json:"decisions,omitempty"`
+	OpenQuestions []string `json:"openQuestions,omitempty"`
+	// Rejected items were still invalid after re-prompting
+	Rejected []RejectedItem `json:"rejected,omitempty"`
+}
+
+// chunkExtractionSchema constrains the model output to chunkExtraction, with
+// items in one of repos
+func chunkExtractionSchema(repos []string) *genai.Schema {
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"summary": {Type: genai.TypeString, Description: "Short summary of this part of the meeting"},
+			"decisions": {
+				Type:        genai.TypeArray,
+				Items:       &genai.Schema{Type: genai.TypeString},
+				Description: "Decisions taken in this part of the meeting",
+			},
+			"openQuestions": {
+				Type:        genai.TypeArray,
+				Items:       &genai.Schema{Type: genai.TypeString},
+				Description: "Questions raised and left unanswered in this part of the meeting",
+			},
+			"actionItems": {
+				Type: genai.TypeArray,
+				Items: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"action": {
+							Type:        genai.TypeString,
+							Enum:        actionItemActions,
+							Description: "create a new issue, update or comment on an existing one, or close one that was finished",
+						},
+						"repo": {
+							Type:        genai.TypeString,
+							Enum:        repos,
+							Description: "The repository of the existing issue for update, close and comment, or where new work belongs for create",
+						},
+						"issueNumber": {Type: genai.TypeInteger, Description: "The existing issue for update, close and comment"},
+						"title":       {Type: genai.TypeString, Description: "Short, specific issue title"},
+						"description": {Type: genai.TypeString, Description: "What needs to be done and why, as discussed"},
+						"owner":       {Type: genai.TypeString, Description: "Speaker who took the item, if any"},
+						"dueDate":     {Type: genai.TypeString, Description: "Due date or deadline as said in the meeting, if any"},
+						"sourceTimestamps": {
+							Type:        genai.TypeArray,
+							Items:       &genai.Schema{Type: genai.TypeString},
+							Description: "HH:MM:SS timestamps where the item was discussed",
+						},
+					},
+					Required: []string{"action", "repo", "title", "description"},
+				},
+			},
+		},
+		Required: []string{"summary", "actionItems"},
+	}
+}
+
+var (
+	genaiClient *genai.Client
+
+	extractionsMu sync.Mutex
+	// extractions caches the extraction of each transcript file
+	extractions = map[string]*MeetingExtraction{}
+)
+
+// extractMeeting extracts action items from every chunk of a transcript concurrently,
+// then merges and de-duplicates them
+func extractMeeting(ctx context.Context, path, repo string, t *transcript.Transcript, chunks []transcript.Chunk) (*MeetingExtraction, error) {
+	extractionsMu.Lock()
+	defer extractionsMu.Unlock()
+	if ex, ok := extractions[path]; ok {
+		return ex, nil
+	}
+
+	prompt, err := prompts.Select(extractPromptName, path)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Extracting action items from %d chunks using prompt %s\n", len(chunks), prompt.ID())
+
+	repos := catalogueRepos()
+	openIssues, err := recentOpenIssues(ctx, repos)
+	if err != nil {
+		return nil, err
+	}
+	validator := newItemValidator(repo, repos)
+	schema := chunkExtractionSchema(repos)
+
+	results := make([]*chunkExtraction, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, extractWorkers)
+	var wg sync.WaitGroup
+	for i, c := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			instruction, err := prompt.Render(ExtractPromptVars{
+				ChunkNumber: c.Index + 1,
+				ChunkCount:  len(chunks),
+				Span:        c.Span(),
+				Speakers:    t.Speakers(),
+				Repos:       repos,
+				OpenIssues:  openIssues,
+				Transcript:  c.Text(),
+			})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i], errs[i] = extractChunk(ctx, instruction, schema, validator)
+		}()
+	}
+	wg.Wait()
+
+	ex := &MeetingExtraction{Attendees: t.Speakers()}
+	var all []ActionItem
+	for i, r := range results {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("failed to extract chunk %d: %v", i+1, errs[i])
+		}
+		ex.Summaries = append(ex.Summaries, ChunkSummary{Span: chunks[i].Span(), Summary: r.Summary})
+		all = append(all, r.ActionItems...)
+		ex.Rejected = append(ex.Rejected, r.rejected...)
+		for _, d := range r.Decisions {
+			ex.Decisions = appendUnique(ex.Decisions, strings.TrimSpace(d))
+		}
+		for _, q := range r.OpenQuestions {
+			ex.OpenQuestions = appendUnique(ex.OpenQuestions, strings.TrimSpace(q))
+		}
+	}
+	ex.ActionItems = mergeActionItems(all)
+	fmt.Printf("Extracted %d action items, %d after merging duplicates, %d rejected\n", len(all), len(ex.ActionItems), len(ex.Rejected))
+
+	if err := writeActionAudit(path, repo, prompt.ID(), ex); err != nil {
+		return nil, err
+	}
+	extractions[path] = ex
+	return ex, nil
+}
+
+// extractChunk asks the model for the structured extraction of one chunk.
+// Items that fail validation are sent back with the problems found, up to
+// maxExtractAttempts times; items still invalid after that are rejected.
+func extractChunk(ctx context.Context, instruction string, schema *genai.Schema, v *itemValidator) (*chunkExtraction, error) {
+	contents := []*genai.Content{genai.NewContentFromText(instruction, genai.RoleUser)}
+	config := &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema:   schema,
+		Temperature:      genai.Ptr[float32](0),
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := genaiClient.Models.GenerateContent(ctx, modelName, contents, config)
+		if err != nil {
+			return nil, err
+		}
+
+		var out chunkExtraction
+		if err := json.Unmarshal([]byte(resp.Text()), &out); err != nil {
+			if attempt >= maxExtractAttempts {
+				return nil, fmt.Errorf("model returned invalid JSON: %v", err)
+			}
+			contents = append(contents,
+				genai.NewContentFromText(resp.Text(), genai.RoleModel),
+				genai.NewContentFromText(fmt.Sprintf("That was not valid JSON (%v). Return the complete result again as JSON matching the schema.", err), genai.RoleUser))
+			continue
+		}
+
+		valid, rejected := v.validateAll(ctx, out.ActionItems)
+		if len(rejected) == 0 || attempt >= maxExtractAttempts {
+			out.ActionItems, out.rejected = valid, rejected
+			return &out, nil
+		}
+
+		fmt.Printf("Re-prompting for %d invalid action items (attempt %d)\n", len(rejected), attempt)
+		contents = append(contents,
+			genai.NewContentFromText(resp.Text(), genai.RoleModel),
+			genai.NewContentFromText(repromptMessage(rejected), genai.RoleUser))
+	}
+}
+
+// mergeActionItems merges items that describe the same task, as found by
+// TF-IDF similarity. The first mention keeps its position in the list.
+func mergeActionItems(items []ActionItem) []ActionItem {
+	if len(items) < 2 {
+		return items
+	}
+
+	docs := make([]similarity.Document, len(items))
+	for i, item := range items {
+		docs[i] = similarity.Document{ID: strconv.Itoa(i), Text: item.Title + "\n" + item.Title + "\n" + item.Description}
+	}
+	idx := similarity.NewTFIDF(docs)
+
+	// Union-find over pairs above the threshold
+	parent := make([]int, len(items))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range items {
+		matches, _ := idx.Search(context.Background(), docs[i].Text, 0)
+		for _, m := range matches {
+			j, _ := strconv.Atoi(m.ID)
+			// Items about different issues are never merged
+			if j != i && m.Score >= duplicateThreshold && items[i].Repo == items[j].Repo && items[i].IssueNumber == items[j].IssueNumber {
+				a, b := find(i), find(j)
+				if a != b {
+					parent[max(a, b)] = min(a, b)
+				}
+			}
+		}
+	}
+
+	groups := map[int][]ActionItem{}
+	var roots []int
+	for i, item := range items {
+		r := find(i)
+		if _, ok := groups[r]; !ok {
+			roots = append(roots, r)
+		}
+		groups[r] = append(groups[r], item)
+	}
+	sort.Ints(roots)
+
+	merged := make([]ActionItem, 0, len(roots))
+	for _, r := range roots {
+		merged = append(merged, mergeGroup(groups[r]))
+	}
+	return merged
+}
+
+// mergeGroup combines duplicates: the longest description wins, timestamps are united
+func mergeGroup(group []ActionItem) ActionItem {
+	out := group[0]
+	for _, item := range group[1:] {
+		if len(item.Description) > len(out.Description) {
+			out.Description = item.Description
+		}
+		if out.Owner == "" {
+			out.Owner = item.Owner
+		}
+		if out.DueDate == "" {
+			out.DueDate = item.DueDate
+		}
+		for _, ts := range item.SourceTimestamps {
+			out.SourceTimestamps = appendUnique(out.SourceTimestamps, ts)
+		}
+	}
+	sort.Strings(out.SourceTimestamps)
+	return out
+}
+
+// renderExtraction formats the merged extraction for the agent
+func renderExtraction(ex *MeetingExtraction) string {
+	var b strings.Builder
+	b.WriteString("Meeting summary:\n")
+	for _, s := range ex.Summaries {
+		if s.Span != "" {
+			fmt.Fprintf(&b, "- [%s] %s\n", s.Span, s.Summary)
+		} else {
+			fmt.Fprintf(&b, "- %s\n", s.Summary)
+		}
+	}
+	if len(ex.Decisions) > 0 {
+		b.WriteString("\nDecisions:\n")
+		for _, d := range ex.Decisions {
+			fmt.Fprintf(&b, "- %s\n", d)
+		}
+	}
+	items, _ := json.MarshalIndent(ex.ActionItems, "", "  ")
+	fmt.Fprintf(&b, "\nAction items, validated, merged and de-duplicated across the meeting:\n%s\n", items)
+	if len(ex.Rejected) > 0 {
+		fmt.Fprintf(&b, "\n%d further items were rejected as invalid and are listed in the audit file; do not act on them.\n", len(ex.Rejected))
+	}
+	return b.String()
+}
@@ -0,0 +1,303 @@
+// Package github is a small typed client for the GitHub REST API, covering
+// the issue operations james-agent needs.
+//
+// The base URL is configurable so the client can be pointed at a local fake
+// server. Requests are retried with exponential backoff on transient errors
+// and on primary and secondary rate limits, honouring the Retry-After and
+// X-RateLimit-Reset headers.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the public GitHub API
+const DefaultBaseURL = "https://api.github.com"
+
+// Config configures a Client.
+type Config struct {
+	// BaseURL of the API, DefaultBaseURL if empty.
+	BaseURL string
+	// Token is sent as a bearer token.
+	Token string
+	// HTTPClient defaults to a client with a 30s timeout.
+	HTTPClient *http.Client
+	// MaxRetries is the number of retries after the first attempt. Defaults to 3, -1 disables retries.
+	MaxRetries int
+	// MaxWait caps a single wait for a rate limit or backoff. Longer waits fail instead. Defaults to 2 minutes.
+	MaxWait time.Duration
+	// UserAgent defaults to "james-agent".
+	UserAgent string
+}
+
+// Client talks to the GitHub REST API.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+	maxRetries int
+	maxWait    time.Duration
+	userAgent  string
+
+	// baseBackoff is the first retry delay, doubled on every attempt
+	baseBackoff time.Duration
+}
+
+// NewClient returns a client for cfg.
+func NewClient(cfg Config) *Client {
+	c := &Client{
+		baseURL:     strings.TrimRight(cfg.BaseURL, "/"),
+		token:       cfg.Token,
+		httpClient:  cfg.HTTPClient,
+		maxRetries:  cfg.MaxRetries,
+		maxWait:     cfg.MaxWait,
+		userAgent:   cfg.UserAgent,
+		baseBackoff: time.Second,
+	}
+	if c.baseURL == "" {
+		c.baseURL = DefaultBaseURL
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = 3
+	} else if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	if c.maxWait == 0 {
+		c.maxWait = 2 * time.Minute
+	}
+	if c.userAgent == "" {
+		c.userAgent = "james-agent"
+	}
+	return c
+}
+
+// BaseURL returns the API base URL the client talks to.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// APIError is a non-2xx response from GitHub.
+type APIError struct {
+	StatusCode       int    `json:"-"`
+	Message          string `json:"message"`
+	DocumentationURL string `json:"documentation_url"`
+	// Body is the raw response body
+	Body string `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("github: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("github: %d %s", e.StatusCode, e.Body)
+}
+
+// RateLimitError is returned when GitHub asks the client to wait longer than MaxWait.
+type RateLimitError struct {
+	*APIError
+	// RetryAfter is how long GitHub asked to wait
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v (rate limited, retry after %s)", e.APIError, e.RetryAfter.Round(time.Second))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.APIError
+}
+
+// IsNotFound reports whether err is a 404 from GitHub.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// ValidateRepo checks that repo has the owner/name form.
+func ValidateRepo(repo string) error {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("repository must be in owner/name form, got %q", repo)
+	}
+	return nil
+}
+
+// repoPath builds /repos/{owner}/{repo} followed by the escaped elements
+func repoPath(repo string, elems ...string) (string, error) {
+	if err := ValidateRepo(repo); err != nil {
+		return "", err
+	}
+	owner, name, _ := strings.Cut(repo, "/")
+	parts := []string{"repos", url.PathEscape(owner), url.PathEscape(name)}
+	for _, e := range elems {
+		parts = append(parts, url.PathEscape(e))
+	}
+	return "/" + strings.Join(parts, "/"), nil
+}
+
+// do sends a JSON request and decodes the JSON response into out.
+// path may be relative to the base URL or an absolute URL (for pagination links).
+func (c *Client) do(ctx context.Context, method, path string, in, out any) (*http.Response, error) {
+	var payload []byte
+	if in != nil {
+		var err error
+		payload, err = json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("github: failed to marshal request: %v", err)
+		}
+	}
+
+	target := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		target = c.baseURL + path
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, body, err := c.send(ctx, method, target, payload)
+
+		var wait time.Duration
+		retry := false
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// The request may have reached GitHub, only repeat idempotent ones
+			retry = idempotent(method)
+			wait = c.backoff(attempt)
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			if out != nil && len(body) > 0 {
+				if err := json.Unmarshal(body, out); err != nil {
+					return resp, fmt.Errorf("github: failed to parse response: %v", err)
+				}
+			}
+			return resp, nil
+		default:
+			apiErr := &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+			_ = json.Unmarshal(body, apiErr)
+			err = apiErr
+
+			if d, limited := rateLimitDelay(resp, body); limited {
+				// Rate limited requests were not processed, so they are always safe to repeat
+				if d > c.maxWait {
+					return resp, &RateLimitError{APIError: apiErr, RetryAfter: d}
+				}
+				retry = true
+				wait = d
+				if wait <= 0 {
+					wait = c.backoff(attempt)
+				}
+			} else if resp.StatusCode >= 500 {
+				retry = idempotent(method)
+				wait = c.backoff(attempt)
+			}
+		}
+
+		if !retry || attempt >= c.maxRetries {
+			return resp, err
+		}
+		if wait > c.maxWait {
+			wait = c.maxWait
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send performs one attempt and reads the whole body
+func (c *Client) send(ctx context.Context, method, target string, payload []byte) (*http.Response, []byte, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("github: failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("github: request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("github: failed to read response: %v", err)
+	}
+	return resp, data, nil
+}
+
+// backoff returns the exponential delay for an attempt, with jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.baseBackoff << attempt
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// rateLimitDelay reports whether resp is a primary or secondary rate limit
+// response and how long GitHub asked to wait (zero if it did not say).
+func rateLimitDelay(resp *http.Response, body []byte) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return time.Until(t), true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			// One extra second for clock skew
+			return time.Until(time.Unix(reset, 0)) + time.Second, true
+		}
+		return 0, true
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || bytes.Contains(bytes.ToLower(body), []byte("secondary rate limit")) {
+		// Secondary limit without a hint: GitHub recommends waiting at least a minute
+		return time.Minute, true
+	}
+	return 0, false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodPatch:
+		return true
+	}
+	return false
+}
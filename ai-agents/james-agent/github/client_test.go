@@ -0,0 +1,178 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// response is one reply of a scripted server
+type response struct {
+	status  int
+	headers map[string]string
+	body    string
+}
+
+// scripted serves the responses in order, repeating the last one, and counts the requests
+func scripted(t *testing.T, responses ...response) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		resp := responses[min(n, len(responses))-1]
+		for k, v := range resp.headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(resp.status)
+		fmt.Fprint(w, resp.body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// testClient returns a client for srv that backs off in microseconds
+func testClient(srv *httptest.Server) *Client {
+	c := NewClient(Config{BaseURL: srv.URL, Token: "test-token", MaxRetries: 2, MaxWait: time.Second})
+	c.baseBackoff = time.Microsecond
+	return c
+}
+
+func TestDoRetries(t *testing.T) {
+	ok := response{status: http.StatusOK, body: `{"number":1}`}
+	for _, tc := range []struct {
+		name      string
+		method    string
+		responses []response
+		wantCalls int32
+		// wantStatus is the status of the returned APIError, 0 for success
+		wantStatus int
+		// wantRetryAfter is set when a RateLimitError is expected
+		wantRetryAfter time.Duration
+	}{
+		{name: "success", method: http.MethodGet, responses: []response{ok}, wantCalls: 1},
+		{name: "server error then success", method: http.MethodGet,
+			responses: []response{{status: http.StatusBadGateway}, ok}, wantCalls: 2},
+		{name: "server error until retries run out", method: http.MethodGet,
+			responses: []response{{status: http.StatusInternalServerError, body: `{"message":"boom"}`}}, wantCalls: 3, wantStatus: http.StatusInternalServerError},
+		{name: "server error on POST is not repeated", method: http.MethodPost,
+			responses: []response{{status: http.StatusBadGateway}, ok}, wantCalls: 1, wantStatus: http.StatusBadGateway},
+		{name: "client error is not repeated", method: http.MethodGet,
+			responses: []response{{status: http.StatusNotFound, body: `{"message":"Not Found"}`}, ok}, wantCalls: 1, wantStatus: http.StatusNotFound},
+		{name: "forbidden without a rate limit is not repeated", method: http.MethodGet,
+			responses: []response{{status: http.StatusForbidden, body: `{"message":"Resource not accessible by integration"}`}, ok}, wantCalls: 1, wantStatus: http.StatusForbidden},
+		{name: "Retry-After within MaxWait is waited for, also on POST", method: http.MethodPost,
+			responses: []response{{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "0"}}, ok}, wantCalls: 2},
+		{name: "Retry-After beyond MaxWait fails", method: http.MethodGet,
+			responses: []response{{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "120"}}, ok}, wantCalls: 1, wantStatus: http.StatusTooManyRequests, wantRetryAfter: 2 * time.Minute},
+		{name: "X-RateLimit-Reset in the past is retried", method: http.MethodGet,
+			responses: []response{{status: http.StatusForbidden, headers: map[string]string{
+				"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)}}, ok}, wantCalls: 2},
+		{name: "X-RateLimit-Reset beyond MaxWait fails", method: http.MethodGet,
+			responses: []response{{status: http.StatusForbidden, headers: map[string]string{
+				"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)}}, ok}, wantCalls: 1, wantStatus: http.StatusForbidden, wantRetryAfter: time.Hour},
+		{name: "secondary rate limit without a hint waits a minute", method: http.MethodGet,
+			responses: []response{{status: http.StatusForbidden, body: `{"message":"You have exceeded a secondary rate limit."}`}, ok}, wantCalls: 1, wantStatus: http.StatusForbidden, wantRetryAfter: time.Minute},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, calls := scripted(t, tc.responses...)
+			var out Issue
+			_, err := testClient(srv).do(context.Background(), tc.method, "/repos/o/r/issues/1", nil, &out)
+
+			if got := calls.Load(); got != tc.wantCalls {
+				t.Errorf("requests = %d, want %d", got, tc.wantCalls)
+			}
+			if tc.wantStatus == 0 {
+				if err != nil || out.Number != 1 {
+					t.Fatalf("do = %+v, %v, want issue 1", out, err)
+				}
+				return
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tc.wantStatus {
+				t.Fatalf("do error = %v, want status %d", err, tc.wantStatus)
+			}
+			var rateErr *RateLimitError
+			if isRate := errors.As(err, &rateErr); isRate != (tc.wantRetryAfter > 0) {
+				t.Fatalf("do error = %v, rate limited %v, want %v", err, isRate, tc.wantRetryAfter > 0)
+			}
+			// X-RateLimit-Reset is in whole seconds and gets a second of slack
+			if rateErr != nil && (rateErr.RetryAfter < tc.wantRetryAfter-time.Second || rateErr.RetryAfter > tc.wantRetryAfter+2*time.Second) {
+				t.Fatalf("RetryAfter = %s, want about %s", rateErr.RetryAfter, tc.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestRateLimitDelayHTTPDate(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	resp.Header.Set("Retry-After", time.Now().Add(30*time.Second).UTC().Format(http.TimeFormat))
+	d, limited := rateLimitDelay(resp, nil)
+	if !limited || d < 28*time.Second || d > 30*time.Second {
+		t.Fatalf("rateLimitDelay = %s, %v, want about 30s", d, limited)
+	}
+}
+
+func TestListIssuesPagination(t *testing.T) {
+	var foreign atomic.Int32
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		foreign.Add(1)
+		fmt.Fprint(w, `[]`)
+	}))
+	defer other.Close()
+
+	for _, tc := range []struct {
+		name string
+		// next is the rel="next" target of the first page, relative to the API unless it is on the other host
+		next      string
+		onOther   bool
+		wantCount int
+		wantErr   bool
+	}{
+		{name: "follows the API host", next: "/repos/o/r/issues?page=2", wantCount: 2},
+		{name: "refuses another host", next: "/steal?page=2", onOther: true, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var srv *httptest.Server
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer test-token" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if r.URL.Query().Get("page") == "2" {
+					fmt.Fprint(w, `[{"number":2,"title":"second"}]`)
+					return
+				}
+				next := srv.URL + tc.next
+				if tc.onOther {
+					next = other.URL + tc.next
+				}
+				w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next", <%s>; rel="last"`, next, next))
+				fmt.Fprint(w, `[{"number":1,"title":"first"}]`)
+			}))
+			defer srv.Close()
+
+			list, err := testClient(srv).ListIssues(context.Background(), "o/r", ListIssuesOptions{})
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ListIssues = %+v, want an error", list)
+				}
+				if foreign.Load() != 0 {
+					t.Fatal("the token was sent to another host")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list.Issues) != tc.wantCount {
+				t.Fatalf("ListIssues returned %d issues, want %d", len(list.Issues), tc.wantCount)
+			}
+		})
+	}
+}
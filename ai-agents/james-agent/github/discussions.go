@@ -0,0 +1,114 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Discussion is a repository discussion.
+type Discussion struct {
+	ID     string `json:"id"`
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+}
+
+// graphQLError is one entry of the errors of a GraphQL response
+type graphQLError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// graphQLURL is the GraphQL endpoint next to the REST base URL. GitHub
+// Enterprise serves REST at /api/v3 and GraphQL at /api/graphql.
+func (c *Client) graphQLURL() string {
+	return strings.TrimSuffix(c.baseURL, "/v3") + "/graphql"
+}
+
+// graphQL runs a query and decodes its data into out. GraphQL reports most
+// errors with status 200, they are returned as an APIError.
+func (c *Client) graphQL(ctx context.Context, query string, vars map[string]any, out any) error {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphQLError  `json:"errors"`
+	}
+	in := map[string]any{"query": query, "variables": vars}
+	if _, err := c.do(ctx, http.MethodPost, c.graphQLURL(), in, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		apiErr := &APIError{StatusCode: http.StatusOK, Message: resp.Errors[0].Message}
+		if resp.Errors[0].Type == "NOT_FOUND" {
+			apiErr.StatusCode = http.StatusNotFound
+		}
+		return apiErr
+	}
+	if out != nil {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			return fmt.Errorf("github: failed to parse response: %v", err)
+		}
+	}
+	return nil
+}
+
+// CreateDiscussion starts a discussion in the category with the given name.
+// Discussions are only available through the GraphQL API.
+func (c *Client) CreateDiscussion(ctx context.Context, repo, category, title, body string) (*Discussion, error) {
+	if err := ValidateRepo(repo); err != nil {
+		return nil, err
+	}
+	owner, name, _ := strings.Cut(repo, "/")
+
+	var found struct {
+		Repository struct {
+			ID                   string `json:"id"`
+			DiscussionCategories struct {
+				Nodes []struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				} `json:"nodes"`
+			} `json:"discussionCategories"`
+		} `json:"repository"`
+	}
+	err := c.graphQL(ctx, `query($owner: String!, $name: String!) {
+  repository(owner: $owner, name: $name) {
+    id
+    discussionCategories(first: 100) { nodes { id name } }
+  }
+}`, map[string]any{"owner": owner, "name": name}, &found)
+	if err != nil {
+		return nil, err
+	}
+
+	var categoryID string
+	var names []string
+	for _, cat := range found.Repository.DiscussionCategories.Nodes {
+		if strings.EqualFold(cat.Name, category) {
+			categoryID = cat.ID
+		}
+		names = append(names, cat.Name)
+	}
+	if categoryID == "" {
+		if len(names) == 0 {
+			return nil, fmt.Errorf("github: discussions are not enabled on %s", repo)
+		}
+		return nil, fmt.Errorf("github: %s has no discussion category %q, available: %s", repo, category, strings.Join(names, ", "))
+	}
+
+	var created struct {
+		CreateDiscussion struct {
+			Discussion Discussion `json:"discussion"`
+		} `json:"createDiscussion"`
+	}
+	err = c.graphQL(ctx, `mutation($repo: ID!, $category: ID!, $title: String!, $body: String!) {
+  createDiscussion(input: {repositoryId: $repo, categoryId: $category, title: $title, body: $body}) {
+    discussion { id number url }
+  }
+}`, map[string]any{"repo": found.Repository.ID, "category": categoryID, "title": title, "body": body}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created.CreateDiscussion.Discussion, nil
+}
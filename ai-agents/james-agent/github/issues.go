@@ -0,0 +1,268 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// User is a GitHub account.
+type User struct {
+	Login   string `json:"login"`
+	HTMLURL string `json:"html_url,omitempty"`
+}
+
+// Label is an issue label.
+type Label struct {
+	ID          int64  `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Color       string `json:"color,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// IssueType is an organization level issue type, such as Bug or Feature.
+type IssueType struct {
+	ID          int64  `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Milestone is a repository milestone.
+type Milestone struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	State  string `json:"state,omitempty"`
+}
+
+// Issue is a GitHub issue. The issues API also returns pull requests, which have PullRequest set.
+type Issue struct {
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Body        string     `json:"body"`
+	State       string     `json:"state"`
+	StateReason string     `json:"state_reason,omitempty"`
+	HTMLURL     string     `json:"html_url"`
+	User        User       `json:"user"`
+	Labels      []Label    `json:"labels"`
+	Assignees   []User     `json:"assignees"`
+	Milestone   *Milestone `json:"milestone,omitempty"`
+	Type        *IssueType `json:"type,omitempty"`
+	Comments    int        `json:"comments"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`
+
+	PullRequest *struct {
+		URL string `json:"url"`
+	} `json:"pull_request,omitempty"`
+}
+
+// IsPullRequest reports whether the issue is a pull request.
+func (i *Issue) IsPullRequest() bool {
+	return i.PullRequest != nil
+}
+
+// Comment is an issue comment.
+type Comment struct {
+	ID        int64     `json:"id"`
+	Body      string    `json:"body"`
+	HTMLURL   string    `json:"html_url"`
+	User      User      `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IssueRequest is the body of a create or edit request. Nil fields are left unchanged on edit.
+type IssueRequest struct {
+	Title       *string   `json:"title,omitempty"`
+	Body        *string   `json:"body,omitempty"`
+	State       *string   `json:"state,omitempty"`
+	StateReason *string   `json:"state_reason,omitempty"`
+	Labels      *[]string `json:"labels,omitempty"`
+	Assignees   *[]string `json:"assignees,omitempty"`
+	Milestone   *int      `json:"milestone,omitempty"`
+	// Type is the name of an organization issue type
+	Type *string `json:"type,omitempty"`
+}
+
+// String returns a pointer to s, for IssueRequest fields.
+func String(s string) *string {
+	return &s
+}
+
+// ListIssuesOptions filters ListIssues.
+type ListIssuesOptions struct {
+	// State is open, closed or all. Defaults to open.
+	State string
+	// Labels only returns issues with all of these labels.
+	Labels []string
+	// Assignee is a login, "none" or "*".
+	Assignee string
+	// Milestone is a milestone number, "none" or "*".
+	Milestone string
+	// Since only returns issues updated at or after this time.
+	Since time.Time
+	// IncludePullRequests keeps pull requests, which the issues API mixes in.
+	IncludePullRequests bool
+	// Max stops listing after this many issues. Zero means no limit.
+	Max int
+}
+
+// IssueList is the result of ListIssues.
+type IssueList struct {
+	Issues []Issue
+	// Truncated is set when more issues matched than Max
+	Truncated bool
+}
+
+// GetIssue returns one issue.
+func (c *Client) GetIssue(ctx context.Context, repo string, number int) (*Issue, error) {
+	path, err := repoPath(repo, "issues", strconv.Itoa(number))
+	if err != nil {
+		return nil, err
+	}
+	var issue Issue
+	if _, err := c.do(ctx, http.MethodGet, path, nil, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// CreateIssue opens a new issue.
+func (c *Client) CreateIssue(ctx context.Context, repo string, req IssueRequest) (*Issue, error) {
+	if req.Title == nil || *req.Title == "" {
+		return nil, fmt.Errorf("github: an issue needs a title")
+	}
+	path, err := repoPath(repo, "issues")
+	if err != nil {
+		return nil, err
+	}
+	var issue Issue
+	if _, err := c.do(ctx, http.MethodPost, path, req, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// EditIssue changes the fields set in req.
+func (c *Client) EditIssue(ctx context.Context, repo string, number int, req IssueRequest) (*Issue, error) {
+	path, err := repoPath(repo, "issues", strconv.Itoa(number))
+	if err != nil {
+		return nil, err
+	}
+	var issue Issue
+	if _, err := c.do(ctx, http.MethodPatch, path, req, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// ListIssues follows the Link header through every page of matching issues,
+// stopping once opts.Max issues have been collected.
+func (c *Client) ListIssues(ctx context.Context, repo string, opts ListIssuesOptions) (*IssueList, error) {
+	path, err := repoPath(repo, "issues")
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("state", opts.State)
+	if opts.State == "" {
+		q.Set("state", "open")
+	}
+	if len(opts.Labels) > 0 {
+		q.Set("labels", strings.Join(opts.Labels, ","))
+	}
+	if opts.Assignee != "" {
+		q.Set("assignee", opts.Assignee)
+	}
+	if opts.Milestone != "" {
+		q.Set("milestone", opts.Milestone)
+	}
+	if !opts.Since.IsZero() {
+		q.Set("since", opts.Since.UTC().Format(time.RFC3339))
+	}
+	q.Set("per_page", "100")
+
+	list := &IssueList{}
+	next := path + "?" + q.Encode()
+	for next != "" {
+		var page []Issue
+		resp, err := c.do(ctx, http.MethodGet, next, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		next = nextPageURL(resp.Header.Get("Link"))
+		// Never send the token to a host other than the API
+		if next != "" && !strings.HasPrefix(next, c.baseURL+"/") {
+			return nil, fmt.Errorf("github: refusing to follow pagination link to %s", next)
+		}
+
+		for _, issue := range page {
+			if issue.IsPullRequest() && !opts.IncludePullRequests {
+				continue
+			}
+			if opts.Max > 0 && len(list.Issues) >= opts.Max {
+				list.Truncated = true
+				return list, nil
+			}
+			list.Issues = append(list.Issues, issue)
+		}
+	}
+	return list, nil
+}
+
+// nextPageURL returns the rel="next" target of a Link header, or ""
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// CreateComment adds a comment to an issue.
+func (c *Client) CreateComment(ctx context.Context, repo string, number int, body string) (*Comment, error) {
+	path, err := repoPath(repo, "issues", strconv.Itoa(number), "comments")
+	if err != nil {
+		return nil, err
+	}
+	var comment Comment
+	if _, err := c.do(ctx, http.MethodPost, path, map[string]string{"body": body}, &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// ListComments returns every comment on an issue.
+func (c *Client) ListComments(ctx context.Context, repo string, number int) ([]Comment, error) {
+	path, err := repoPath(repo, "issues", strconv.Itoa(number), "comments")
+	if err != nil {
+		return nil, err
+	}
+	return listAll[Comment](ctx, c, path)
+}
+
+// SearchIssues returns the first page (up to 100) of issues and pull requests
+// matching a search query, e.g. `repo:owner/name in:body "text"`.
+func (c *Client) SearchIssues(ctx context.Context, query string) ([]Issue, error) {
+	q := url.Values{}
+	q.Set("q", query)
+	q.Set("per_page", "100")
+	var result struct {
+		Items []Issue `json:"items"`
+	}
+	if _, err := c.do(ctx, http.MethodGet, "/search/issues?"+q.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
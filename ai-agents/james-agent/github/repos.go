@@ -0,0 +1,91 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ListLabels returns the labels defined in a repository.
+func (c *Client) ListLabels(ctx context.Context, repo string) ([]Label, error) {
+	path, err := repoPath(repo, "labels")
+	if err != nil {
+		return nil, err
+	}
+	return listAll[Label](ctx, c, path)
+}
+
+// ListAssignees returns the users issues in a repository can be assigned to.
+func (c *Client) ListAssignees(ctx context.Context, repo string) ([]User, error) {
+	path, err := repoPath(repo, "assignees")
+	if err != nil {
+		return nil, err
+	}
+	return listAll[User](ctx, c, path)
+}
+
+// ListMilestones returns the open milestones of a repository.
+func (c *Client) ListMilestones(ctx context.Context, repo string) ([]Milestone, error) {
+	path, err := repoPath(repo, "milestones")
+	if err != nil {
+		return nil, err
+	}
+	return listAll[Milestone](ctx, c, path+"?state=open")
+}
+
+// ListIssueTypes returns the issue types of an organization. Repositories owned
+// by a user have no issue types and return a 404.
+func (c *Client) ListIssueTypes(ctx context.Context, org string) ([]IssueType, error) {
+	var types []IssueType
+	if _, err := c.do(ctx, http.MethodGet, "/orgs/"+url.PathEscape(org)+"/issue-types", nil, &types); err != nil {
+		return nil, err
+	}
+	return types, nil
+}
+
+// AddLabels adds labels to an issue, keeping the ones it has.
+func (c *Client) AddLabels(ctx context.Context, repo string, number int, labels []string) error {
+	path, err := repoPath(repo, "issues", strconv.Itoa(number), "labels")
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, http.MethodPost, path, map[string][]string{"labels": labels}, nil)
+	return err
+}
+
+// AddAssignees adds assignees to an issue, keeping the current ones.
+func (c *Client) AddAssignees(ctx context.Context, repo string, number int, logins []string) error {
+	path, err := repoPath(repo, "issues", strconv.Itoa(number), "assignees")
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, http.MethodPost, path, map[string][]string{"assignees": logins}, nil)
+	return err
+}
+
+// listAll follows the Link header through every page of a list endpoint
+func listAll[T any](ctx context.Context, c *Client, path string) ([]T, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	var all []T
+	next := path + sep + "per_page=100"
+	for next != "" {
+		var page []T
+		resp, err := c.do(ctx, http.MethodGet, next, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+
+		next = nextPageURL(resp.Header.Get("Link"))
+		if next != "" && !strings.HasPrefix(next, c.baseURL+"/") {
+			return nil, fmt.Errorf("github: refusing to follow pagination link to %s", next)
+		}
+	}
+	return all, nil
+}
@@ -9,6 +9,13 @@ require (
 	google.golang.org/genai v1.35.0
 )
 
+require gopkg.in/yaml.v3 v3.0.1
+
+require (
+	github.com/modelcontextprotocol/go-sdk v0.7.0
+	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
+)
+
 require (
 	cloud.google.com/go v0.123.0 // indirect
 	cloud.google.com/go/auth v0.17.0 // indirect
@@ -36,6 +43,9 @@ require (
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20251014184007-4626949a642f // indirect
 	google.golang.org/grpc v1.76.0 // indirect
 	google.golang.org/protobuf v1.36.10 // indirect
+	promptlib v0.0.0-00010101000000-000000000000
 	rsc.io/omap v1.2.0 // indirect
 	rsc.io/ordered v1.1.1 // indirect
 )
+
+replace promptlib => ../promptlib
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// markerPrefix starts the hidden marker james-agent adds to what it writes in the
+// tracker. Trackers that show HTML comments store it in their own hidden form.
+const markerPrefix = "<!-- james-agent"
+
+// LedgerEntry is one action performed in the issue tracker
+type LedgerEntry struct {
+	Key    string    `json:"key"`
+	Action string    `json:"action"`
+	Repo   string    `json:"repo"`
+	Number int       `json:"number"`
+	Title  string    `json:"title,omitempty"`
+	URL    string    `json:"url,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// LedgerTranscript is the history of one transcript, keyed by its content hash
+type LedgerTranscript struct {
+	Hash       string        `json:"hash"`
+	Transcript string        `json:"transcript"`
+	Runs       int           `json:"runs"`
+	FirstRunAt time.Time     `json:"firstRunAt"`
+	LastRunAt  time.Time     `json:"lastRunAt"`
+	Entries    []LedgerEntry `json:"entries"`
+}
+
+// Ledger records the actions of every run, so re-running a transcript
+// reconciles with what was done before instead of repeating it
+type Ledger struct {
+	path string
+
+	mu          sync.Mutex
+	Transcripts map[string]*LedgerTranscript `json:"transcripts"`
+}
+
+var (
+	// ledger is the run ledger, nil when disabled
+	ledger *Ledger
+	// previousRun is the history of the current transcript before this run
+	previousRun *LedgerTranscript
+)
+
+// openLedger loads the ledger file, starting an empty one if it does not exist
+func openLedger(path string) (*Ledger, error) {
+	l := &Ledger{path: path, Transcripts: map[string]*LedgerTranscript{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ledger: %v", err)
+	}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to parse ledger %s: %v", path, err)
+	}
+	if l.Transcripts == nil {
+		l.Transcripts = map[string]*LedgerTranscript{}
+	}
+	return l, nil
+}
+
+// history returns a copy of what earlier runs did with a transcript, or nil
+func (l *Ledger) history(hash string) *LedgerTranscript {
+	if l == nil || hash == "" {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t, ok := l.Transcripts[hash]
+	if !ok {
+		return nil
+	}
+	previous := *t
+	previous.Entries = append([]LedgerEntry(nil), t.Entries...)
+	return &previous
+}
+
+// startRun counts a run on a transcript
+func (l *Ledger) startRun(m Meeting) error {
+	if l == nil || m.Hash == "" {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	t, ok := l.Transcripts[m.Hash]
+	if !ok {
+		t = &LedgerTranscript{Hash: m.Hash, Transcript: m.Source, FirstRunAt: now}
+		l.Transcripts[m.Hash] = t
+	}
+	t.Runs++
+	t.LastRunAt = now
+	return l.save()
+}
+
+// find returns the entry recorded for an action key
+func (l *Ledger) find(hash, key string) (LedgerEntry, bool) {
+	if l == nil {
+		return LedgerEntry{}, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t, ok := l.Transcripts[hash]; ok {
+		for _, e := range t.Entries {
+			if e.Key == key {
+				return e, true
+			}
+		}
+	}
+	return LedgerEntry{}, false
+}
+
+// record adds an entry and saves the ledger
+func (l *Ledger) record(m Meeting, e LedgerEntry) error {
+	if l == nil || m.Hash == "" {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	t, ok := l.Transcripts[m.Hash]
+	if !ok {
+		t = &LedgerTranscript{Hash: m.Hash, Transcript: m.Source, FirstRunAt: time.Now(), LastRunAt: time.Now()}
+		l.Transcripts[m.Hash] = t
+	}
+	for _, existing := range t.Entries {
+		if existing.Key == e.Key {
+			return nil
+		}
+	}
+	t.Entries = append(t.Entries, e)
+	return l.save()
+}
+
+// save writes the ledger atomically. The caller holds l.mu.
+func (l *Ledger) save() error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal ledger: %v", err)
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write ledger: %v", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("failed to save ledger: %v", err)
+	}
+	return nil
+}
+
+// describeHistory tells the agent what earlier runs already did
+func describeHistory(t *LedgerTranscript) string {
+	if t == nil || len(t.Entries) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "This transcript was already processed %d time(s), last on %s. These actions were taken then and will be skipped if repeated:\n", t.Runs, t.LastRunAt.Format("2006-01-02 15:04"))
+	for _, e := range t.Entries {
+		fmt.Fprintf(&b, "- %s %s#%d %s %s\n", e.Action, e.Repo, e.Number, e.Title, e.URL)
+	}
+	return b.String()
+}
+
+// hashFile returns the hex SHA-256 of a file's contents
+func hashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// actionKey identifies an action independently of the run: creates by their
+// normalised title, other actions by their issue and content
+func actionKey(args GitHubActionParams) string {
+	d := args.IssueData
+	parts := []string{d.Repo, args.Action}
+	switch args.Action {
+	case "create":
+		parts = append(parts, strings.Join(strings.Fields(strings.ToLower(d.Title)), " "))
+	case "close":
+		// An issue is closed once, whatever the wording of the evidence
+		parts = append(parts, strconv.Itoa(d.Number))
+	default:
+		parts = append(parts, strconv.Itoa(d.Number), d.Mode, d.Title, strings.TrimSpace(d.Body),
+			strings.Join(d.Labels, ","), strings.Join(d.Assignees, ","), d.Milestone, d.Type)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// marker is the hidden HTML comment that identifies text written for a transcript
+func marker(m Meeting, key string) string {
+	return fmt.Sprintf("%s transcript=%s key=%s -->", markerPrefix, shortHash(m.Hash), key)
+}
+
+// withMarker appends the marker to a body, when the transcript is known
+func withMarker(body string, m Meeting, key string) string {
+	if m.Hash == "" {
+		return body
+	}
+	return strings.TrimRight(body, "\n") + "\n\n" + marker(m, key) + "\n"
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 16 {
+		return hash[:16]
+	}
+	return hash
+}
+
+// reconcileCreate looks in the tracker for an issue created from this transcript
+// for the same item, by the marker with its key; the caller checked the ledger.
+// Titles are not compared: distinct items often have similar titles.
+func reconcileCreate(ctx context.Context, m Meeting, data GitHubIssueData, key string) (*IssueSummary, error) {
+	if m.Hash == "" {
+		return nil, nil
+	}
+
+	// The marker finds issues the ledger missed: created elsewhere, before the
+	// ledger existed or by a run stopped before it recorded them
+	issues, err := issueTracker.SearchIssues(ctx, data.Repo, markerSearch(m))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for earlier issues: %v", err)
+	}
+	for _, issue := range issues {
+		if strings.Contains(issue.Body, marker(m, key)) {
+			return &IssueSummary{Number: issue.Number, Title: issue.Title, State: issue.State, URL: issue.URL}, nil
+		}
+	}
+	return nil, nil
+}
+
+// markerSearch is the phrase finding the issues that carry a marker of the transcript
+func markerSearch(m Meeting) string {
+	return "james-agent transcript=" + shortHash(m.Hash)
+}
+
+// markedIssues lists the issues of repos carrying a marker of the transcript.
+// Without ledger entries they are shown to the agent, as the exact key of a
+// create misses an issue whose title the model worded differently before.
+func markedIssues(ctx context.Context, m Meeting, repos []string) ([]IssueSummary, error) {
+	if issueTracker == nil || m.Hash == "" {
+		return nil, nil
+	}
+	prefix := fmt.Sprintf("%s transcript=%s ", markerPrefix, shortHash(m.Hash))
+	var out []IssueSummary
+	for _, repo := range repos {
+		issues, err := issueTracker.SearchIssues(ctx, repo, markerSearch(m))
+		if err != nil {
+			return nil, fmt.Errorf("failed to search %s for earlier issues: %v", repo, err)
+		}
+		for _, issue := range issues {
+			if strings.Contains(issue.Body, prefix) {
+				out = append(out, IssueSummary{Repo: repo, Number: issue.Number, Title: issue.Title, State: issue.State, URL: issue.URL})
+			}
+		}
+	}
+	return out, nil
+}
+
+// describeMarkedIssues tells the agent which issues earlier runs left their marker on
+func describeMarkedIssues(issues []IssueSummary) string {
+	if len(issues) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("The ledger has no record of this transcript, but these issues carry its marker: an earlier run created them or added notes to them. Do not create them again; for an item about the same work, update the issue instead:\n")
+	for _, issue := range issues {
+		fmt.Fprintf(&b, "- %s#%d %s (%s) %s\n", issue.Repo, issue.Number, issue.Title, issue.State, issue.URL)
+	}
+	return b.String()
+}
+
+// issueHasMarker reports whether an issue body or comment already carries the marker
+func issueHasMarker(ctx context.Context, repo string, number int, m Meeting, key string) (bool, error) {
+	if m.Hash == "" {
+		return false, nil
+	}
+	mk := marker(m, key)
+	issue, err := issueTracker.GetIssue(ctx, repo, number)
+	if err != nil {
+		return false, err
+	}
+	if strings.Contains(issue.Body, mk) {
+		return true, nil
+	}
+	comments, err := issueTracker.ListComments(ctx, repo, number)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range comments {
+		if strings.Contains(c.Body, mk) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// recordAction adds a successful action to the ledger
+func recordAction(args GitHubActionParams, key string, result GitHubActionResult) {
+	e := LedgerEntry{Key: key, Action: args.Action, Repo: args.IssueData.Repo, Number: args.IssueData.Number, At: time.Now()}
+	if result.Issue != nil {
+		e.Number, e.Title, e.URL = result.Issue.Number, result.Issue.Title, result.Issue.URL
+	}
+	if result.CommentURL != "" {
+		e.URL = result.CommentURL
+	}
+	if err := ledger.record(currentMeeting, e); err != nil {
+		fmt.Printf("Failed to record action in ledger: %v\n", err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/mcptoolset"
+)
+
+// MCPServerConfig is an MCP server whose tools are given to the agent.
+// Exactly one of Command and URL is set.
+type MCPServerConfig struct {
+	Name string `yaml:"name"`
+	// Command and Args start a server speaking MCP over stdio
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	// Env is added to the environment of the command. Values may reference
+	// variables as ${VAR} so tokens stay out of the file.
+	Env map[string]string `yaml:"env"`
+	// URL is the endpoint of a server speaking streamable HTTP
+	URL string `yaml:"url"`
+	// Headers are sent with every HTTP request, expanded like Env
+	Headers map[string]string `yaml:"headers"`
+	// Tools lists the tools the agent may call; "*" allows all of them
+	Tools []string `yaml:"tools"`
+	// ReadOnly marks servers whose allowed tools change nothing. Only these
+	// are connected for -dry-run and -interactive, which cannot review MCP calls.
+	ReadOnly bool `yaml:"read_only"`
+	// UncheckedWrites opts in to a server whose tools change things. Its calls
+	// bypass the validation, close policy, catalogue and ledger of
+	// GitHubMCPServerAction, so one with issue tools can close any issue.
+	UncheckedWrites bool `yaml:"unchecked_writes"`
+}
+
+// validate reports configuration mistakes before any server is started
+func (c MCPServerConfig) validate() error {
+	switch {
+	case c.Name == "":
+		return fmt.Errorf("mcp server without a name")
+	case (c.Command == "") == (c.URL == ""):
+		return fmt.Errorf("mcp server %s: set either command or url", c.Name)
+	case len(c.Tools) == 0:
+		return fmt.Errorf("mcp server %s: list the tools the agent may use, or \"*\" for all", c.Name)
+	case c.URL == "" && len(c.Headers) > 0:
+		return fmt.Errorf("mcp server %s: headers are only sent to url servers", c.Name)
+	case c.ReadOnly && c.UncheckedWrites:
+		return fmt.Errorf("mcp server %s: set read_only or unchecked_writes, not both", c.Name)
+	case !c.ReadOnly && !c.UncheckedWrites:
+		return fmt.Errorf("mcp server %s: mark it read_only, or set unchecked_writes to let its tools change things without james-agent's checks", c.Name)
+	}
+	return nil
+}
+
+// newMCPToolsets connects the agent to the configured MCP servers. Servers are
+// started lazily by the toolset when the agent first lists its tools.
+func newMCPToolsets(servers []MCPServerConfig, mode string) ([]tool.Toolset, error) {
+	var toolsets []tool.Toolset
+	for _, s := range servers {
+		if err := s.validate(); err != nil {
+			return nil, err
+		}
+		if mode != modeExecute && !s.ReadOnly {
+			fmt.Printf("Skipping MCP server %s: its calls cannot be planned or confirmed, mark it read_only if it changes nothing\n", s.Name)
+			continue
+		}
+
+		var transport mcp.Transport
+		if s.Command != "" {
+			cmd := exec.Command(s.Command, s.Args...)
+			cmd.Env = os.Environ()
+			for k, v := range s.Env {
+				cmd.Env = append(cmd.Env, k+"="+os.ExpandEnv(v))
+			}
+			// The server's log goes to our stderr, stdout carries the protocol
+			cmd.Stderr = os.Stderr
+			transport = &mcp.CommandTransport{Command: cmd}
+		} else {
+			headers := http.Header{}
+			for k, v := range s.Headers {
+				headers.Set(k, os.ExpandEnv(v))
+			}
+			transport = &mcp.StreamableClientTransport{
+				Endpoint:   s.URL,
+				HTTPClient: &http.Client{Transport: &headerTransport{headers: headers}},
+			}
+		}
+
+		toolset, err := mcptoolset.New(mcptoolset.Config{
+			Transport:  transport,
+			ToolFilter: allowTools(s.Tools),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("mcp server %s: %v", s.Name, err)
+		}
+		toolsets = append(toolsets, toolset)
+		fmt.Printf("Using MCP server %s with tools %v\n", s.Name, s.Tools)
+		if s.UncheckedWrites {
+			log.Printf("WARNING: calls to MCP server %s bypass the close policy, repository catalogue and ledger", s.Name)
+		}
+	}
+	return toolsets, nil
+}
+
+// allowTools is the allow-list of one server, "*" allowing all tools
+func allowTools(names []string) tool.Predicate {
+	if slices.Contains(names, "*") {
+		return func(agent.ReadonlyContext, tool.Tool) bool { return true }
+	}
+	return tool.StringPredicate(names)
+}
+
+// headerTransport adds fixed headers, such as Authorization, to every request
+type headerTransport struct {
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header[k] = v
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
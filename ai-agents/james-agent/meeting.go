@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Update modes of the update action
+const (
+	updateModeComment    = "comment"
+	updateModeAppendBody = "append_body"
+)
+
+// Meeting identifies the meeting the current transcript comes from
+type Meeting struct {
+	// Source is the transcript file name
+	Source string `json:"source"`
+	// URL links the recording or notes, if known
+	URL  string `json:"url,omitempty"`
+	Date string `json:"date"`
+	// Hash is the SHA-256 of the transcript, which identifies it across runs
+	Hash string `json:"hash,omitempty"`
+}
+
+// currentMeeting is referenced by the comments and notes james-agent writes
+var currentMeeting Meeting
+
+// newMeeting describes a transcript. Without an explicit date the file's modification date is used.
+func newMeeting(transcriptPath, url, date string) (Meeting, error) {
+	m := Meeting{Source: filepath.Base(transcriptPath), URL: url, Date: date}
+	hash, err := hashFile(transcriptPath)
+	if err != nil {
+		return m, err
+	}
+	m.Hash = hash
+	if m.Date == "" {
+		info, err := os.Stat(transcriptPath)
+		if err != nil {
+			return m, err
+		}
+		m.Date = info.ModTime().Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", m.Date); err != nil {
+		return m, fmt.Errorf("invalid meeting date %q, use YYYY-MM-DD", date)
+	}
+	return m, nil
+}
+
+// reference links the meeting in Markdown
+func (m Meeting) reference() string {
+	if m.URL != "" {
+		return fmt.Sprintf("[%s](%s)", m.Source, m.URL)
+	}
+	return fmt.Sprintf("`%s`", m.Source)
+}
+
+// meetingComment is the comment an update adds to an issue
+func meetingComment(m Meeting, notes string) string {
+	return fmt.Sprintf("**Meeting notes, %s** (from %s)\n\n%s\n", m.Date, m.reference(), strings.TrimSpace(notes))
+}
+
+// appendMeetingNotes keeps the original description and adds a dated section after it
+func appendMeetingNotes(original string, m Meeting, notes string) string {
+	section := fmt.Sprintf("## Meeting notes (%s)\n\n_From %s_\n\n%s\n", m.Date, m.reference(), strings.TrimSpace(notes))
+	original = strings.TrimRight(original, "\n")
+	if original == "" {
+		return section
+	}
+	return original + "\n\n" + section
+}
@@ -0,0 +1,72 @@
+package mermaid
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	classNameRe = `[\p{L}\p{N}_]+(?:~[^~]+~)?`
+	// classRe is a class declaration, e.g. class Shape~T~["Label"] {
+	classRe = regexp.MustCompile(`^class\s+` + classNameRe + `(?:\["[^"]*"\])?(?:\s*:::[\w-]+)?\s*(\{)?\s*$`)
+	// relationRe is a relationship with optional cardinalities and label,
+	// e.g. Customer "1" --> "*" Order : places
+	relationRe = regexp.MustCompile(`^` + classNameRe + `\s*(?:"[^"]*"\s*)?(?:<\||\*|o|<)?(?:--|\.\.)(?:\|>|\*|o|>)?\s*(?:"[^"]*"\s*)?` + classNameRe + `\s*(?::.*)?$`)
+	// memberRe adds a member outside a class body, e.g. Animal : +int age
+	memberRe     = regexp.MustCompile(`^` + classNameRe + `\s*:\s*\S`)
+	annotationRe = regexp.MustCompile(`^<<[^>]+>>\s*` + classNameRe + `$`)
+	classNoteRe  = regexp.MustCompile(`^note\s+(?:for\s+` + classNameRe + `\s+)?"[^"]*"$`)
+	namespaceRe  = regexp.MustCompile(`^namespace\s+[\w.]+\s*\{$`)
+)
+
+// classKeywords start statements without further checks
+var classKeywords = []string{"direction ", "classDef ", "cssClass ", "style ", "click ", "link ", "callback ", "title", "accTitle", "accDescr"}
+
+func checkClass(lines []line) []Error {
+	var errs []Error
+	var stack []block
+	for _, l := range lines {
+		// Members inside a class body are free form
+		if len(stack) > 0 && stack[len(stack)-1].name != "namespace" {
+			if l.text == "}" {
+				stack = stack[:len(stack)-1]
+			} else if strings.Contains(l.text, "{") || strings.Contains(l.text, "}") {
+				errs = append(errs, errorf(l, "unexpected brace in the body of %s, close it with } on its own line", stack[len(stack)-1].name))
+			}
+			continue
+		}
+
+		switch {
+		case l.text == "}":
+			if len(stack) == 0 {
+				errs = append(errs, errorf(l, "} without a class body or namespace"))
+				continue
+			}
+			stack = stack[:len(stack)-1]
+		case strings.HasPrefix(l.text, "class "):
+			m := classRe.FindStringSubmatch(l.text)
+			if m == nil {
+				errs = append(errs, errorf(l, "invalid class declaration %q, expected e.g. class Name or class Name {", excerpt(l.text)))
+				continue
+			}
+			if m[1] != "" {
+				stack = append(stack, block{name: "class " + strings.Fields(l.text)[1], line: l})
+			}
+		case strings.HasPrefix(l.text, "namespace "):
+			if !namespaceRe.MatchString(l.text) {
+				errs = append(errs, errorf(l, "invalid namespace %q, expected namespace Name {", excerpt(l.text)))
+				continue
+			}
+			stack = append(stack, block{name: "namespace", line: l})
+		case strings.HasPrefix(l.text, "note"):
+			if !classNoteRe.MatchString(l.text) {
+				errs = append(errs, errorf(l, "invalid note %q, expected note \"text\" or note for Class \"text\"", excerpt(l.text)))
+			}
+		case relationRe.MatchString(l.text), memberRe.MatchString(l.text), annotationRe.MatchString(l.text):
+		case hasKeyword(l.text, classKeywords):
+		default:
+			errs = append(errs, errorf(l, "unrecognised statement %q, expected a class, a member such as Name : +field or a relationship such as A <|-- B", excerpt(l.text)))
+		}
+	}
+	return append(errs, unclosed(stack, "}")...)
+}
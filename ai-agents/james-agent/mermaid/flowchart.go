@@ -0,0 +1,241 @@
+package mermaid
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	directions = map[string]bool{"TB": true, "TD": true, "BT": true, "RL": true, "LR": true}
+
+	nodeIDRe = regexp.MustCompile(`^[\p{L}\p{N}_]+(?:-[\p{L}\p{N}_]+)*`)
+	// textLinkRe is a link with inline text, e.g. "-- yes -->" or "-. maybe .->"
+	textLinkRe = regexp.MustCompile(`^[<xo]?(?:--|==|-\.)\s*[^-=.>|\s][^|]*?\s*(?:-{2,}[>xo]?|={2,}[>xo]?|\.-+[>xo]?)`)
+	// linkRe is a link without inline text, e.g. "-->", "---", "==>", "-.->" or "<-->"
+	linkRe      = regexp.MustCompile(`^[<xo]?(?:-{2,}|={2,}|-\.+-|~{3,})[>xo]?`)
+	pipeLabelRe = regexp.MustCompile(`^\|[^|]*\|`)
+	classRefRe  = regexp.MustCompile(`^:::[\w-]+`)
+)
+
+// shapes are the node shape delimiters, longest opener first
+var shapes = []struct {
+	open   string
+	closes []string
+}{
+	{"(((", []string{")))"}},
+	{"((", []string{"))"}},
+	{"([", []string{"])"}},
+	{"[[", []string{"]]"}},
+	{"[(", []string{")]"}},
+	{"{{", []string{"}}"}},
+	{"[/", []string{"/]", `\]`}},
+	{`[\`, []string{`\]`, "/]"}},
+	{"(", []string{")"}},
+	{"[", []string{"]"}},
+	{"{", []string{"}"}},
+	{">", []string{"]"}},
+}
+
+// flowchartKeywords start statements that are not node chains
+var flowchartKeywords = []string{"classDef ", "class ", "style ", "linkStyle ", "click ", "accTitle", "accDescr"}
+
+func checkFlowchart(header line, lines []line) []Error {
+	var errs []Error
+	// The header may be followed by statements, e.g. graph TD; A-->B
+	stmts := splitStatements(header.text)
+	if _, direction, _ := strings.Cut(stmts[0], " "); direction != "" && !directions[strings.TrimSpace(direction)] {
+		errs = append(errs, errorf(header, "unknown direction %q, use TB, TD, BT, RL or LR", strings.TrimSpace(direction)))
+	}
+	if len(stmts) > 1 {
+		lines = append([]line{{n: header.n, text: strings.Join(stmts[1:], ";")}}, lines...)
+	}
+
+	var stack []block
+	for _, l := range lines {
+		for _, stmt := range splitStatements(l.text) {
+			switch {
+			case stmt == "end":
+				if len(stack) == 0 {
+					errs = append(errs, errorf(l, "end without a subgraph"))
+					continue
+				}
+				stack = stack[:len(stack)-1]
+			case stmt == "subgraph" || strings.HasPrefix(stmt, "subgraph "):
+				stack = append(stack, block{name: "subgraph", line: l})
+			case strings.HasPrefix(stmt, "direction "):
+				if d := strings.TrimSpace(strings.TrimPrefix(stmt, "direction ")); !directions[d] {
+					errs = append(errs, errorf(l, "unknown direction %q, use TB, TD, BT, RL or LR", d))
+				}
+			case hasKeyword(stmt, flowchartKeywords):
+			default:
+				if err := checkChain(stmt); err != nil {
+					errs = append(errs, errorf(l, "%v", err))
+				}
+			}
+		}
+	}
+	return append(errs, unclosed(stack, "end")...)
+}
+
+func hasKeyword(stmt string, keywords []string) bool {
+	for _, k := range keywords {
+		if strings.HasPrefix(stmt, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitStatements splits a line on the semicolons outside quotes and labels
+func splitStatements(text string) []string {
+	var out []string
+	depth, quoted, start := 0, false, 0
+	for i, r := range text {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '[' || r == '(' || r == '{':
+			depth++
+		case r == ']' || r == ')' || r == '}':
+			depth--
+		case r == ';' && depth <= 0:
+			out = append(out, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	out = append(out, strings.TrimSpace(text[start:]))
+	var stmts []string
+	for _, s := range out {
+		if s != "" {
+			stmts = append(stmts, s)
+		}
+	}
+	return stmts
+}
+
+// checkChain checks a statement of nodes joined by links, e.g. A[Start] --> B & C
+func checkChain(s string) error {
+	rest, err := nodeGroup(s)
+	if err != nil {
+		return err
+	}
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			return nil
+		}
+		link := textLinkRe.FindString(rest)
+		if link == "" {
+			link = linkRe.FindString(rest)
+		}
+		if link == "" {
+			return fmt.Errorf("unexpected %q, expected a link such as --> between nodes", excerpt(rest))
+		}
+		rest = strings.TrimLeft(rest[len(link):], " \t")
+		if label := pipeLabelRe.FindString(rest); label != "" {
+			rest = strings.TrimLeft(rest[len(label):], " \t")
+		} else if strings.HasPrefix(rest, "|") {
+			return fmt.Errorf("link label %q is not closed with |", excerpt(rest))
+		}
+		if rest == "" {
+			return fmt.Errorf("link %q has no target node", strings.TrimSpace(link))
+		}
+		if rest, err = nodeGroup(rest); err != nil {
+			return err
+		}
+	}
+}
+
+// nodeGroup reads nodes joined by &
+func nodeGroup(s string) (string, error) {
+	for {
+		rest, err := node(s)
+		if err != nil {
+			return "", err
+		}
+		trimmed := strings.TrimLeft(rest, " \t")
+		if !strings.HasPrefix(trimmed, "&") {
+			return rest, nil
+		}
+		s = strings.TrimLeft(trimmed[1:], " \t")
+	}
+}
+
+// node reads a node id with its optional shape and class
+func node(s string) (string, error) {
+	id := nodeIDRe.FindString(s)
+	if id == "" {
+		return "", fmt.Errorf("expected a node id at %q", excerpt(s))
+	}
+	rest := s[len(id):]
+
+	// Shape data, e.g. A@{ shape: rect }
+	if strings.HasPrefix(rest, "@{") {
+		end := strings.Index(rest, "}")
+		if end < 0 {
+			return "", fmt.Errorf("node %s: shape data is not closed with }", id)
+		}
+		rest = rest[end+1:]
+	} else {
+		for _, sh := range shapes {
+			if !strings.HasPrefix(rest, sh.open) {
+				continue
+			}
+			var err error
+			if rest, err = label(id, rest[len(sh.open):], sh.open, sh.closes); err != nil {
+				return "", err
+			}
+			break
+		}
+	}
+	if c := classRefRe.FindString(rest); c != "" {
+		rest = rest[len(c):]
+	}
+	return rest, nil
+}
+
+// label reads a node label up to its closing delimiter. Unquoted labels must
+// not contain brackets or quotes, mermaid takes them for shape delimiters.
+func label(id, s, open string, closes []string) (string, error) {
+	if strings.HasPrefix(s, `"`) {
+		end := strings.Index(s[1:], `"`)
+		if end < 0 {
+			return "", fmt.Errorf("node %s: quoted label is not closed with \"", id)
+		}
+		after := s[end+2:]
+		for _, c := range closes {
+			if strings.HasPrefix(after, c) {
+				return after[len(c):], nil
+			}
+		}
+		return "", fmt.Errorf("node %s: expected %q after the quoted label", id, closes[0])
+	}
+
+	end, closer := -1, ""
+	for _, c := range closes {
+		if i := strings.Index(s, c); i >= 0 && (end < 0 || i < end) {
+			end, closer = i, c
+		}
+	}
+	if end < 0 {
+		return "", fmt.Errorf("node %s: %q is not closed with %q", id, open, closes[0])
+	}
+	text := s[:end]
+	if i := strings.IndexAny(text, `[](){}"`); i >= 0 {
+		return "", fmt.Errorf("node %s: label %q contains %q, quote the label: %s%s\"...\"%s", id, text, text[i:i+1], id, open, closes[0])
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("node %s: empty label", id)
+	}
+	return s[end+len(closer):], nil
+}
+
+// excerpt shortens the rest of a statement for an error message
+func excerpt(s string) string {
+	if r := []rune(s); len(r) > 30 {
+		return string(r[:30]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,159 @@
+// Package mermaid finds the ```mermaid blocks of a Markdown text and checks
+// their syntax, so broken diagrams can be repaired before they are published.
+//
+// The check is a lightweight grammar for flowcharts, sequence diagrams and
+// class diagrams, the kinds james-agent writes. It catches the common
+// mistakes: unknown statements, unbalanced brackets, unquoted labels with
+// special characters, links without a target and unclosed blocks. Other
+// diagram types are only checked for a known header.
+package mermaid
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Block is a mermaid code block of a Markdown text
+type Block struct {
+	// Line is the line of the opening fence, counted from 1
+	Line   int
+	Source string
+}
+
+// Error is a syntax error in a diagram
+type Error struct {
+	// Block is the diagram, counted from 1, and BlockLine the line of its fence
+	Block     int
+	BlockLine int
+	// Line is the line within the diagram, counted from 1
+	Line    int
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Block == 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("diagram %d, line %d: %s", e.Block, e.Line, e.Message)
+}
+
+var fenceRe = regexp.MustCompile("^\\s*(```+|~~~+)\\s*(\\S*)")
+
+// Extract returns the mermaid blocks of a Markdown text. An unterminated
+// block extends to the end of the text, as GitHub renders it.
+func Extract(markdown string) []Block {
+	var blocks []Block
+	lines := strings.Split(markdown, "\n")
+	for i := 0; i < len(lines); i++ {
+		m := fenceRe.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		fence := m[1]
+		var body []string
+		j := i + 1
+		for ; j < len(lines); j++ {
+			if strings.HasPrefix(strings.TrimSpace(lines[j]), fence) && strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(lines[j]), fence[:1])) == "" {
+				break
+			}
+			body = append(body, strings.TrimRight(lines[j], "\r"))
+		}
+		if strings.EqualFold(m[2], "mermaid") {
+			blocks = append(blocks, Block{Line: i + 1, Source: strings.Join(body, "\n")})
+		}
+		i = j
+	}
+	return blocks
+}
+
+// ValidateMarkdown checks every mermaid block of a Markdown text
+func ValidateMarkdown(markdown string) []Error {
+	var errs []Error
+	for i, b := range Extract(markdown) {
+		for _, e := range Validate(b.Source) {
+			e.Block, e.BlockLine = i+1, b.Line
+			errs = append(errs, e)
+		}
+	}
+	return errs
+}
+
+// otherTypes are diagram types that are accepted without a grammar check
+var otherTypes = map[string]bool{
+	"stateDiagram": true, "stateDiagram-v2": true, "erDiagram": true, "gantt": true,
+	"pie": true, "journey": true, "gitGraph": true, "mindmap": true, "timeline": true,
+	"quadrantChart": true, "requirementDiagram": true, "C4Context": true, "C4Container": true,
+	"C4Component": true, "C4Dynamic": true, "C4Deployment": true, "sankey-beta": true,
+	"xychart-beta": true, "block-beta": true, "packet-beta": true, "kanban": true,
+	"architecture-beta": true, "zenuml": true,
+}
+
+// line is a statement of a diagram with its line number
+type line struct {
+	n    int
+	text string
+}
+
+// Validate checks the syntax of one diagram
+func Validate(source string) []Error {
+	lines := statements(source)
+	if len(lines) == 0 {
+		return []Error{{Line: 1, Message: "empty diagram"}}
+	}
+	header := lines[0]
+	kind, _, _ := strings.Cut(header.text, " ")
+	switch kind {
+	case "flowchart", "flowchart-elk", "graph":
+		return checkFlowchart(header, lines[1:])
+	case "sequenceDiagram":
+		return checkSequence(lines[1:])
+	case "classDiagram", "classDiagram-v2":
+		return checkClass(lines[1:])
+	}
+	if otherTypes[kind] {
+		return nil
+	}
+	return []Error{{Line: header.n, Message: fmt.Sprintf("unknown diagram type %q, start with flowchart, sequenceDiagram or classDiagram", kind)}}
+}
+
+// statements drops front matter, %% comments and directives and blank lines
+func statements(source string) []line {
+	var out []line
+	raw := strings.Split(source, "\n")
+	i := 0
+	// YAML front matter, e.g. a title
+	if len(raw) > 0 && strings.TrimSpace(raw[0]) == "---" {
+		for i = 1; i < len(raw) && strings.TrimSpace(raw[i]) != "---"; i++ {
+		}
+		i++
+	}
+	for ; i < len(raw); i++ {
+		t := strings.TrimSpace(raw[i])
+		if t == "" || strings.HasPrefix(t, "%%") {
+			continue
+		}
+		out = append(out, line{n: i + 1, text: t})
+	}
+	return out
+}
+
+// errorf is an Error at a line
+func errorf(l line, format string, args ...any) Error {
+	return Error{Line: l.n, Message: fmt.Sprintf(format, args...)}
+}
+
+// block tracks the nesting of blocks closed by "end" or "}"
+type block struct {
+	name string
+	line line
+}
+
+// unclosed reports the blocks still open at the end of a diagram
+func unclosed(stack []block, closer string) []Error {
+	var errs []Error
+	for _, b := range stack {
+		errs = append(errs, errorf(b.line, "%s is never closed with %q", b.name, closer))
+	}
+	return errs
+}
@@ -0,0 +1,76 @@
+package mermaid
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// messageRe is a message, e.g. "Alice->>+Bob: Hello"
+	messageRe     = regexp.MustCompile(`^([^:]+?)\s*(<<-->>|<<->>|-->>|->>|-->|->|--x|-x|--\)|-\))\s*([+-]?)\s*([^:]*?)\s*(:.*)?$`)
+	participantRe = regexp.MustCompile(`^(?:create\s+)?(?:participant|actor)\s+\S`)
+	noteRe        = regexp.MustCompile(`^[Nn]ote\s+(?:left of|right of|over)\s+[^:]+:`)
+	actorRe       = regexp.MustCompile(`^(?:activate|deactivate|destroy)\s+\S`)
+)
+
+// sequenceBlocks are the statements opening a block closed by end, with the
+// statements allowed directly inside them
+var sequenceBlocks = map[string]string{
+	"loop": "", "alt": "else", "opt": "", "par": "and", "critical": "option",
+	"break": "", "rect": "", "box": "",
+}
+
+// sequenceKeywords start statements without further checks
+var sequenceKeywords = []string{"autonumber", "title", "accTitle", "accDescr", "link ", "links ", "properties ", "details "}
+
+func checkSequence(lines []line) []Error {
+	var errs []Error
+	var stack []block
+	for _, l := range lines {
+		word, _, _ := strings.Cut(l.text, " ")
+		switch {
+		case l.text == "end":
+			if len(stack) == 0 {
+				errs = append(errs, errorf(l, "end without a loop, alt, opt, par, critical, break, rect or box"))
+				continue
+			}
+			stack = stack[:len(stack)-1]
+		case hasBlock(word):
+			stack = append(stack, block{name: word, line: l})
+		case word == "else" || word == "and" || word == "option":
+			if len(stack) == 0 || sequenceBlocks[stack[len(stack)-1].name] != word {
+				errs = append(errs, errorf(l, "%s is only allowed inside %s", word, parentOf(word)))
+			}
+		case participantRe.MatchString(l.text), actorRe.MatchString(l.text), noteRe.MatchString(l.text):
+		case strings.HasPrefix(strings.ToLower(l.text), "note "):
+			errs = append(errs, errorf(l, "a note needs a position and text, e.g. Note right of Alice: text"))
+		case hasKeyword(l.text, sequenceKeywords):
+		default:
+			m := messageRe.FindStringSubmatch(l.text)
+			switch {
+			case m == nil:
+				errs = append(errs, errorf(l, "unrecognised statement %q, expected a message such as Alice->>Bob: text", excerpt(l.text)))
+			case m[4] == "":
+				errs = append(errs, errorf(l, "message %q has no receiver", excerpt(l.text)))
+			case m[5] == "":
+				errs = append(errs, errorf(l, "message %q needs a text after a colon, e.g. %s%s%s: text", excerpt(l.text), m[1], m[2], m[4]))
+			}
+		}
+	}
+	return append(errs, unclosed(stack, "end")...)
+}
+
+func hasBlock(word string) bool {
+	_, ok := sequenceBlocks[word]
+	return ok
+}
+
+// parentOf names the block a continuation belongs to
+func parentOf(word string) string {
+	for name, cont := range sequenceBlocks {
+		if cont == word {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"james-agent/main/tracker"
+
+	"google.golang.org/adk/tool"
+)
+
+// repoMetadataTTL is how long labels, assignees, milestones and issue types are cached
+const repoMetadataTTL = 10 * time.Minute
+
+// repoMetadata is what issue fields are validated against. Keys are lower case.
+type repoMetadata struct {
+	labels     map[string]string
+	assignees  map[string]string
+	milestones map[string]tracker.Milestone
+	// issueTypes is nil when the owner has no issue types
+	issueTypes map[string]string
+	fetchedAt  time.Time
+}
+
+var (
+	repoMetadataMu    sync.Mutex
+	repoMetadataCache = map[string]*repoMetadata{}
+)
+
+// getRepoMetadata returns the cached metadata of repo, fetching it when stale
+func getRepoMetadata(ctx context.Context, repo string) (*repoMetadata, error) {
+	repoMetadataMu.Lock()
+	defer repoMetadataMu.Unlock()
+
+	if md, ok := repoMetadataCache[repo]; ok && time.Since(md.fetchedAt) < repoMetadataTTL {
+		return md, nil
+	}
+
+	md := &repoMetadata{
+		labels:     map[string]string{},
+		assignees:  map[string]string{},
+		milestones: map[string]tracker.Milestone{},
+		fetchedAt:  time.Now(),
+	}
+
+	labels, err := issueTracker.ListLabels(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range labels {
+		md.labels[strings.ToLower(l)] = l
+	}
+
+	assignees, err := issueTracker.ListAssignees(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range assignees {
+		md.assignees[strings.ToLower(u)] = u
+	}
+
+	milestones, err := issueTracker.ListMilestones(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range milestones {
+		md.milestones[strings.ToLower(m.Title)] = m
+	}
+
+	types, err := issueTracker.ListIssueTypes(ctx, repo)
+	switch {
+	case err == nil:
+		md.issueTypes = map[string]string{}
+		for _, t := range types {
+			md.issueTypes[strings.ToLower(t)] = t
+		}
+	case errors.Is(err, tracker.ErrNotSupported):
+		// Personal accounts, GitLab and Gitea have no configurable issue types
+	default:
+		return nil, err
+	}
+
+	repoMetadataCache[repo] = md
+	return md, nil
+}
+
+// resolveIssueData validates labels, assignees, milestone and type against the
+// repository and rewrites them to their canonical form. Assignees may be
+// speaker names from the people directory; milestones may be titles.
+func resolveIssueData(ctx context.Context, data *GitHubIssueData) error {
+	if len(data.Labels) == 0 && len(data.Assignees) == 0 && data.Milestone == "" && data.Type == "" {
+		return nil
+	}
+	if issueTracker == nil {
+		return errors.New(trackerMissing)
+	}
+	md, err := getRepoMetadata(ctx, data.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to fetch repository metadata: %v", err)
+	}
+
+	var problems []string
+
+	var labels, unknownLabels []string
+	for _, l := range data.Labels {
+		if name, ok := md.labels[strings.ToLower(strings.TrimSpace(l))]; ok {
+			labels = appendUnique(labels, name)
+		} else {
+			unknownLabels = append(unknownLabels, l)
+		}
+	}
+	if len(unknownLabels) > 0 {
+		problems = append(problems, fmt.Sprintf("Unknown labels %s. Existing labels: %s.", strings.Join(unknownLabels, ", "), listValues(md.labels)))
+	}
+
+	var logins, unknownPeople []string
+	for _, a := range data.Assignees {
+		name := strings.TrimPrefix(strings.TrimSpace(a), "@")
+		if login, ok := people.lookup(name); ok {
+			name = login
+		}
+		if login, ok := md.assignees[strings.ToLower(name)]; ok {
+			logins = appendUnique(logins, login)
+		} else {
+			unknownPeople = append(unknownPeople, a)
+		}
+	}
+	if len(unknownPeople) > 0 {
+		problems = append(problems, fmt.Sprintf("Cannot assign %s: not an assignable user or a name in the people directory. Assignable users: %s.", strings.Join(unknownPeople, ", "), listValues(md.assignees)))
+	}
+
+	milestone := ""
+	if data.Milestone != "" {
+		if m, ok := findMilestone(md, data.Milestone); ok {
+			milestone = strconv.Itoa(m.ID)
+		} else {
+			titles := map[string]string{}
+			for k, m := range md.milestones {
+				titles[k] = m.Title
+			}
+			problems = append(problems, fmt.Sprintf("Unknown milestone %q. Open milestones: %s.", data.Milestone, listValues(titles)))
+		}
+	}
+
+	issueType := ""
+	if data.Type != "" {
+		if md.issueTypes == nil {
+			problems = append(problems, "Issue types are not available in this repository, leave 'type' empty.")
+		} else if name, ok := md.issueTypes[strings.ToLower(data.Type)]; ok {
+			issueType = name
+		} else {
+			problems = append(problems, fmt.Sprintf("Unknown issue type %q. Issue types: %s.", data.Type, listValues(md.issueTypes)))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, " "))
+	}
+	data.Labels, data.Assignees, data.Milestone, data.Type = labels, logins, milestone, issueType
+	return nil
+}
+
+// findMilestone matches a milestone by number or title
+func findMilestone(md *repoMetadata, s string) (tracker.Milestone, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if n, err := strconv.Atoi(s); err == nil {
+		for _, m := range md.milestones {
+			if m.ID == n {
+				return m, true
+			}
+		}
+	}
+	m, ok := md.milestones[strings.ToLower(s)]
+	return m, ok
+}
+
+// listValues lists the values of a lookup map for an error message
+func listValues(m map[string]string) string {
+	if len(m) == 0 {
+		return "none"
+	}
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	if len(values) > 50 {
+		values = append(values[:50], "...")
+	}
+	return strings.Join(values, ", ")
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// GetRepoMetadata tool structs and function
+type GetRepoMetadataParams struct {
+	Repo string `json:"repo" jsonschema:"The repository (owner/repo)"`
+}
+
+type GetRepoMetadataResult struct {
+	Status       string   `json:"status"`
+	Labels       []string `json:"labels,omitempty"`
+	Milestones   []string `json:"milestones,omitempty"`
+	IssueTypes   []string `json:"issueTypes,omitempty"`
+	Assignees    []string `json:"assignees,omitempty"`
+	People       []Person `json:"people,omitempty"`
+	ErrorMessage string   `json:"errorMessage,omitempty"`
+	Code         int      `json:"code,omitempty"`
+}
+
+func GetRepoMetadata(ctx tool.Context, args GetRepoMetadataParams) GetRepoMetadataResult {
+	if issueTracker == nil {
+		return GetRepoMetadataResult{Status: "error", ErrorMessage: trackerMissing}
+	}
+	md, err := getRepoMetadata(ctx, args.Repo)
+	if err != nil {
+		msg, code := trackerError(err)
+		return GetRepoMetadataResult{Status: "error", ErrorMessage: msg, Code: code}
+	}
+
+	result := GetRepoMetadataResult{Status: "success", People: people.People}
+	for _, v := range md.labels {
+		result.Labels = append(result.Labels, v)
+	}
+	for _, m := range md.milestones {
+		result.Milestones = append(result.Milestones, fmt.Sprintf("#%d %s", m.ID, m.Title))
+	}
+	for _, v := range md.issueTypes {
+		result.IssueTypes = append(result.IssueTypes, v)
+	}
+	for _, v := range md.assignees {
+		result.Assignees = append(result.Assignees, v)
+	}
+	sort.Strings(result.Labels)
+	sort.Strings(result.Milestones)
+	sort.Strings(result.IssueTypes)
+	sort.Strings(result.Assignees)
+	return result
+}
@@ -0,0 +1,627 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"james-agent/main/github"
+	"james-agent/main/similarity"
+)
+
+// minutesMatchThreshold is the similarity above which an issue action is
+// listed under the action item it implements
+const minutesMatchThreshold = 0.3
+
+// MinutesConfig configures the meeting minutes written after every run
+type MinutesConfig struct {
+	// Dir receives the Markdown and HTML minutes, "minutes" by default
+	Dir string `yaml:"dir"`
+	// Discussion, Wiki and Email optionally publish the minutes; dry runs never do
+	Discussion *DiscussionConfig `yaml:"discussion"`
+	Wiki       *WikiConfig       `yaml:"wiki"`
+	Email      *EmailConfig      `yaml:"email"`
+}
+
+// DiscussionConfig posts the minutes as a GitHub Discussion
+type DiscussionConfig struct {
+	// Repo defaults to the repository of the run
+	Repo string `yaml:"repo"`
+	// Category is the name of an existing discussion category
+	Category string `yaml:"category"`
+	// APIURL defaults to $GITHUB_API_URL or the public GitHub API
+	APIURL   string `yaml:"api_url"`
+	TokenEnv string `yaml:"token_env"`
+}
+
+// WikiConfig pushes the minutes as a page of a git backed wiki, as GitHub,
+// GitLab and Gitea have
+type WikiConfig struct {
+	// URL of the wiki's git repository. {repo} is replaced with the repository
+	// of the run, e.g. https://github.com/{repo}.wiki.git
+	URL string `yaml:"url"`
+	// Username sent with the token, "oauth2" works for GitHub, GitLab and Gitea
+	Username string `yaml:"username"`
+	TokenEnv string `yaml:"token_env"`
+}
+
+// EmailConfig sends the minutes through an SMTP server
+type EmailConfig struct {
+	Host string `yaml:"host"`
+	// Port defaults to 587; STARTTLS is used when the server offers it
+	Port        int      `yaml:"port"`
+	Username    string   `yaml:"username"`
+	PasswordEnv string   `yaml:"password_env"`
+	From        string   `yaml:"from"`
+	To          []string `yaml:"to"`
+}
+
+// minutesConfig is set from the configuration file
+var minutesConfig MinutesConfig
+
+// validate reports configuration mistakes before any run
+func (c MinutesConfig) validate() error {
+	if d := c.Discussion; d != nil && d.Category == "" {
+		return fmt.Errorf("minutes discussion: set the category to post in")
+	}
+	if w := c.Wiki; w != nil && w.URL == "" {
+		return fmt.Errorf("minutes wiki: set the url of the wiki's git repository")
+	}
+	if e := c.Email; e != nil {
+		switch {
+		case e.Host == "":
+			return fmt.Errorf("minutes email: set the smtp host")
+		case e.From == "" || len(e.To) == 0:
+			return fmt.Errorf("minutes email: set from and to")
+		}
+	}
+	return nil
+}
+
+// Minutes is the record of one meeting
+type Minutes struct {
+	Meeting   Meeting
+	Repo      string
+	Attendees []string
+	Summaries []ChunkSummary
+	Decisions []string
+	Items     []MinutesItem
+	// Other are the issue changes not matched to an action item
+	Other         []IssueLink
+	OpenQuestions []string
+	// DryRun minutes list planned changes
+	DryRun bool
+}
+
+// MinutesItem is an action item with the issues it led to
+type MinutesItem struct {
+	Title   string
+	Owner   string
+	DueDate string
+	Links   []IssueLink
+}
+
+// IssueLink is one issue change of the run
+type IssueLink struct {
+	// Ref is the issue, e.g. octo/widgets#12
+	Ref  string
+	URL  string
+	Note string
+}
+
+// MinutesResult is where the minutes of a run went
+type MinutesResult struct {
+	Markdown  string   `json:"markdown"`
+	HTML      string   `json:"html"`
+	Published []string `json:"published,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// newMinutes combines the extraction with the actions of the run. ex may be
+// nil when the agent never read the transcript.
+func newMinutes(repo string, meeting Meeting, ex *MeetingExtraction, actions []PlannedAction, dryRun bool) *Minutes {
+	m := &Minutes{Meeting: meeting, Repo: repo, DryRun: dryRun}
+	if ex == nil {
+		ex = &MeetingExtraction{}
+	}
+	m.Attendees = ex.Attendees
+	m.Summaries = ex.Summaries
+	m.Decisions = ex.Decisions
+	m.OpenQuestions = ex.OpenQuestions
+	for _, item := range ex.ActionItems {
+		m.Items = append(m.Items, MinutesItem{Title: item.Title, Owner: item.Owner, DueDate: item.DueDate})
+	}
+
+	var idx *similarity.TFIDF
+	if len(ex.ActionItems) > 0 {
+		docs := make([]similarity.Document, len(ex.ActionItems))
+		for i, item := range ex.ActionItems {
+			docs[i] = similarity.Document{ID: strconv.Itoa(i), Text: item.Title + "\n" + item.Title + "\n" + item.Description}
+		}
+		idx = similarity.NewTFIDF(docs)
+	}
+	for _, a := range actions {
+		link := issueLink(a)
+		i := matchActionItem(ex.ActionItems, idx, a)
+		if i < 0 {
+			m.Other = append(m.Other, link)
+			continue
+		}
+		m.Items[i].Links = append(m.Items[i].Links, link)
+	}
+	return m
+}
+
+// matchActionItem finds the action item an issue action implements, by issue
+// number or else by similarity of the texts. It returns -1 without a match.
+func matchActionItem(items []ActionItem, idx *similarity.TFIDF, a PlannedAction) int {
+	if idx == nil {
+		return -1
+	}
+	if a.IssueData.Number != 0 {
+		for i, item := range items {
+			if item.Repo == a.IssueData.Repo && item.IssueNumber == a.IssueData.Number {
+				return i
+			}
+		}
+	}
+	matches, _ := idx.Search(context.Background(), a.IssueData.Title+"\n"+a.IssueData.Body, 1)
+	if len(matches) == 0 || matches[0].Score < minutesMatchThreshold {
+		return -1
+	}
+	i, _ := strconv.Atoi(matches[0].ID)
+	// An item about an existing issue is not implemented by another issue
+	if items[i].IssueNumber != 0 && (items[i].Repo != a.IssueData.Repo || items[i].IssueNumber != a.IssueData.Number) {
+		return -1
+	}
+	return i
+}
+
+// issueLink describes what an action did to its issue
+func issueLink(a PlannedAction) IssueLink {
+	d := a.IssueData
+	link := IssueLink{Ref: fmt.Sprintf("%s#%d", d.Repo, d.Number)}
+	r := a.Result
+	if r != nil && r.Issue != nil {
+		link.Ref = fmt.Sprintf("%s#%d", d.Repo, r.Issue.Number)
+		link.URL = r.Issue.URL
+	}
+	if r != nil && r.CommentURL != "" {
+		link.URL = r.CommentURL
+	}
+	if a.Action == "create" && (r == nil || r.Issue == nil) {
+		link.Ref = d.Repo + ": " + d.Title
+	}
+
+	done := map[string]string{"create": "created", "update": "updated", "comment": "commented on", "close": "closed"}[a.Action]
+	switch {
+	case r == nil:
+		link.Note = "not performed"
+	case r.Status == "success":
+		link.Note = done
+	case r.Status == "exists", r.Status == "already_done":
+		link.Note = done + " in an earlier run"
+	case r.Status == "planned":
+		link.Note = "planned: " + a.Action
+	case r.Status == "skipped":
+		link.Note = "skipped: " + a.Action
+	default:
+		link.Note = fmt.Sprintf("%s failed: %s", a.Action, r.ErrorMessage)
+	}
+	return link
+}
+
+// title is the heading and subject of the minutes
+func (m *Minutes) title() string {
+	return fmt.Sprintf("Meeting minutes: %s (%s)", strings.TrimSuffix(m.Meeting.Source, filepath.Ext(m.Meeting.Source)), m.Meeting.Date)
+}
+
+// markdown renders the minutes as Markdown
+func (m *Minutes) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", m.title())
+	fmt.Fprintf(&b, "- Meeting: %s on %s\n", m.Meeting.reference(), m.Meeting.Date)
+	fmt.Fprintf(&b, "- Repository: %s\n", m.Repo)
+	if len(m.Attendees) > 0 {
+		fmt.Fprintf(&b, "- Attendees: %s\n", strings.Join(m.Attendees, ", "))
+	}
+	if m.DryRun {
+		b.WriteString("\n_Dry run: the issue changes below were planned, not performed._\n")
+	}
+
+	b.WriteString("\n## Summary\n\n")
+	if len(m.Summaries) == 0 {
+		b.WriteString("_No summary was extracted._\n")
+	}
+	for _, s := range m.Summaries {
+		if s.Span != "" {
+			fmt.Fprintf(&b, "- [%s] %s\n", s.Span, s.Summary)
+		} else {
+			fmt.Fprintf(&b, "- %s\n", s.Summary)
+		}
+	}
+
+	b.WriteString("\n## Decisions\n\n")
+	writeMarkdownList(&b, m.Decisions, "_No decisions were recorded._")
+
+	b.WriteString("\n## Action items\n\n")
+	if len(m.Items) == 0 {
+		b.WriteString("_No action items._\n")
+	}
+	for _, item := range m.Items {
+		fmt.Fprintf(&b, "- **%s**", item.Title)
+		if details := item.details(); details != "" {
+			fmt.Fprintf(&b, " (%s)", details)
+		}
+		b.WriteString("\n")
+		for _, l := range item.Links {
+			fmt.Fprintf(&b, "  - %s\n", l.markdown())
+		}
+	}
+	if len(m.Other) > 0 {
+		b.WriteString("\nOther issue changes:\n\n")
+		for _, l := range m.Other {
+			fmt.Fprintf(&b, "- %s\n", l.markdown())
+		}
+	}
+
+	b.WriteString("\n## Open questions\n\n")
+	writeMarkdownList(&b, m.OpenQuestions, "_No open questions._")
+	return b.String()
+}
+
+func writeMarkdownList(b *strings.Builder, list []string, empty string) {
+	if len(list) == 0 {
+		b.WriteString(empty + "\n")
+	}
+	for _, s := range list {
+		fmt.Fprintf(b, "- %s\n", s)
+	}
+}
+
+// details are the owner and due date of an item
+func (i MinutesItem) details() string {
+	var parts []string
+	if i.Owner != "" {
+		parts = append(parts, "owner: "+i.Owner)
+	}
+	if i.DueDate != "" {
+		parts = append(parts, "due: "+i.DueDate)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (l IssueLink) markdown() string {
+	if l.URL != "" {
+		return fmt.Sprintf("[%s](%s) %s", l.Ref, l.URL, l.Note)
+	}
+	return fmt.Sprintf("%s %s", l.Ref, l.Note)
+}
+
+// minutesHTML renders the same content as markdown; the template escapes every value
+var minutesHTML = template.Must(template.New("minutes").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+.empty, .note { color: #666; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<ul>
+<li>Meeting: {{if .M.Meeting.URL}}<a href="{{.M.Meeting.URL}}">{{.M.Meeting.Source}}</a>{{else}}<code>{{.M.Meeting.Source}}</code>{{end}} on {{.M.Meeting.Date}}</li>
+<li>Repository: {{.M.Repo}}</li>
+{{- if .M.Attendees}}
+<li>Attendees: {{range $i, $a := .M.Attendees}}{{if $i}}, {{end}}{{$a}}{{end}}</li>
+{{- end}}
+</ul>
+{{- if .M.DryRun}}
+<p class="note">Dry run: the issue changes below were planned, not performed.</p>
+{{- end}}
+<h2>Summary</h2>
+{{if .M.Summaries}}<ul>
+{{- range .M.Summaries}}
+<li>{{if .Span}}[{{.Span}}] {{end}}{{.Summary}}</li>
+{{- end}}
+</ul>{{else}}<p class="empty">No summary was extracted.</p>{{end}}
+<h2>Decisions</h2>
+{{if .M.Decisions}}<ul>
+{{- range .M.Decisions}}
+<li>{{.}}</li>
+{{- end}}
+</ul>{{else}}<p class="empty">No decisions were recorded.</p>{{end}}
+<h2>Action items</h2>
+{{if .M.Items}}<ul>
+{{- range .M.Items}}
+<li><strong>{{.Title}}</strong>{{with .Details}} ({{.}}){{end}}
+{{- if .Links}}
+<ul>
+{{- range .Links}}
+<li>{{template "link" .}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</li>
+{{- end}}
+</ul>{{else}}<p class="empty">No action items.</p>{{end}}
+{{- if .M.Other}}
+<p>Other issue changes:</p>
+<ul>
+{{- range .M.Other}}
+<li>{{template "link" .}}</li>
+{{- end}}
+</ul>
+{{- end}}
+<h2>Open questions</h2>
+{{if .M.OpenQuestions}}<ul>
+{{- range .M.OpenQuestions}}
+<li>{{.}}</li>
+{{- end}}
+</ul>{{else}}<p class="empty">No open questions.</p>{{end}}
+</body>
+</html>
+{{define "link"}}{{if .URL}}<a href="{{.URL}}">{{.Ref}}</a>{{else}}{{.Ref}}{{end}} {{.Note}}{{end}}`))
+
+// html renders the minutes as a standalone HTML page
+func (m *Minutes) html() (string, error) {
+	type item struct {
+		MinutesItem
+		Details string
+	}
+	type minutes struct {
+		Minutes
+		Items []item
+	}
+	view := minutes{Minutes: *m}
+	for _, i := range m.Items {
+		view.Items = append(view.Items, item{MinutesItem: i, Details: i.details()})
+	}
+	var buf bytes.Buffer
+	if err := minutesHTML.Execute(&buf, map[string]any{"Title": m.title(), "M": view}); err != nil {
+		return "", fmt.Errorf("failed to render minutes: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// name is the file and page name of the minutes, e.g. 2025-01-31-standup-minutes
+func (m *Minutes) name() string {
+	base := strings.TrimSuffix(m.Meeting.Source, filepath.Ext(m.Meeting.Source))
+	base = strings.Trim(unsafeNameChars.ReplaceAllString(base, "-"), "-")
+	if base == "" {
+		base = "meeting"
+	}
+	return fmt.Sprintf("%s-%s-minutes", m.Meeting.Date, base)
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// writeMinutes writes the minutes to cfg.Dir and, unless dry, publishes them
+// where configured. Failed publishers are reported but do not fail the run.
+func writeMinutes(ctx context.Context, cfg MinutesConfig, m *Minutes) (*MinutesResult, error) {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = "minutes"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create minutes directory: %v", err)
+	}
+	md := m.markdown()
+	html, err := m.html()
+	if err != nil {
+		return nil, err
+	}
+	res := &MinutesResult{
+		Markdown: filepath.Join(dir, m.name()+".md"),
+		HTML:     filepath.Join(dir, m.name()+".html"),
+	}
+	if err := os.WriteFile(res.Markdown, []byte(md), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write minutes: %v", err)
+	}
+	if err := os.WriteFile(res.HTML, []byte(html), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write minutes: %v", err)
+	}
+	fmt.Printf("Meeting minutes written to %s and %s\n", res.Markdown, res.HTML)
+
+	if m.DryRun {
+		if cfg.Discussion != nil || cfg.Wiki != nil || cfg.Email != nil {
+			fmt.Println("Dry run: the minutes are not published")
+		}
+		return res, nil
+	}
+	publish := func(what string, fn func() (string, error)) {
+		where, err := fn()
+		if err != nil {
+			fmt.Printf("Failed to publish the minutes as %s: %v\n", what, err)
+			res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", what, err))
+			return
+		}
+		fmt.Printf("Minutes published as %s: %s\n", what, where)
+		res.Published = append(res.Published, where)
+	}
+	if cfg.Discussion != nil {
+		publish("discussion", func() (string, error) { return postDiscussion(ctx, *cfg.Discussion, m, md) })
+	}
+	if cfg.Wiki != nil {
+		publish("wiki page", func() (string, error) { return pushWikiPage(ctx, *cfg.Wiki, m, md) })
+	}
+	if cfg.Email != nil {
+		publish("email", func() (string, error) { return sendMinutesEmail(*cfg.Email, m, md, html) })
+	}
+	return res, nil
+}
+
+// postDiscussion starts a GitHub Discussion with the minutes
+func postDiscussion(ctx context.Context, cfg DiscussionConfig, m *Minutes, md string) (string, error) {
+	repo := cfg.Repo
+	if repo == "" {
+		repo = m.Repo
+	}
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = os.Getenv("GITHUB_API_URL")
+	}
+	tokenEnv := cfg.TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = "GITHUB_TOKEN"
+	}
+	token := os.Getenv(tokenEnv)
+	if token == "" {
+		return "", fmt.Errorf("token not found in environment variable %s", tokenEnv)
+	}
+	client := github.NewClient(github.Config{BaseURL: apiURL, Token: token})
+	d, err := client.CreateDiscussion(ctx, repo, cfg.Category, m.title(), md)
+	if err != nil {
+		return "", err
+	}
+	return d.URL, nil
+}
+
+// pushWikiPage commits the minutes to the wiki repository and pushes them.
+// The token is passed to git in the environment, never on the command line.
+func pushWikiPage(ctx context.Context, cfg WikiConfig, m *Minutes, md string) (string, error) {
+	url := strings.ReplaceAll(cfg.URL, "{repo}", m.Repo)
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if cfg.TokenEnv != "" {
+		token := os.Getenv(cfg.TokenEnv)
+		if token == "" {
+			return "", fmt.Errorf("token not found in environment variable %s", cfg.TokenEnv)
+		}
+		user := cfg.Username
+		if user == "" {
+			user = "oauth2"
+		}
+		auth := base64.StdEncoding.EncodeToString([]byte(user + ":" + token))
+		env = append(env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth)
+	}
+
+	dir, err := os.MkdirTemp("", "james-wiki-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	git := func(args ...string) error {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		cmd.Env = env
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	if err := git("clone", "--depth", "1", url, "."); err != nil {
+		return "", err
+	}
+	page := m.name() + ".md"
+	if err := os.WriteFile(filepath.Join(dir, page), []byte(md), 0644); err != nil {
+		return "", fmt.Errorf("failed to write wiki page: %v", err)
+	}
+	if err := git("add", page); err != nil {
+		return "", err
+	}
+	if err := git("-c", "user.name=james-agent", "-c", "user.email=james-agent@users.noreply.localhost",
+		"commit", "-m", "Add "+m.title()); err != nil {
+		return "", err
+	}
+	if err := git("push", "origin", "HEAD"); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s in %s", m.name(), url), nil
+}
+
+// sendMinutesEmail mails the minutes as Markdown text with an HTML alternative
+func sendMinutesEmail(cfg EmailConfig, m *Minutes, md, html string) (string, error) {
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		passwordEnv := cfg.PasswordEnv
+		if passwordEnv == "" {
+			passwordEnv = "JAMES_SMTP_PASSWORD"
+		}
+		password := os.Getenv(passwordEnv)
+		if password == "" {
+			return "", fmt.Errorf("smtp password not found in environment variable %s", passwordEnv)
+		}
+		// PlainAuth refuses to send the password without TLS, except to localhost
+		auth = smtp.PlainAuth("", cfg.Username, password, cfg.Host)
+	}
+
+	msg, err := minutesMessage(cfg, m.title(), md, html)
+	if err != nil {
+		return "", err
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	if err := smtp.SendMail(addr, auth, cfg.From, cfg.To, msg); err != nil {
+		return "", err
+	}
+	return strings.Join(cfg.To, ", "), nil
+}
+
+// minutesMessage builds a multipart/alternative message
+func minutesMessage(cfg EmailConfig, subject, md, html string) ([]byte, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", md},
+		{"text/html; charset=utf-8", html},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to build email: %v", err)
+		}
+		if _, err := pw.Write([]byte(wrapBase64(part.content))); err != nil {
+			return nil, fmt.Errorf("failed to build email: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build email: %v", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// wrapBase64 encodes s in lines of 76 characters, as MIME requires
+func wrapBase64(s string) string {
+	enc := base64.StdEncoding.EncodeToString([]byte(s))
+	var b strings.Builder
+	for len(enc) > 76 {
+		b.WriteString(enc[:76] + "\r\n")
+		enc = enc[76:]
+	}
+	b.WriteString(enc + "\r\n")
+	return b.String()
+}
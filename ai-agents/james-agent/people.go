@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Person maps a meeting participant to a GitHub login
+type Person struct {
+	Name    string   `yaml:"name" json:"name"`
+	Login   string   `yaml:"login" json:"login"`
+	Aliases []string `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+}
+
+// PeopleDirectory maps speaker names in transcripts to GitHub logins
+type PeopleDirectory struct {
+	People []Person `yaml:"people"`
+}
+
+// people is the loaded directory, empty when no file is configured
+var people = &PeopleDirectory{}
+
+// loadPeople reads a people directory file. A missing file is only an error when required.
+func loadPeople(path string, required bool) (*PeopleDirectory, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !required {
+		return &PeopleDirectory{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read people directory: %v", err)
+	}
+
+	var dir PeopleDirectory
+	if err := yaml.Unmarshal(data, &dir); err != nil {
+		return nil, fmt.Errorf("failed to parse people directory %s: %v", path, err)
+	}
+	for i, p := range dir.People {
+		if p.Name == "" || p.Login == "" {
+			return nil, fmt.Errorf("people directory %s: entry %d needs a name and a login", path, i+1)
+		}
+	}
+	return &dir, nil
+}
+
+// lookup returns the login of a speaker, matching names and aliases case-insensitively
+func (d *PeopleDirectory) lookup(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	for _, p := range d.People {
+		if strings.EqualFold(p.Name, name) {
+			return p.Login, true
+		}
+		for _, alias := range p.Aliases {
+			if strings.EqualFold(alias, name) {
+				return p.Login, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"james-agent/main/tracker"
+	"james-agent/main/transcript"
+)
+
+// Action modes of GitHubMCPServerAction
+const (
+	modeExecute     = "execute"
+	modeDryRun      = "dry-run"
+	modeInteractive = "interactive"
+)
+
+// actionMode decides whether GitHub actions are performed, planned or confirmed first
+var actionMode = modeExecute
+
+// PlannedAction is a GitHub action recorded by a dry run
+type PlannedAction struct {
+	Action    string          `json:"action"`
+	IssueData GitHubIssueData `json:"issueData"`
+	// Result is filled in when the plan is applied, applied actions are skipped on a second run
+	Result *GitHubActionResult `json:"result,omitempty"`
+}
+
+// Plan is the reviewable output of a dry run
+type Plan struct {
+	Transcript    string          `json:"transcript"`
+	Repo          string          `json:"repo"`
+	PromptVersion string          `json:"promptVersion"`
+	Meeting       Meeting         `json:"meeting"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	Actions       []PlannedAction `json:"actions"`
+
+	mu sync.Mutex
+}
+
+// actionPlan collects the actions of a dry run
+var actionPlan = &Plan{}
+
+// actionLog collects every action of a run with its result, for the daemon's status API
+var actionLog = &Plan{}
+
+// record adds an action with its result
+func (p *Plan) record(args GitHubActionParams, result GitHubActionResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Actions = append(p.Actions, PlannedAction{Action: args.Action, IssueData: args.IssueData, Result: &result})
+}
+
+// actions returns a copy of the recorded actions
+func (p *Plan) actions() []PlannedAction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PlannedAction(nil), p.Actions...)
+}
+
+// add records an action
+func (p *Plan) add(args GitHubActionParams) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Actions = append(p.Actions, PlannedAction{Action: args.Action, IssueData: args.IssueData})
+	return len(p.Actions)
+}
+
+// save writes the plan as JSON to path and as Markdown next to it
+func (p *Plan) save(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write plan: %v", err)
+	}
+	mdPath := strings.TrimSuffix(path, ".json") + ".md"
+	if err := os.WriteFile(mdPath, []byte(p.markdown()), 0644); err != nil {
+		return fmt.Errorf("failed to write plan summary: %v", err)
+	}
+	return nil
+}
+
+// markdown renders the plan for review
+func (p *Plan) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Planned GitHub actions for %s\n\n", p.Repo)
+	fmt.Fprintf(&b, "- Transcript: `%s`\n", p.Transcript)
+	fmt.Fprintf(&b, "- Meeting: %s on %s\n", p.Meeting.reference(), p.Meeting.Date)
+	fmt.Fprintf(&b, "- Prompt: `%s`\n", p.PromptVersion)
+	fmt.Fprintf(&b, "- Created: %s\n\n", p.CreatedAt.Format(time.RFC1123))
+	if len(p.Actions) == 0 {
+		b.WriteString("No actions were planned.\n")
+	}
+	for i, a := range p.Actions {
+		fmt.Fprintf(&b, "## %d. %s\n\n", i+1, describeAction(GitHubActionParams{Action: a.Action, IssueData: a.IssueData}))
+		if a.IssueData.Title != "" && a.Action != "create" {
+			fmt.Fprintf(&b, "New title: %s\n\n", a.IssueData.Title)
+		}
+		if len(a.IssueData.Labels) > 0 {
+			fmt.Fprintf(&b, "Labels: %s\n\n", strings.Join(a.IssueData.Labels, ", "))
+		}
+		if len(a.IssueData.Assignees) > 0 {
+			fmt.Fprintf(&b, "Assignees: %s\n\n", strings.Join(a.IssueData.Assignees, ", "))
+		}
+		if a.IssueData.Milestone != "" {
+			fmt.Fprintf(&b, "Milestone: #%s\n\n", a.IssueData.Milestone)
+		}
+		if a.IssueData.Type != "" {
+			fmt.Fprintf(&b, "Type: %s\n\n", a.IssueData.Type)
+		}
+		if a.IssueData.Evidence != "" {
+			fmt.Fprintf(&b, "Evidence: \"%s\"\n\n", a.IssueData.Evidence)
+		}
+		if a.IssueData.Body != "" {
+			for _, line := range strings.Split(a.IssueData.Body, "\n") {
+				b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+			}
+			b.WriteString("\n")
+		}
+		if a.Result != nil {
+			fmt.Fprintf(&b, "Applied: %s\n\n", a.Result.Status)
+		}
+	}
+	return b.String()
+}
+
+// loadPlan reads a plan written by a dry run
+func loadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %v", err)
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %v", path, err)
+	}
+	return &plan, nil
+}
+
+// applyPlan performs the actions of a plan that have not been applied yet.
+// The plan file is rewritten after each action, so a failed run can be resumed.
+func applyPlan(ctx context.Context, path string, interactive bool) error {
+	plan, err := loadPlan(path)
+	if err != nil {
+		return err
+	}
+	// Updates reference the meeting the plan was made from
+	currentMeeting = plan.Meeting
+	targetRepo = plan.Repo
+	previousRun = ledger.history(plan.Meeting.Hash)
+	// The transcript checks the evidence of closes again, when it is still there
+	if t, err := transcript.ParseFile(plan.Transcript); err == nil {
+		currentTranscript = t
+	} else {
+		fmt.Printf("Transcript %s not available, close evidence is not checked again: %v\n", plan.Transcript, err)
+	}
+	if err := ledger.startRun(plan.Meeting); err != nil {
+		return err
+	}
+
+	failed := 0
+	for i := range plan.Actions {
+		a := &plan.Actions[i]
+		args := GitHubActionParams{Action: a.Action, IssueData: a.IssueData}
+		if a.Result != nil && a.Result.Status == "success" {
+			fmt.Printf("%d. Already applied: %s\n", i+1, describeAction(args))
+			continue
+		}
+
+		var result GitHubActionResult
+		if err := prepareAction(ctx, &args); err != nil {
+			result = GitHubActionResult{Status: "error", ErrorMessage: err.Error()}
+		} else if interactive && !confirmAction(args) {
+			result = GitHubActionResult{Status: "skipped"}
+		} else {
+			result = executeAction(ctx, args)
+		}
+		a.Result = &result
+
+		fmt.Printf("%d. %s: %s %s\n", i+1, describeAction(args), result.Status, result.ErrorMessage)
+		if result.Status == "error" {
+			failed++
+		}
+		if err := plan.save(path); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d actions failed", failed, len(plan.Actions))
+	}
+	return nil
+}
+
+// describeAction is a one line summary of an action
+func describeAction(args GitHubActionParams) string {
+	d := args.IssueData
+	switch args.Action {
+	case "create":
+		return fmt.Sprintf("Create issue in %s: %s", d.Repo, d.Title)
+	case "update":
+		if d.Mode == updateModeAppendBody {
+			return fmt.Sprintf("Append meeting notes to %s#%d", d.Repo, d.Number)
+		}
+		if d.Body != "" {
+			return fmt.Sprintf("Comment on %s#%d", d.Repo, d.Number)
+		}
+		return fmt.Sprintf("Update %s#%d", d.Repo, d.Number)
+	case "comment":
+		return fmt.Sprintf("Comment on %s#%d", d.Repo, d.Number)
+	case "close":
+		if d.StateReason == tracker.ReasonNotPlanned {
+			return fmt.Sprintf("Close %s#%d as not planned", d.Repo, d.Number)
+		}
+		return fmt.Sprintf("Close %s#%d as completed", d.Repo, d.Number)
+	}
+	return fmt.Sprintf("%s %s#%d", args.Action, d.Repo, d.Number)
+}
+
+var (
+	stdinMu     sync.Mutex
+	stdinReader = bufio.NewReader(os.Stdin)
+)
+
+// confirmAction shows an action on the terminal and asks whether to perform it
+func confirmAction(args GitHubActionParams) bool {
+	stdinMu.Lock()
+	defer stdinMu.Unlock()
+
+	fmt.Printf("\n%s\n", describeAction(args))
+	if args.IssueData.Evidence != "" {
+		fmt.Printf("Evidence: %q\n", args.IssueData.Evidence)
+	}
+	if args.IssueData.Body != "" {
+		fmt.Printf("%s\n", args.IssueData.Body)
+	}
+	fmt.Print("Perform this action? [y/N] ")
+	answer, err := stdinReader.ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"james-agent/main/tracker"
+	"james-agent/main/tracker/trackertest"
+)
+
+// TestApplyPlanRefusesForbiddenClose applies a reviewed plan whose close the
+// configuration forbids, and checks the issue is left open
+func TestApplyPlanRefusesForbiddenClose(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		policy  ClosePolicy
+		repos   []RepoEntry
+		label   string
+		repo    string
+		refusal string
+	}{
+		{name: "disabled", policy: ClosePolicy{Disabled: true}, refusal: "disabled by the close policy"},
+		{name: "protected label", policy: ClosePolicy{ProtectedLabels: []string{"P1"}}, label: "P1", refusal: "protected label"},
+		{name: "other author", policy: ClosePolicy{Authors: []string{"bob"}}, refusal: "opened by alice"},
+		{name: "repo outside the catalogue", repos: []RepoEntry{{Name: "acme/web", Description: "Web app"}}, repo: "acme/other", refusal: "not in the catalogue"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := trackertest.NewGitHub()
+			defer srv.Close()
+			tr, err := tracker.New(srv.Config())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.label != "" {
+				if err := tr.AddLabels(context.Background(), srv.Repo, 1, []string{tc.label}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			repo := srv.Repo
+			if tc.repo != "" {
+				repo = tc.repo
+			}
+
+			savedTracker, savedPolicy, savedRepos, savedLedger := issueTracker, closePolicy, repoCatalogue, ledger
+			defer func() {
+				issueTracker, closePolicy, repoCatalogue, ledger = savedTracker, savedPolicy, savedRepos, savedLedger
+				currentTranscript = nil
+			}()
+			issueTracker, closePolicy, repoCatalogue, ledger = tr, tc.policy, tc.repos, nil
+
+			path := filepath.Join(t.TempDir(), "plan.json")
+			plan := &Plan{
+				Transcript: filepath.Join(t.TempDir(), "missing.txt"),
+				Repo:       srv.Repo,
+				Actions: []PlannedAction{{Action: "close", IssueData: GitHubIssueData{
+					Repo: repo, Number: 1, Evidence: "That one is done.",
+				}}},
+			}
+			data, err := json.Marshal(plan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := applyPlan(context.Background(), path, false); err == nil {
+				t.Fatal("applyPlan succeeded, want the close refused")
+			}
+			applied, err := loadPlan(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := applied.Actions[0].Result
+			if result == nil || result.Status != "error" || !strings.Contains(result.ErrorMessage, tc.refusal) {
+				t.Fatalf("result = %+v, want an error containing %q", result, tc.refusal)
+			}
+			issue, err := tr.GetIssue(context.Background(), srv.Repo, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if issue.State != "open" || slices.ContainsFunc(srv.Requests, func(r string) bool { return strings.HasPrefix(r, "POST") && strings.HasSuffix(r, "/comments") }) {
+				t.Fatalf("issue was touched: state %s, requests %v", issue.State, srv.Requests)
+			}
+		})
+	}
+}
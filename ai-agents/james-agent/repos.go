@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"james-agent/main/similarity"
+
+	"google.golang.org/adk/tool"
+)
+
+// Routing weights
+const (
+	// keywordBoost is added to the text similarity for every catalogue keyword in the item
+	keywordBoost = 0.25
+	// routeThreshold is the score below which an item stays in the default repository
+	routeThreshold = 0.15
+)
+
+// RepoEntry describes a repository action items can be filed in
+type RepoEntry struct {
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description" json:"description"`
+	Keywords    []string `yaml:"keywords" json:"keywords,omitempty"`
+}
+
+// repoCatalogue is the configured repositories. When empty, every item goes to targetRepo.
+var repoCatalogue []RepoEntry
+
+// validateCatalogue reports configuration mistakes before any run
+func validateCatalogue(entries []RepoEntry) error {
+	seen := map[string]bool{}
+	for i, e := range entries {
+		switch {
+		case e.Name == "":
+			return fmt.Errorf("repos: entry %d needs a name", i+1)
+		case seen[e.Name]:
+			return fmt.Errorf("repos: %s is listed twice", e.Name)
+		case e.Description == "" && len(e.Keywords) == 0:
+			return fmt.Errorf("repos: %s needs a description or keywords to route items to it", e.Name)
+		}
+		seen[e.Name] = true
+	}
+	return nil
+}
+
+// catalogued reports whether items may be filed in repo
+func catalogued(repo string) bool {
+	if len(repoCatalogue) == 0 || repo == targetRepo {
+		return true
+	}
+	for _, e := range repoCatalogue {
+		if e.Name == repo {
+			return true
+		}
+	}
+	return false
+}
+
+// catalogueNames lists the repositories items may be filed in
+func catalogueNames() string {
+	return strings.Join(catalogueRepos(), ", ")
+}
+
+// catalogueRepos returns the repositories items may be filed in, the default first
+func catalogueRepos() []string {
+	names := []string{}
+	if targetRepo != "" {
+		names = append(names, targetRepo)
+	}
+	for _, e := range repoCatalogue {
+		if e.Name != targetRepo {
+			names = append(names, e.Name)
+		}
+	}
+	return names
+}
+
+// RouteActionItem tool structs and function
+type RouteActionItemParams struct {
+	Title       string `json:"title" jsonschema:"The action item title"`
+	Description string `json:"description,omitempty" jsonschema:"The action item description"`
+}
+
+type RepoCandidate struct {
+	Repo  string  `json:"repo"`
+	Score float64 `json:"score"`
+	// Keywords are the catalogue keywords found in the item
+	Keywords []string `json:"matchedKeywords,omitempty"`
+}
+
+type RouteActionItemResult struct {
+	Status       string          `json:"status"`
+	Repo         string          `json:"repo,omitempty"`
+	Reason       string          `json:"reason,omitempty"`
+	Candidates   []RepoCandidate `json:"candidates,omitempty"`
+	ErrorMessage string          `json:"errorMessage,omitempty"`
+}
+
+func RouteActionItem(ctx tool.Context, args RouteActionItemParams) RouteActionItemResult {
+	text := strings.TrimSpace(args.Title + "\n" + args.Description)
+	if text == "" {
+		return RouteActionItemResult{Status: "error", ErrorMessage: "Missing 'title'."}
+	}
+	result := routeItem(text)
+	fmt.Printf("Routed %q to %s: %s\n", args.Title, result.Repo, result.Reason)
+	return result
+}
+
+// routeItem scores the catalogue by TF-IDF similarity to the item plus a boost
+// for every keyword it mentions. Items without a clear match stay in targetRepo.
+func routeItem(text string) RouteActionItemResult {
+	if len(repoCatalogue) == 0 {
+		return RouteActionItemResult{Status: "success", Repo: targetRepo, Reason: "no repository catalogue is configured"}
+	}
+
+	docs := make([]similarity.Document, len(repoCatalogue))
+	for i, e := range repoCatalogue {
+		_, short, _ := strings.Cut(e.Name, "/")
+		keywords := strings.Join(e.Keywords, " ")
+		docs[i] = similarity.Document{ID: strconv.Itoa(i), Text: short + "\n" + e.Description + "\n" + keywords + "\n" + keywords}
+	}
+	matches, _ := similarity.NewTFIDF(docs).Search(context.Background(), text, 0)
+	scores := make([]float64, len(repoCatalogue))
+	for _, m := range matches {
+		i, _ := strconv.Atoi(m.ID)
+		scores[i] = m.Score
+	}
+
+	words := " " + normalizeQuote(text) + " "
+	candidates := make([]RepoCandidate, len(repoCatalogue))
+	for i, e := range repoCatalogue {
+		c := RepoCandidate{Repo: e.Name}
+		for _, k := range e.Keywords {
+			if nk := normalizeQuote(k); nk != "" && strings.Contains(words, " "+nk+" ") {
+				c.Keywords = append(c.Keywords, k)
+			}
+		}
+		c.Score = math.Round(math.Min(1, scores[i]+keywordBoost*float64(len(c.Keywords)))*100) / 100
+		candidates[i] = c
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+	best := candidates[0]
+	switch {
+	case best.Score < routeThreshold:
+		return RouteActionItemResult{Status: "success", Repo: targetRepo, Candidates: candidates,
+			Reason: fmt.Sprintf("no repository matched clearly (best %s with %.2f), using the default", best.Repo, best.Score)}
+	case len(candidates) > 1 && candidates[1].Score == best.Score:
+		return RouteActionItemResult{Status: "success", Repo: targetRepo, Candidates: candidates,
+			Reason: fmt.Sprintf("%s and %s match equally, using the default; pick one of them if the item clearly belongs there", best.Repo, candidates[1].Repo)}
+	}
+	reason := fmt.Sprintf("best match with score %.2f", best.Score)
+	if len(best.Keywords) > 0 {
+		reason += ", mentions " + strings.Join(best.Keywords, ", ")
+	}
+	return RouteActionItemResult{Status: "success", Repo: best.Repo, Reason: reason, Candidates: candidates}
+}
+
+// RepoSummary is what a run did in one repository
+type RepoSummary struct {
+	Repo    string         `json:"repo"`
+	Created []IssueSummary `json:"created,omitempty"`
+	// Updated includes issues that were commented on
+	Updated []IssueSummary `json:"updated,omitempty"`
+	Closed  []IssueSummary `json:"closed,omitempty"`
+	// Planned counts the actions of a dry run
+	Planned int `json:"planned,omitempty"`
+}
+
+// summarizeByRepo groups the performed and planned actions of a run by repository
+func summarizeByRepo(actions []PlannedAction) []RepoSummary {
+	var out []RepoSummary
+	index := map[string]int{}
+	for _, a := range actions {
+		if a.Result == nil || (a.Result.Status != "success" && a.Result.Status != "planned") {
+			continue
+		}
+		repo := a.IssueData.Repo
+		i, ok := index[repo]
+		if !ok {
+			i = len(out)
+			index[repo] = i
+			out = append(out, RepoSummary{Repo: repo})
+		}
+		s := &out[i]
+		if a.Result.Status == "planned" {
+			s.Planned++
+			continue
+		}
+		issue := IssueSummary{Number: a.IssueData.Number}
+		if a.Result.Issue != nil {
+			issue = IssueSummary{Number: a.Result.Issue.Number, Title: a.Result.Issue.Title, URL: a.Result.Issue.URL}
+		}
+		switch a.Action {
+		case "create":
+			s.Created = append(s.Created, issue)
+		case "close":
+			s.Closed = append(s.Closed, issue)
+		default:
+			if !slices.ContainsFunc(s.Updated, func(u IssueSummary) bool { return u.Number == issue.Number }) {
+				s.Updated = append(s.Updated, issue)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Repo < out[j].Repo })
+	return out
+}
+
+// printRepoSummary prints one line per repository
+func printRepoSummary(summaries []RepoSummary) {
+	if len(summaries) == 0 {
+		return
+	}
+	fmt.Println("Issues by repository:")
+	for _, s := range summaries {
+		var parts []string
+		for _, g := range []struct {
+			verb   string
+			issues []IssueSummary
+		}{{"created", s.Created}, {"updated", s.Updated}, {"closed", s.Closed}} {
+			if len(g.issues) == 0 {
+				continue
+			}
+			numbers := make([]string, len(g.issues))
+			for i, issue := range g.issues {
+				numbers[i] = "#" + strconv.Itoa(issue.Number)
+			}
+			parts = append(parts, fmt.Sprintf("%d %s (%s)", len(g.issues), g.verb, strings.Join(numbers, ", ")))
+		}
+		if s.Planned > 0 {
+			parts = append(parts, fmt.Sprintf("%d planned", s.Planned))
+		}
+		fmt.Printf("  %s: %s\n", s.Repo, strings.Join(parts, ", "))
+	}
+}
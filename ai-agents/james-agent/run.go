@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"james-agent/main/transcript"
+
+	adkagent "google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model/gemini"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+	"google.golang.org/genai"
+)
+
+// RunOptions describe one transcript to process
+type RunOptions struct {
+	Transcript  string
+	Repo        string
+	MeetingURL  string
+	MeetingDate string
+	// Mode is modeExecute, modeDryRun or modeInteractive
+	Mode string
+	// PlanPath is where a dry run writes its plan
+	PlanPath string
+	// ActionsPath is where the validated action items are written for audit,
+	// actionsOutPath when empty
+	ActionsPath string
+}
+
+// RunResult is what a run did
+type RunResult struct {
+	Meeting       Meeting         `json:"meeting"`
+	PromptVersion string          `json:"promptVersion"`
+	Actions       []PlannedAction `json:"actions"`
+	PlanPath      string          `json:"planPath,omitempty"`
+	// ActionsPath is the audit file of the validated action items
+	ActionsPath string `json:"actionsPath,omitempty"`
+	// Repos summarises the issues created, updated and closed per repository
+	Repos []RepoSummary `json:"repos,omitempty"`
+	// Minutes is where the meeting minutes were written and published
+	Minutes *MinutesResult `json:"minutes,omitempty"`
+	// StreamErrors are the errors reported while the agent ran, such as failed model calls
+	StreamErrors []string `json:"streamErrors,omitempty"`
+}
+
+// mcpToolsets are the MCP servers of the configured mode, shared by every run
+var mcpToolsets []tool.Toolset
+
+// runTranscript runs the agent on one transcript. Runs share the package state
+// of the tools, so they must not overlap; the daemon queues them.
+func runTranscript(ctx context.Context, opts RunOptions) (*RunResult, error) {
+	// Reset the state of the previous run
+	targetRepo = opts.Repo
+	actionMode = opts.Mode
+	actionPlan = &Plan{}
+	actionLog = &Plan{}
+	currentActionsPath = opts.ActionsPath
+	if currentActionsPath == "" {
+		currentActionsPath = actionsOutPath
+	}
+	extractionsMu.Lock()
+	extractions = map[string]*MeetingExtraction{}
+	extractionsMu.Unlock()
+
+	// Verify file exists
+	if _, err := os.Stat(opts.Transcript); os.IsNotExist(err) {
+		return nil, fmt.Errorf("File not found: %s", opts.Transcript)
+	}
+	meeting, err := newMeeting(opts.Transcript, opts.MeetingURL, opts.MeetingDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read meeting details: %v", err)
+	}
+	currentMeeting = meeting
+	currentTranscript, err = transcript.ParseFile(opts.Transcript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %v", err)
+	}
+
+	previousRun = ledger.history(meeting.Hash)
+	if previousRun != nil {
+		fmt.Printf("%s was processed %d time(s) before, last on %s, with %d recorded actions; they will not be repeated\n",
+			meeting.Source, previousRun.Runs, previousRun.LastRunAt.Format("2006-01-02 15:04"), len(previousRun.Entries))
+	}
+	// A dry run only plans, so it does not count as a run
+	if actionMode != modeDryRun {
+		if err := ledger.startRun(meeting); err != nil {
+			return nil, fmt.Errorf("failed to update ledger: %v", err)
+		}
+	}
+
+	// Create model
+	model, err := gemini.NewModel(ctx, modelName, &genai.ClientConfig{
+		APIKey: os.Getenv("GOOGLE_API_KEY"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create model: %v", err)
+	}
+
+	transcriptTool, err := functiontool.New(functiontool.Config{
+		Name:        "GenerateSystemPromptFromTranscript",
+		Description: "Reads a meeting transcript file (WebVTT, SRT, Zoom, Teams, Google Meet, Otter or plain text) and generates a system prompt from its speaker attributed, timestamped contents with GitHub action suggestions",
+	}, GenerateSystemPromptFromTranscript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GenerateSystemPromptFromTranscript tool: %v", err)
+	}
+
+	githubActionTool, err := functiontool.New(functiontool.Config{
+		Name:        "GitHubMCPServerAction",
+		Description: "Tool for interacting with the configured issue tracker (GitHub, GitLab, Gitea or Jira) to create, update, comment on, or close issues. Mermaid diagrams in the body are syntax checked first; if errors are returned, fix the diagrams and call again.",
+	}, GitHubMCPServerAction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHubMCPServerAction tool: %v", err)
+	}
+
+	githubListTool, err := functiontool.New(functiontool.Config{
+		Name:        "GitHubMCPServerListIssues",
+		Description: "Lists existing issues (pull requests excluded) for a repository, optionally filtered by labels, assignee, milestone or update date. Use this to check for duplicates before creating new issues. If 'truncated' is true, not every issue was listed.",
+	}, GitHubMCPServerListIssues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHubMCPServerListIssues tool: %v", err)
+	}
+
+	similarTool, err := functiontool.New(functiontool.Config{
+		Name:        "FindSimilarIssues",
+		Description: "Ranks the open issues of a repository by similarity to a text, such as an action item from the meeting. Returns candidates with scores from 0 to 1 to decide between updating an existing issue and creating a new one.",
+	}, FindSimilarIssues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create FindSimilarIssues tool: %v", err)
+	}
+
+	metadataTool, err := functiontool.New(functiontool.Config{
+		Name:        "GetRepoMetadata",
+		Description: "Lists the labels, open milestones, issue types and assignable users of a repository, and the people directory mapping speaker names to tracker logins.",
+	}, GetRepoMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GetRepoMetadata tool: %v", err)
+	}
+
+	tools := []tool.Tool{transcriptTool, githubActionTool, githubListTool, similarTool, metadataTool}
+	if len(repoCatalogue) > 0 {
+		routeTool, err := functiontool.New(functiontool.Config{
+			Name:        "RouteActionItem",
+			Description: "Chooses the repository of the catalogue an action item belongs in, from the repository descriptions and keywords. Returns the repository with a reason and the scored candidates; items without a clear match go to the default repository.",
+		}, RouteActionItem)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create RouteActionItem tool: %v", err)
+		}
+		tools = append(tools, routeTool)
+	}
+
+	// Render the agent instruction from the prompt store
+	prompt, err := prompts.Select(jamesPromptName, opts.Transcript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select prompt: %v", err)
+	}
+	agentInstruction, err := prompt.Render(JamesPromptVars{RepoName: opts.Repo, Repos: repoCatalogue})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt: %v", err)
+	}
+	fmt.Printf("Using prompt %s\n", prompt.ID())
+
+	// Create agent
+	agent, err := llmagent.New(llmagent.Config{
+		Name:        "james_agent",
+		Model:       model,
+		Description: "Agent that processes meeting transcripts, generates actionable prompts, and interacts with GitHub MCP server to manage issues based on meeting discussions.",
+		Instruction: agentInstruction,
+		Tools:       tools,
+		Toolsets:    mcpToolsets,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agent: %v", err)
+	}
+
+	// Create session service
+	sessionService := session.InMemoryService()
+
+	// Create runner
+	agentRunner, err := runner.New(runner.Config{
+		Agent:          agent,
+		AppName:        "james_agent",
+		SessionService: sessionService,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create runner: %v", err)
+	}
+
+	// Create session
+	userID := "user123"
+	appName := "james_agent"
+	sessResp, err := sessionService.Create(ctx, &session.CreateRequest{
+		AppName: appName,
+		UserID:  userID,
+		State: map[string]any{
+			"prompt_version": prompt.ID(),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating session: %v", err)
+	}
+
+	// Create message to process the transcript file
+	userMessage := fmt.Sprintf("Please process the meeting transcript from file '%s'. Follow your instructions to check for existing issues before creating any new ones in the %s repository.", opts.Transcript, opts.Repo)
+
+	// Run agent
+	msg := &genai.Content{
+		Role: "user",
+		Parts: []*genai.Part{
+			{Text: userMessage},
+		},
+	}
+	events := agentRunner.Run(ctx, userID, sessResp.Session.ID(), msg, adkagent.RunConfig{})
+
+	// Process events and display results
+	fmt.Printf("Processing transcript file: %s\n", opts.Transcript)
+	fmt.Println("Agent is working...")
+
+	result := &RunResult{Meeting: currentMeeting, PromptVersion: prompt.ID()}
+	for _, err := range events {
+		if err != nil {
+			// These are actually normal events, not errors
+			fmt.Printf("Error in event stream: %+v\n", err)
+			result.StreamErrors = append(result.StreamErrors, err.Error())
+		}
+	}
+	result.Actions = actionLog.actions()
+	result.Repos = summarizeByRepo(result.Actions)
+	printRepoSummary(result.Repos)
+	if actionMode == modeDryRun {
+		actionPlan.Transcript = opts.Transcript
+		actionPlan.Repo = opts.Repo
+		actionPlan.PromptVersion = prompt.ID()
+		actionPlan.Meeting = currentMeeting
+		actionPlan.CreatedAt = time.Now()
+		if err := actionPlan.save(opts.PlanPath); err != nil {
+			return result, fmt.Errorf("failed to save plan: %v", err)
+		}
+		result.PlanPath = opts.PlanPath
+		fmt.Printf("Dry run: %d actions planned in %s, review them and run with -apply %s\n", len(actionPlan.Actions), opts.PlanPath, opts.PlanPath)
+	}
+
+	extractionsMu.Lock()
+	extraction := extractions[opts.Transcript]
+	extractionsMu.Unlock()
+	if extraction != nil {
+		result.ActionsPath = currentActionsPath
+	}
+	minutes := newMinutes(opts.Repo, currentMeeting, extraction, result.Actions, actionMode == modeDryRun)
+	result.Minutes, err = writeMinutes(ctx, minutesConfig, minutes)
+	if err != nil {
+		return result, err
+	}
+
+	fmt.Println("Agent processing completed!")
+	return result, nil
+}
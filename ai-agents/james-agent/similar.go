@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"james-agent/main/similarity"
+	"james-agent/main/tracker"
+
+	"google.golang.org/adk/tool"
+)
+
+// similarIndexTTL is how long the index of a repository's open issues is reused
+const similarIndexTTL = 5 * time.Minute
+
+// issueIndex is the similarity index over the open issues of one repository
+type issueIndex struct {
+	index   similarity.Index
+	issues  map[string]tracker.Issue
+	builtAt time.Time
+}
+
+var (
+	issueIndexesMu sync.Mutex
+	issueIndexes   = map[string]*issueIndex{}
+)
+
+// newEmbedder returns the embeddings endpoint from the environment, or nil to use TF-IDF
+func newEmbedder() *similarity.Embedder {
+	url := os.Getenv("JAMES_EMBEDDINGS_URL")
+	if url == "" {
+		return nil
+	}
+	return &similarity.Embedder{
+		URL:    url,
+		Model:  envOr("JAMES_EMBEDDINGS_MODEL", "nomic-embed-text"),
+		APIKey: os.Getenv("JAMES_EMBEDDINGS_API_KEY"),
+	}
+}
+
+// getIssueIndex returns the cached index for repo, rebuilding it when it is stale
+func getIssueIndex(ctx context.Context, repo string) (*issueIndex, error) {
+	issueIndexesMu.Lock()
+	defer issueIndexesMu.Unlock()
+
+	if idx, ok := issueIndexes[repo]; ok && time.Since(idx.builtAt) < similarIndexTTL {
+		return idx, nil
+	}
+
+	list, err := issueTracker.ListIssues(ctx, repo, tracker.ListOptions{State: "open", Max: maxListLimit})
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &issueIndex{issues: make(map[string]tracker.Issue), builtAt: time.Now()}
+	docs := make([]similarity.Document, 0, len(list.Issues))
+	for _, issue := range list.Issues {
+		id := strconv.Itoa(issue.Number)
+		idx.issues[id] = issue
+		// The title is repeated so it weighs more than a long body
+		docs = append(docs, similarity.Document{ID: id, Text: issue.Title + "\n" + issue.Title + "\n" + issue.Body})
+	}
+
+	if embedder := newEmbedder(); embedder != nil {
+		emb, err := similarity.NewEmbeddings(ctx, embedder, docs)
+		if err == nil {
+			idx.index = emb
+		} else {
+			fmt.Printf("Embeddings unavailable, falling back to TF-IDF: %v\n", err)
+		}
+	}
+	if idx.index == nil {
+		idx.index = similarity.NewTFIDF(docs)
+	}
+
+	issueIndexes[repo] = idx
+	return idx, nil
+}
+
+// invalidateIssueIndex drops the cached index after issues were created or changed
+func invalidateIssueIndex(repo string) {
+	issueIndexesMu.Lock()
+	delete(issueIndexes, repo)
+	issueIndexesMu.Unlock()
+}
+
+// FindSimilarIssues tool structs and function
+type FindSimilarIssuesParams struct {
+	Repo string `json:"repo" jsonschema:"The repository (owner/repo)"`
+	Text string `json:"text" jsonschema:"The action item or topic from the meeting to look for"`
+	K    int    `json:"k,omitempty" jsonschema:"Number of candidates to return (default 5)"`
+}
+
+type SimilarIssue struct {
+	Number  int     `json:"number"`
+	Title   string  `json:"title"`
+	URL     string  `json:"url"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet,omitempty"`
+}
+
+type FindSimilarIssuesResult struct {
+	Status       string         `json:"status"`
+	Method       string         `json:"method,omitempty"`
+	Candidates   []SimilarIssue `json:"candidates"`
+	ErrorMessage string         `json:"errorMessage,omitempty"`
+	Code         int            `json:"code,omitempty"`
+}
+
+func FindSimilarIssues(ctx tool.Context, args FindSimilarIssuesParams) FindSimilarIssuesResult {
+	if issueTracker == nil {
+		return FindSimilarIssuesResult{Status: "error", ErrorMessage: trackerMissing}
+	}
+	if args.Text == "" {
+		return FindSimilarIssuesResult{Status: "error", ErrorMessage: "Missing 'text' to compare against."}
+	}
+	k := args.K
+	if k <= 0 {
+		k = 5
+	}
+
+	idx, err := getIssueIndex(ctx, args.Repo)
+	if err != nil {
+		msg, code := trackerError(err)
+		return FindSimilarIssuesResult{Status: "error", ErrorMessage: msg, Code: code}
+	}
+	matches, err := idx.index.Search(ctx, args.Text, k)
+	if err != nil {
+		return FindSimilarIssuesResult{Status: "error", ErrorMessage: fmt.Sprintf("Similarity search failed: %v", err)}
+	}
+
+	candidates := []SimilarIssue{}
+	for _, m := range matches {
+		issue := idx.issues[m.ID]
+		candidates = append(candidates, SimilarIssue{
+			Number:  issue.Number,
+			Title:   issue.Title,
+			URL:     issue.URL,
+			Score:   float64(int(m.Score*1000)) / 1000,
+			Snippet: snippet(issue.Body, snippetLength),
+		})
+	}
+	fmt.Printf("FindSimilarIssues(%q) found %d candidates using %s\n", args.Text, len(candidates), idx.index.Method())
+
+	return FindSimilarIssuesResult{Status: "success", Method: idx.index.Method(), Candidates: candidates}
+}
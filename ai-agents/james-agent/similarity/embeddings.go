@@ -0,0 +1,161 @@
+package similarity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// embedBatchSize is the number of texts sent per embeddings request
+const embedBatchSize = 64
+
+// Embedder calls an OpenAI-compatible /embeddings endpoint, such as a local
+// Ollama, llama.cpp or vLLM server.
+type Embedder struct {
+	// URL of the endpoint, e.g. http://localhost:11434/v1/embeddings
+	URL    string
+	Model  string
+	APIKey string
+
+	HTTPClient *http.Client
+}
+
+// Embed returns one vector per text.
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	var vectors [][]float64
+	for start := 0; start < len(texts); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(texts))
+		batch, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *Embedder) embedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	payload, err := json.Marshal(map[string]any{"model": e.Model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embeddings request: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embeddings request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+
+	client := e.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: time.Minute}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embeddings response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse embeddings response: %v", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings endpoint returned %d vectors for %d texts", len(result.Data), len(texts))
+	}
+
+	vectors := make([][]float64, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embeddings endpoint returned index %d out of range", d.Index)
+		}
+		vectors[d.Index] = normalize(d.Embedding)
+	}
+	return vectors, nil
+}
+
+// Embeddings is an index of document embeddings.
+type Embeddings struct {
+	embedder *Embedder
+	ids      []string
+	vectors  [][]float64
+}
+
+// NewEmbeddings embeds every document.
+func NewEmbeddings(ctx context.Context, embedder *Embedder, docs []Document) (*Embeddings, error) {
+	texts := make([]string, len(docs))
+	idx := &Embeddings{embedder: embedder}
+	for i, d := range docs {
+		texts[i] = d.Text
+		idx.ids = append(idx.ids, d.ID)
+	}
+	vectors, err := embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	idx.vectors = vectors
+	return idx, nil
+}
+
+// Method implements Index.
+func (idx *Embeddings) Method() string {
+	return "embeddings:" + idx.embedder.Model
+}
+
+// Search implements Index.
+func (idx *Embeddings) Search(ctx context.Context, text string, k int) ([]Match, error) {
+	q, err := idx.embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(idx.ids))
+	for i, v := range idx.vectors {
+		scores[i] = dot(q[0], v)
+	}
+	return topK(idx.ids, scores, k), nil
+}
+
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range min(len(a), len(b)) {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func normalize(v []float64) []float64 {
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+	norm = math.Sqrt(norm)
+	if norm == 0 {
+		return v
+	}
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+// Package similarity ranks documents by how similar they are to a query,
+// either with a local TF-IDF index or with embeddings from an
+// OpenAI-compatible endpoint.
+package similarity
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Document is an indexed text.
+type Document struct {
+	ID   string
+	Text string
+}
+
+// Match is a ranked search result. Score is the cosine similarity, from 0 to 1.
+type Match struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// Index finds the documents most similar to a text.
+type Index interface {
+	Search(ctx context.Context, text string, k int) ([]Match, error)
+	// Method names the scoring method, e.g. "tfidf"
+	Method() string
+}
+
+// TFIDF is an in-memory TF-IDF index.
+type TFIDF struct {
+	ids     []string
+	vectors []map[string]float64
+	idf     map[string]float64
+	// unseenIDF is the IDF of a term in no document, the highest there is
+	unseenIDF float64
+}
+
+// NewTFIDF indexes docs.
+func NewTFIDF(docs []Document) *TFIDF {
+	idx := &TFIDF{idf: make(map[string]float64)}
+
+	counts := make([]map[string]int, len(docs))
+	df := make(map[string]int)
+	for i, d := range docs {
+		counts[i] = termCounts(d.Text)
+		for term := range counts[i] {
+			df[term]++
+		}
+	}
+
+	// Smoothed IDF, so terms in every document still count a little
+	n := float64(len(docs))
+	for term, f := range df {
+		idx.idf[term] = math.Log((1+n)/(1+float64(f))) + 1
+	}
+	idx.unseenIDF = math.Log(1+n) + 1
+
+	for i, d := range docs {
+		idx.ids = append(idx.ids, d.ID)
+		idx.vectors = append(idx.vectors, idx.weigh(counts[i]))
+	}
+	return idx
+}
+
+// Method implements Index.
+func (idx *TFIDF) Method() string {
+	return "tfidf"
+}
+
+// Search implements Index.
+func (idx *TFIDF) Search(_ context.Context, text string, k int) ([]Match, error) {
+	query := idx.weigh(termCounts(text))
+	scores := make([]float64, len(idx.ids))
+	for i, v := range idx.vectors {
+		scores[i] = sparseDot(query, v)
+	}
+	return topK(idx.ids, scores, k), nil
+}
+
+// weigh turns term counts into a unit length TF-IDF vector. Unknown terms
+// cannot match any document, so they are left out of the vector, but they
+// count in its length with the highest IDF: a query that only partly overlaps
+// a document must not score as if it were all overlap.
+func (idx *TFIDF) weigh(counts map[string]int) map[string]float64 {
+	v := make(map[string]float64, len(counts))
+	var norm float64
+	for term, c := range counts {
+		idf, ok := idx.idf[term]
+		if !ok {
+			idf = idx.unseenIDF
+		}
+		w := (1 + math.Log(float64(c))) * idf
+		if ok {
+			v[term] = w
+		}
+		norm += w * w
+	}
+	norm = math.Sqrt(norm)
+	for term := range v {
+		v[term] /= norm
+	}
+	return v
+}
+
+func sparseDot(a, b map[string]float64) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	var sum float64
+	for term, w := range a {
+		sum += w * b[term]
+	}
+	return sum
+}
+
+// termCounts tokenizes text into lower case words, without stop words
+func termCounts(text string) map[string]int {
+	counts := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		if len([]rune(w)) < 2 || stopWords[w] {
+			continue
+		}
+		counts[stem(w)]++
+	}
+	return counts
+}
+
+// stem strips a few common English suffixes so "errors" matches "error"
+func stem(w string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if len(w) > len(suffix)+3 && strings.HasSuffix(w, suffix) {
+			return strings.TrimSuffix(w, suffix)
+		}
+	}
+	return w
+}
+
+// topK returns the k best scores, highest first, skipping zero scores
+func topK(ids []string, scores []float64, k int) []Match {
+	matches := make([]Match, 0, len(ids))
+	for i, s := range scores {
+		if s > 0 {
+			matches = append(matches, Match{ID: ids[i], Score: math.Min(s, 1)})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "can": true, "do": true, "for": true, "from": true, "has": true, "have": true, "if": true,
+	"in": true, "into": true, "is": true, "it": true, "its": true, "of": true, "on": true, "or": true,
+	"should": true, "so": true, "that": true, "the": true, "their": true, "then": true, "there": true,
+	"this": true, "to": true, "was": true, "we": true, "were": true, "will": true, "with": true,
+	"would": true, "need": true, "needs": true, "also": true, "our": true, "us": true, "you": true,
+}
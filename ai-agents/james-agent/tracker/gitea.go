@@ -0,0 +1,371 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GiteaBaseURL is the API of gitea.com
+const GiteaBaseURL = "https://gitea.com/api/v1"
+
+// Gitea is the IssueTracker of Gitea and Forgejo, using the REST API v1.
+type Gitea struct {
+	rest *restClient
+}
+
+// NewGitea returns a Gitea tracker authenticated with an access token.
+func NewGitea(cfg Config) *Gitea {
+	if cfg.URL == "" {
+		cfg.URL = GiteaBaseURL
+	}
+	return &Gitea{rest: newRESTClient(KindGitea, cfg.URL, cfg.HTTPClient, func(r *http.Request) {
+		r.Header.Set("Authorization", "token "+cfg.Token)
+	})}
+}
+
+type giteaUser struct {
+	Login string `json:"login"`
+}
+
+type giteaLabel struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type giteaIssue struct {
+	Number    int          `json:"number"`
+	Title     string       `json:"title"`
+	Body      string       `json:"body"`
+	State     string       `json:"state"`
+	HTMLURL   string       `json:"html_url"`
+	User      giteaUser    `json:"user"`
+	Labels    []giteaLabel `json:"labels"`
+	Assignees []giteaUser  `json:"assignees"`
+	Milestone *struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+	PullRequest *struct{} `json:"pull_request"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type giteaComment struct {
+	ID        int64     `json:"id"`
+	Body      string    `json:"body"`
+	HTMLURL   string    `json:"html_url"`
+	User      giteaUser `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (g *Gitea) Kind() string { return KindGitea }
+
+// repoPath returns the API path of a repository and the elements below it
+func (g *Gitea) repoPath(repo string, elems ...string) (string, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", fmt.Errorf("gitea: repository must be owner/repo, got %q", repo)
+	}
+	path := "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(name)
+	for _, e := range elems {
+		path += "/" + url.PathEscape(e)
+	}
+	return path, nil
+}
+
+func (g *Gitea) GetIssue(ctx context.Context, repo string, number int) (*Issue, error) {
+	path, err := g.repoPath(repo, "issues", strconv.Itoa(number))
+	if err != nil {
+		return nil, err
+	}
+	var issue giteaIssue
+	if _, err := g.rest.do(ctx, http.MethodGet, path, nil, &issue); err != nil {
+		return nil, err
+	}
+	return issue.toIssue(), nil
+}
+
+func (g *Gitea) ListIssues(ctx context.Context, repo string, opts ListOptions) (*IssueList, error) {
+	path, err := g.repoPath(repo, "issues")
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{"type": {"issues"}, "limit": {"50"}}
+	q.Set("state", opts.State)
+	if opts.State == "" {
+		q.Set("state", "open")
+	}
+	if len(opts.Labels) > 0 {
+		q.Set("labels", strings.Join(opts.Labels, ","))
+	}
+	if opts.Assignee != "" {
+		q.Set("assigned_by", opts.Assignee)
+	}
+	if opts.Milestone != "" {
+		q.Set("milestones", opts.Milestone)
+	}
+	if !opts.Since.IsZero() {
+		q.Set("since", opts.Since.UTC().Format(time.RFC3339))
+	}
+
+	issues, truncated, err := listPages[giteaIssue](ctx, g.rest, path+"?"+q.Encode(), opts.Max)
+	if err != nil {
+		return nil, err
+	}
+	list := &IssueList{Truncated: truncated}
+	for _, issue := range issues {
+		list.Issues = append(list.Issues, *issue.toIssue())
+	}
+	return list, nil
+}
+
+func (g *Gitea) SearchIssues(ctx context.Context, repo, phrase string) ([]Issue, error) {
+	path, err := g.repoPath(repo, "issues")
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{"q": {phrase}, "type": {"issues"}, "state": {"all"}, "limit": {"100"}}
+	var issues []giteaIssue
+	if _, err := g.rest.do(ctx, http.MethodGet, path+"?"+q.Encode(), nil, &issues); err != nil {
+		return nil, err
+	}
+	var out []Issue
+	for _, issue := range issues {
+		out = append(out, *issue.toIssue())
+	}
+	return out, nil
+}
+
+// giteaIssueRequest is the body of a create or edit
+type giteaIssueRequest struct {
+	Title     *string   `json:"title,omitempty"`
+	Body      *string   `json:"body,omitempty"`
+	State     *string   `json:"state,omitempty"`
+	Assignees *[]string `json:"assignees,omitempty"`
+	Labels    *[]int64  `json:"labels,omitempty"`
+	Milestone *int      `json:"milestone,omitempty"`
+}
+
+func (g *Gitea) CreateIssue(ctx context.Context, repo string, req IssueRequest) (*Issue, error) {
+	if req.Title == nil || *req.Title == "" {
+		return nil, fmt.Errorf("gitea: an issue needs a title")
+	}
+	if req.Type != nil {
+		return nil, ErrNotSupported
+	}
+	path, err := g.repoPath(repo, "issues")
+	if err != nil {
+		return nil, err
+	}
+	body := giteaIssueRequest{Title: req.Title, Body: req.Body, Assignees: req.Assignees, Milestone: req.Milestone}
+	if req.Labels != nil {
+		ids, err := g.labelIDs(ctx, repo, *req.Labels)
+		if err != nil {
+			return nil, err
+		}
+		body.Labels = &ids
+	}
+	var issue giteaIssue
+	if _, err := g.rest.do(ctx, http.MethodPost, path, body, &issue); err != nil {
+		return nil, err
+	}
+	return issue.toIssue(), nil
+}
+
+func (g *Gitea) UpdateIssue(ctx context.Context, repo string, number int, req IssueRequest) (*Issue, error) {
+	if req.Type != nil {
+		return nil, ErrNotSupported
+	}
+	return g.patch(ctx, repo, number, giteaIssueRequest{Title: req.Title, Body: req.Body, Milestone: req.Milestone})
+}
+
+// CloseIssue closes an issue; Gitea has no close reasons
+func (g *Gitea) CloseIssue(ctx context.Context, repo string, number int, reason string) (*Issue, error) {
+	return g.patch(ctx, repo, number, giteaIssueRequest{State: String("closed")})
+}
+
+func (g *Gitea) patch(ctx context.Context, repo string, number int, body giteaIssueRequest) (*Issue, error) {
+	path, err := g.repoPath(repo, "issues", strconv.Itoa(number))
+	if err != nil {
+		return nil, err
+	}
+	var issue giteaIssue
+	if _, err := g.rest.do(ctx, http.MethodPatch, path, body, &issue); err != nil {
+		return nil, err
+	}
+	return issue.toIssue(), nil
+}
+
+func (g *Gitea) AddComment(ctx context.Context, repo string, number int, body string) (*Comment, error) {
+	path, err := g.repoPath(repo, "issues", strconv.Itoa(number), "comments")
+	if err != nil {
+		return nil, err
+	}
+	var c giteaComment
+	if _, err := g.rest.do(ctx, http.MethodPost, path, map[string]string{"body": body}, &c); err != nil {
+		return nil, err
+	}
+	comment := c.toComment()
+	return &comment, nil
+}
+
+func (g *Gitea) ListComments(ctx context.Context, repo string, number int) ([]Comment, error) {
+	path, err := g.repoPath(repo, "issues", strconv.Itoa(number), "comments")
+	if err != nil {
+		return nil, err
+	}
+	comments, _, err := listPages[giteaComment](ctx, g.rest, path, 0)
+	if err != nil {
+		return nil, err
+	}
+	var out []Comment
+	for _, c := range comments {
+		out = append(out, c.toComment())
+	}
+	return out, nil
+}
+
+func (g *Gitea) listLabels(ctx context.Context, repo string) ([]giteaLabel, error) {
+	path, err := g.repoPath(repo, "labels")
+	if err != nil {
+		return nil, err
+	}
+	labels, _, err := listPages[giteaLabel](ctx, g.rest, path+"?limit=50", 0)
+	return labels, err
+}
+
+func (g *Gitea) ListLabels(ctx context.Context, repo string) ([]string, error) {
+	labels, err := g.listLabels(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, l := range labels {
+		names = append(names, l.Name)
+	}
+	return names, nil
+}
+
+// labelIDs maps label names to the IDs the Gitea API takes
+func (g *Gitea) labelIDs(ctx context.Context, repo string, names []string) ([]int64, error) {
+	labels, err := g.listLabels(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	ids := []int64{}
+	for _, name := range names {
+		found := false
+		for _, l := range labels {
+			if strings.EqualFold(l.Name, name) {
+				ids = append(ids, l.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("gitea: no label %q in %s", name, repo)
+		}
+	}
+	return ids, nil
+}
+
+func (g *Gitea) AddLabels(ctx context.Context, repo string, number int, labels []string) error {
+	ids, err := g.labelIDs(ctx, repo, labels)
+	if err != nil {
+		return err
+	}
+	path, err := g.repoPath(repo, "issues", strconv.Itoa(number), "labels")
+	if err != nil {
+		return err
+	}
+	_, err = g.rest.do(ctx, http.MethodPost, path, map[string][]int64{"labels": ids}, nil)
+	return err
+}
+
+// AddAssignees keeps the current assignees, as Gitea replaces the whole list
+func (g *Gitea) AddAssignees(ctx context.Context, repo string, number int, logins []string) error {
+	issue, err := g.GetIssue(ctx, repo, number)
+	if err != nil {
+		return err
+	}
+	all := issue.Assignees
+	for _, login := range logins {
+		if !contains(all, login) {
+			all = append(all, login)
+		}
+	}
+	_, err = g.patch(ctx, repo, number, giteaIssueRequest{Assignees: &all})
+	return err
+}
+
+func (g *Gitea) ListAssignees(ctx context.Context, repo string) ([]string, error) {
+	path, err := g.repoPath(repo, "assignees")
+	if err != nil {
+		return nil, err
+	}
+	var users []giteaUser
+	if _, err := g.rest.do(ctx, http.MethodGet, path, nil, &users); err != nil {
+		return nil, err
+	}
+	var logins []string
+	for _, u := range users {
+		logins = append(logins, u.Login)
+	}
+	return logins, nil
+}
+
+func (g *Gitea) ListMilestones(ctx context.Context, repo string) ([]Milestone, error) {
+	path, err := g.repoPath(repo, "milestones")
+	if err != nil {
+		return nil, err
+	}
+	milestones, _, err := listPages[struct {
+		ID    int    `json:"id"`
+		Title string `json:"title"`
+	}](ctx, g.rest, path+"?state=open&limit=50", 0)
+	if err != nil {
+		return nil, err
+	}
+	var out []Milestone
+	for _, m := range milestones {
+		out = append(out, Milestone{ID: m.ID, Title: m.Title})
+	}
+	return out, nil
+}
+
+// ListIssueTypes always fails, Gitea has no issue types
+func (g *Gitea) ListIssueTypes(ctx context.Context, repo string) ([]string, error) {
+	return nil, ErrNotSupported
+}
+
+func (i *giteaIssue) toIssue() *Issue {
+	out := &Issue{
+		Number:      i.Number,
+		Title:       i.Title,
+		Body:        i.Body,
+		State:       i.State,
+		URL:         i.HTMLURL,
+		Author:      i.User.Login,
+		PullRequest: i.PullRequest != nil,
+		CreatedAt:   i.CreatedAt,
+		UpdatedAt:   i.UpdatedAt,
+	}
+	for _, l := range i.Labels {
+		out.Labels = append(out.Labels, l.Name)
+	}
+	for _, u := range i.Assignees {
+		out.Assignees = append(out.Assignees, u.Login)
+	}
+	if i.Milestone != nil {
+		out.Milestone = i.Milestone.Title
+	}
+	return out
+}
+
+func (c giteaComment) toComment() Comment {
+	return Comment{ID: strconv.FormatInt(c.ID, 10), Body: c.Body, URL: c.HTMLURL, Author: c.User.Login, CreatedAt: c.CreatedAt}
+}
@@ -0,0 +1,213 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"james-agent/main/github"
+)
+
+// GitHub is the IssueTracker of GitHub and GitHub Enterprise.
+type GitHub struct {
+	client *github.Client
+}
+
+// NewGitHub wraps a GitHub client.
+func NewGitHub(client *github.Client) *GitHub {
+	return &GitHub{client: client}
+}
+
+func (g *GitHub) Kind() string { return KindGitHub }
+
+func (g *GitHub) GetIssue(ctx context.Context, repo string, number int) (*Issue, error) {
+	issue, err := g.client.GetIssue(ctx, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	return fromGitHub(issue), nil
+}
+
+func (g *GitHub) ListIssues(ctx context.Context, repo string, opts ListOptions) (*IssueList, error) {
+	list, err := g.client.ListIssues(ctx, repo, github.ListIssuesOptions{
+		State:     opts.State,
+		Labels:    opts.Labels,
+		Assignee:  opts.Assignee,
+		Milestone: opts.Milestone,
+		Since:     opts.Since,
+		Max:       opts.Max,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := &IssueList{Truncated: list.Truncated}
+	for i := range list.Issues {
+		out.Issues = append(out.Issues, *fromGitHub(&list.Issues[i]))
+	}
+	return out, nil
+}
+
+func (g *GitHub) SearchIssues(ctx context.Context, repo, phrase string) ([]Issue, error) {
+	if err := github.ValidateRepo(repo); err != nil {
+		return nil, err
+	}
+	issues, err := g.client.SearchIssues(ctx, fmt.Sprintf(`repo:%s is:issue in:body %q`, repo, phrase))
+	if err != nil {
+		return nil, err
+	}
+	var out []Issue
+	for i := range issues {
+		out = append(out, *fromGitHub(&issues[i]))
+	}
+	return out, nil
+}
+
+func (g *GitHub) CreateIssue(ctx context.Context, repo string, req IssueRequest) (*Issue, error) {
+	issue, err := g.client.CreateIssue(ctx, repo, toGitHub(req))
+	if err != nil {
+		return nil, err
+	}
+	return fromGitHub(issue), nil
+}
+
+func (g *GitHub) UpdateIssue(ctx context.Context, repo string, number int, req IssueRequest) (*Issue, error) {
+	issue, err := g.client.EditIssue(ctx, repo, number, toGitHub(req))
+	if err != nil {
+		return nil, err
+	}
+	return fromGitHub(issue), nil
+}
+
+func (g *GitHub) CloseIssue(ctx context.Context, repo string, number int, reason string) (*Issue, error) {
+	req := github.IssueRequest{State: github.String("closed")}
+	if reason != "" {
+		req.StateReason = github.String(reason)
+	}
+	issue, err := g.client.EditIssue(ctx, repo, number, req)
+	if err != nil {
+		return nil, err
+	}
+	return fromGitHub(issue), nil
+}
+
+func (g *GitHub) AddComment(ctx context.Context, repo string, number int, body string) (*Comment, error) {
+	c, err := g.client.CreateComment(ctx, repo, number, body)
+	if err != nil {
+		return nil, err
+	}
+	return &Comment{ID: fmt.Sprint(c.ID), Body: c.Body, URL: c.HTMLURL, Author: c.User.Login, CreatedAt: c.CreatedAt}, nil
+}
+
+func (g *GitHub) ListComments(ctx context.Context, repo string, number int) ([]Comment, error) {
+	comments, err := g.client.ListComments(ctx, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	var out []Comment
+	for _, c := range comments {
+		out = append(out, Comment{ID: fmt.Sprint(c.ID), Body: c.Body, URL: c.HTMLURL, Author: c.User.Login, CreatedAt: c.CreatedAt})
+	}
+	return out, nil
+}
+
+func (g *GitHub) ListLabels(ctx context.Context, repo string) ([]string, error) {
+	labels, err := g.client.ListLabels(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, l := range labels {
+		names = append(names, l.Name)
+	}
+	return names, nil
+}
+
+func (g *GitHub) AddLabels(ctx context.Context, repo string, number int, labels []string) error {
+	return g.client.AddLabels(ctx, repo, number, labels)
+}
+
+func (g *GitHub) AddAssignees(ctx context.Context, repo string, number int, logins []string) error {
+	return g.client.AddAssignees(ctx, repo, number, logins)
+}
+
+func (g *GitHub) ListAssignees(ctx context.Context, repo string) ([]string, error) {
+	users, err := g.client.ListAssignees(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	var logins []string
+	for _, u := range users {
+		logins = append(logins, u.Login)
+	}
+	return logins, nil
+}
+
+func (g *GitHub) ListMilestones(ctx context.Context, repo string) ([]Milestone, error) {
+	milestones, err := g.client.ListMilestones(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	var out []Milestone
+	for _, m := range milestones {
+		out = append(out, Milestone{ID: m.Number, Title: m.Title})
+	}
+	return out, nil
+}
+
+// ListIssueTypes returns the issue types of the repository owner. Personal accounts have none.
+func (g *GitHub) ListIssueTypes(ctx context.Context, repo string) ([]string, error) {
+	owner, _, _ := strings.Cut(repo, "/")
+	types, err := g.client.ListIssueTypes(ctx, owner)
+	var apiErr *github.APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusForbidden) {
+		return nil, ErrNotSupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, t := range types {
+		names = append(names, t.Name)
+	}
+	return names, nil
+}
+
+func fromGitHub(issue *github.Issue) *Issue {
+	out := &Issue{
+		Number:      issue.Number,
+		Title:       issue.Title,
+		Body:        issue.Body,
+		State:       issue.State,
+		URL:         issue.HTMLURL,
+		Author:      issue.User.Login,
+		PullRequest: issue.IsPullRequest(),
+		CreatedAt:   issue.CreatedAt,
+		UpdatedAt:   issue.UpdatedAt,
+	}
+	for _, l := range issue.Labels {
+		out.Labels = append(out.Labels, l.Name)
+	}
+	for _, u := range issue.Assignees {
+		out.Assignees = append(out.Assignees, u.Login)
+	}
+	if issue.Milestone != nil {
+		out.Milestone = issue.Milestone.Title
+	}
+	if issue.Type != nil {
+		out.Type = issue.Type.Name
+	}
+	return out
+}
+
+func toGitHub(req IssueRequest) github.IssueRequest {
+	return github.IssueRequest{
+		Title:     req.Title,
+		Body:      req.Body,
+		Labels:    req.Labels,
+		Assignees: req.Assignees,
+		Milestone: req.Milestone,
+		Type:      req.Type,
+	}
+}
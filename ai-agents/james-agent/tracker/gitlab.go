@@ -0,0 +1,416 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitLabBaseURL is the API of gitlab.com
+const GitLabBaseURL = "https://gitlab.com/api/v4"
+
+// GitLab is the IssueTracker of GitLab, using the REST API v4.
+type GitLab struct {
+	rest *restClient
+
+	mu sync.Mutex
+	// userIDs caches the user ID of each username, which assigning needs
+	userIDs map[string]int
+}
+
+// NewGitLab returns a GitLab tracker. The token is a personal, project or group access token.
+func NewGitLab(cfg Config) *GitLab {
+	if cfg.URL == "" {
+		cfg.URL = GitLabBaseURL
+	}
+	return &GitLab{
+		rest: newRESTClient(KindGitLab, cfg.URL, cfg.HTTPClient, func(r *http.Request) {
+			r.Header.Set("PRIVATE-TOKEN", cfg.Token)
+		}),
+		userIDs: map[string]int{},
+	}
+}
+
+type gitlabUser struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+type gitlabIssue struct {
+	IID         int          `json:"iid"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	State       string       `json:"state"`
+	WebURL      string       `json:"web_url"`
+	Author      gitlabUser   `json:"author"`
+	Labels      []string     `json:"labels"`
+	Assignees   []gitlabUser `json:"assignees"`
+	Milestone   *struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+	IssueType string    `json:"issue_type"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type gitlabNote struct {
+	ID        int        `json:"id"`
+	Body      string     `json:"body"`
+	Author    gitlabUser `json:"author"`
+	System    bool       `json:"system"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (g *GitLab) Kind() string { return KindGitLab }
+
+// projectPath returns the API path of a project and the elements below it
+func (g *GitLab) projectPath(repo string, elems ...string) (string, error) {
+	if strings.Trim(repo, "/") == "" || !strings.Contains(repo, "/") {
+		return "", fmt.Errorf("gitlab: project must be the full path, e.g. group/project, got %q", repo)
+	}
+	path := "/projects/" + url.PathEscape(strings.Trim(repo, "/"))
+	for _, e := range elems {
+		path += "/" + url.PathEscape(e)
+	}
+	return path, nil
+}
+
+func (g *GitLab) GetIssue(ctx context.Context, repo string, number int) (*Issue, error) {
+	path, err := g.projectPath(repo, "issues", strconv.Itoa(number))
+	if err != nil {
+		return nil, err
+	}
+	var issue gitlabIssue
+	if _, err := g.rest.do(ctx, http.MethodGet, path, nil, &issue); err != nil {
+		return nil, err
+	}
+	return issue.toIssue(), nil
+}
+
+func (g *GitLab) ListIssues(ctx context.Context, repo string, opts ListOptions) (*IssueList, error) {
+	path, err := g.projectPath(repo, "issues")
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	switch opts.State {
+	case "", "open":
+		q.Set("state", "opened")
+	case "closed":
+		q.Set("state", "closed")
+	}
+	if len(opts.Labels) > 0 {
+		q.Set("labels", strings.Join(opts.Labels, ","))
+	}
+	if opts.Assignee != "" {
+		q.Set("assignee_username", opts.Assignee)
+	}
+	if opts.Milestone != "" {
+		q.Set("milestone", opts.Milestone)
+	}
+	if !opts.Since.IsZero() {
+		q.Set("updated_after", opts.Since.UTC().Format(time.RFC3339))
+	}
+	q.Set("order_by", "created_at")
+	q.Set("per_page", "100")
+
+	issues, truncated, err := listPages[gitlabIssue](ctx, g.rest, path+"?"+q.Encode(), opts.Max)
+	if err != nil {
+		return nil, err
+	}
+	list := &IssueList{Truncated: truncated}
+	for _, issue := range issues {
+		list.Issues = append(list.Issues, *issue.toIssue())
+	}
+	return list, nil
+}
+
+func (g *GitLab) SearchIssues(ctx context.Context, repo, phrase string) ([]Issue, error) {
+	path, err := g.projectPath(repo, "issues")
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{"search": {phrase}, "in": {"title,description"}, "scope": {"all"}, "per_page": {"100"}}
+	var issues []gitlabIssue
+	if _, err := g.rest.do(ctx, http.MethodGet, path+"?"+q.Encode(), nil, &issues); err != nil {
+		return nil, err
+	}
+	var out []Issue
+	for _, issue := range issues {
+		out = append(out, *issue.toIssue())
+	}
+	return out, nil
+}
+
+// gitlabIssueRequest is the body of a create or update
+type gitlabIssueRequest struct {
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Labels      *string `json:"labels,omitempty"`
+	AddLabels   *string `json:"add_labels,omitempty"`
+	AssigneeIDs *[]int  `json:"assignee_ids,omitempty"`
+	MilestoneID *int    `json:"milestone_id,omitempty"`
+	IssueType   *string `json:"issue_type,omitempty"`
+	StateEvent  *string `json:"state_event,omitempty"`
+}
+
+func (g *GitLab) request(ctx context.Context, req IssueRequest) (gitlabIssueRequest, error) {
+	out := gitlabIssueRequest{Title: req.Title, Description: req.Body, MilestoneID: req.Milestone, IssueType: req.Type}
+	if req.Labels != nil {
+		out.Labels = String(strings.Join(*req.Labels, ","))
+	}
+	if req.Assignees != nil {
+		ids, err := g.userIDsOf(ctx, *req.Assignees)
+		if err != nil {
+			return out, err
+		}
+		out.AssigneeIDs = &ids
+	}
+	return out, nil
+}
+
+func (g *GitLab) CreateIssue(ctx context.Context, repo string, req IssueRequest) (*Issue, error) {
+	if req.Title == nil || *req.Title == "" {
+		return nil, fmt.Errorf("gitlab: an issue needs a title")
+	}
+	path, err := g.projectPath(repo, "issues")
+	if err != nil {
+		return nil, err
+	}
+	body, err := g.request(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var issue gitlabIssue
+	if _, err := g.rest.do(ctx, http.MethodPost, path, body, &issue); err != nil {
+		return nil, err
+	}
+	return issue.toIssue(), nil
+}
+
+func (g *GitLab) UpdateIssue(ctx context.Context, repo string, number int, req IssueRequest) (*Issue, error) {
+	body, err := g.request(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return g.put(ctx, repo, number, body)
+}
+
+// CloseIssue closes an issue; GitLab has no close reasons
+func (g *GitLab) CloseIssue(ctx context.Context, repo string, number int, reason string) (*Issue, error) {
+	return g.put(ctx, repo, number, gitlabIssueRequest{StateEvent: String("close")})
+}
+
+func (g *GitLab) put(ctx context.Context, repo string, number int, body gitlabIssueRequest) (*Issue, error) {
+	path, err := g.projectPath(repo, "issues", strconv.Itoa(number))
+	if err != nil {
+		return nil, err
+	}
+	var issue gitlabIssue
+	if _, err := g.rest.do(ctx, http.MethodPut, path, body, &issue); err != nil {
+		return nil, err
+	}
+	return issue.toIssue(), nil
+}
+
+func (g *GitLab) AddComment(ctx context.Context, repo string, number int, body string) (*Comment, error) {
+	path, err := g.projectPath(repo, "issues", strconv.Itoa(number), "notes")
+	if err != nil {
+		return nil, err
+	}
+	var note gitlabNote
+	if _, err := g.rest.do(ctx, http.MethodPost, path, map[string]string{"body": body}, &note); err != nil {
+		return nil, err
+	}
+	// Notes have no web URL of their own; they are anchors on the issue page
+	issue, err := g.GetIssue(ctx, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	c := note.toComment(issue.URL)
+	return &c, nil
+}
+
+func (g *GitLab) ListComments(ctx context.Context, repo string, number int) ([]Comment, error) {
+	issue, err := g.GetIssue(ctx, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	path, err := g.projectPath(repo, "issues", strconv.Itoa(number), "notes")
+	if err != nil {
+		return nil, err
+	}
+	notes, _, err := listPages[gitlabNote](ctx, g.rest, path+"?per_page=100", 0)
+	if err != nil {
+		return nil, err
+	}
+	var out []Comment
+	for _, n := range notes {
+		// System notes record changes such as label edits
+		if !n.System {
+			out = append(out, n.toComment(issue.URL))
+		}
+	}
+	return out, nil
+}
+
+func (g *GitLab) ListLabels(ctx context.Context, repo string) ([]string, error) {
+	path, err := g.projectPath(repo, "labels")
+	if err != nil {
+		return nil, err
+	}
+	labels, _, err := listPages[struct {
+		Name string `json:"name"`
+	}](ctx, g.rest, path+"?per_page=100", 0)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, l := range labels {
+		names = append(names, l.Name)
+	}
+	return names, nil
+}
+
+func (g *GitLab) AddLabels(ctx context.Context, repo string, number int, labels []string) error {
+	_, err := g.put(ctx, repo, number, gitlabIssueRequest{AddLabels: String(strings.Join(labels, ","))})
+	return err
+}
+
+// AddAssignees keeps the current assignees, as GitLab replaces the whole list
+func (g *GitLab) AddAssignees(ctx context.Context, repo string, number int, logins []string) error {
+	issue, err := g.GetIssue(ctx, repo, number)
+	if err != nil {
+		return err
+	}
+	all := issue.Assignees
+	for _, login := range logins {
+		if !contains(all, login) {
+			all = append(all, login)
+		}
+	}
+	ids, err := g.userIDsOf(ctx, all)
+	if err != nil {
+		return err
+	}
+	_, err = g.put(ctx, repo, number, gitlabIssueRequest{AssigneeIDs: &ids})
+	return err
+}
+
+// ListAssignees returns the project members, including inherited ones
+func (g *GitLab) ListAssignees(ctx context.Context, repo string) ([]string, error) {
+	path, err := g.projectPath(repo, "members", "all")
+	if err != nil {
+		return nil, err
+	}
+	members, _, err := listPages[gitlabUser](ctx, g.rest, path+"?per_page=100", 0)
+	if err != nil {
+		return nil, err
+	}
+	var logins []string
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, m := range members {
+		g.userIDs[m.Username] = m.ID
+		logins = append(logins, m.Username)
+	}
+	return logins, nil
+}
+
+func (g *GitLab) ListMilestones(ctx context.Context, repo string) ([]Milestone, error) {
+	path, err := g.projectPath(repo, "milestones")
+	if err != nil {
+		return nil, err
+	}
+	milestones, _, err := listPages[struct {
+		ID    int    `json:"id"`
+		Title string `json:"title"`
+	}](ctx, g.rest, path+"?state=active&per_page=100", 0)
+	if err != nil {
+		return nil, err
+	}
+	var out []Milestone
+	for _, m := range milestones {
+		out = append(out, Milestone{ID: m.ID, Title: m.Title})
+	}
+	return out, nil
+}
+
+// ListIssueTypes returns the work item types GitLab issues can be created as
+func (g *GitLab) ListIssueTypes(ctx context.Context, repo string) ([]string, error) {
+	return []string{"issue", "incident", "task"}, nil
+}
+
+// userIDsOf looks up the user IDs of usernames
+func (g *GitLab) userIDsOf(ctx context.Context, logins []string) ([]int, error) {
+	ids := []int{}
+	for _, login := range logins {
+		g.mu.Lock()
+		id, ok := g.userIDs[login]
+		g.mu.Unlock()
+		if !ok {
+			var users []gitlabUser
+			if _, err := g.rest.do(ctx, http.MethodGet, "/users?username="+url.QueryEscape(login), nil, &users); err != nil {
+				return nil, err
+			}
+			if len(users) == 0 {
+				return nil, fmt.Errorf("gitlab: no user %q", login)
+			}
+			id = users[0].ID
+			g.mu.Lock()
+			g.userIDs[login] = id
+			g.mu.Unlock()
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (i *gitlabIssue) toIssue() *Issue {
+	out := &Issue{
+		Number:    i.IID,
+		Title:     i.Title,
+		Body:      i.Description,
+		State:     "open",
+		URL:       i.WebURL,
+		Author:    i.Author.Username,
+		Labels:    i.Labels,
+		Type:      i.IssueType,
+		CreatedAt: i.CreatedAt,
+		UpdatedAt: i.UpdatedAt,
+	}
+	if i.State == "closed" {
+		out.State = "closed"
+	}
+	for _, u := range i.Assignees {
+		out.Assignees = append(out.Assignees, u.Username)
+	}
+	if i.Milestone != nil {
+		out.Milestone = i.Milestone.Title
+	}
+	return out
+}
+
+func (n gitlabNote) toComment(issueURL string) Comment {
+	return Comment{
+		ID:        strconv.Itoa(n.ID),
+		Body:      n.Body,
+		URL:       fmt.Sprintf("%s#note_%d", issueURL, n.ID),
+		Author:    n.Author.Username,
+		CreatedAt: n.CreatedAt,
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
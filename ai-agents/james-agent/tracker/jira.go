@@ -0,0 +1,537 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jiraTimeLayout is the timestamp format of the Jira REST API
+const jiraTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// jiraDefaultType is the issue type of created issues when none is given, as Jira requires one
+const jiraDefaultType = "Task"
+
+// jiraProjectPattern matches a project key
+var jiraProjectPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]+$`)
+
+var (
+	// htmlComment matches a one-line HTML comment, such as the james-agent marker
+	htmlComment = regexp.MustCompile(`<!--[ \t]*([^{}\n]*?)[ \t]*-->`)
+	// jiraAnchor matches the anchor macro HTML comments are stored as
+	jiraAnchor = regexp.MustCompile(`\{anchor:([^{}\n]*)\}`)
+)
+
+// toJiraText stores HTML comments as anchor macros. Jira shows an HTML comment
+// verbatim, while an anchor renders as nothing and its text stays searchable.
+func toJiraText(s string) string {
+	return htmlComment.ReplaceAllString(s, "{anchor:$1}")
+}
+
+// fromJiraText turns anchor macros back into the HTML comments they were written as
+func fromJiraText(s string) string {
+	return jiraAnchor.ReplaceAllString(s, "<!-- $1 -->")
+}
+
+// Jira is the IssueTracker of Jira Cloud and Data Center, using the REST API v2.
+// The repository is a project key and issue PROJ-123 has the number 123.
+type Jira struct {
+	rest    *restClient
+	siteURL string
+	// cloud identifies users by account ID instead of user name
+	cloud bool
+}
+
+// NewJira returns a Jira tracker. With an Email it authenticates like Jira
+// Cloud, with the email and an API token; otherwise the token is sent as a
+// Data Center personal access token.
+func NewJira(cfg Config) *Jira {
+	siteURL := strings.TrimRight(cfg.URL, "/")
+	return &Jira{
+		rest: newRESTClient(KindJira, siteURL+"/rest/api/2", cfg.HTTPClient, func(r *http.Request) {
+			if cfg.Email != "" {
+				r.SetBasicAuth(cfg.Email, cfg.Token)
+			} else {
+				r.Header.Set("Authorization", "Bearer "+cfg.Token)
+			}
+		}),
+		siteURL: siteURL,
+		cloud:   cfg.Email != "",
+	}
+}
+
+type jiraUser struct {
+	AccountID   string `json:"accountId,omitempty"`
+	Name        string `json:"name,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// login is the identifier used to assign the user
+func (u *jiraUser) login() string {
+	if u == nil {
+		return ""
+	}
+	if u.AccountID != "" {
+		return u.AccountID
+	}
+	return u.Name
+}
+
+type jiraName struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string     `json:"summary"`
+		Description string     `json:"description"`
+		Labels      []string   `json:"labels"`
+		Assignee    *jiraUser  `json:"assignee"`
+		Reporter    *jiraUser  `json:"reporter"`
+		FixVersions []jiraName `json:"fixVersions"`
+		IssueType   jiraName   `json:"issuetype"`
+		Status      struct {
+			StatusCategory struct {
+				Key string `json:"key"`
+			} `json:"statusCategory"`
+		} `json:"status"`
+		Created string `json:"created"`
+		Updated string `json:"updated"`
+	} `json:"fields"`
+}
+
+type jiraComment struct {
+	ID      string    `json:"id"`
+	Body    string    `json:"body"`
+	Author  *jiraUser `json:"author"`
+	Created string    `json:"created"`
+}
+
+// jiraFields are the fields requested for an issue
+const jiraFields = "summary,description,labels,assignee,reporter,fixVersions,issuetype,status,created,updated"
+
+func (j *Jira) Kind() string { return KindJira }
+
+// issueKey returns the key of issue number in project
+func (j *Jira) issueKey(project string, number int) (string, error) {
+	if !jiraProjectPattern.MatchString(project) {
+		return "", fmt.Errorf("jira: repository must be a project key such as PROJ, got %q", project)
+	}
+	return fmt.Sprintf("%s-%d", project, number), nil
+}
+
+func (j *Jira) GetIssue(ctx context.Context, repo string, number int) (*Issue, error) {
+	key, err := j.issueKey(repo, number)
+	if err != nil {
+		return nil, err
+	}
+	var issue jiraIssue
+	if _, err := j.rest.do(ctx, http.MethodGet, "/issue/"+key+"?fields="+jiraFields, nil, &issue); err != nil {
+		return nil, err
+	}
+	return j.toIssue(&issue), nil
+}
+
+// search runs a JQL query through every page, stopping after max issues.
+// Jira Cloud removed /search for /search/jql, which pages with a token
+// instead of startAt; Data Center only has /search.
+func (j *Jira) search(ctx context.Context, jql string, max int) ([]Issue, bool, error) {
+	path := "/search"
+	if j.cloud {
+		path = "/search/jql"
+	}
+	body := map[string]any{"jql": jql, "maxResults": 100, "fields": strings.Split(jiraFields, ",")}
+	var out []Issue
+	for startAt := 0; ; {
+		var page struct {
+			Issues        []jiraIssue `json:"issues"`
+			Total         int         `json:"total"`
+			NextPageToken string      `json:"nextPageToken"`
+			IsLast        bool        `json:"isLast"`
+		}
+		if !j.cloud {
+			body["startAt"] = startAt
+		}
+		if _, err := j.rest.do(ctx, http.MethodPost, path, body, &page); err != nil {
+			return nil, false, err
+		}
+		for i := range page.Issues {
+			if max > 0 && len(out) >= max {
+				return out, true, nil
+			}
+			out = append(out, *j.toIssue(&page.Issues[i]))
+		}
+		startAt += len(page.Issues)
+		if j.cloud {
+			if page.IsLast || page.NextPageToken == "" {
+				return out, false, nil
+			}
+			body["nextPageToken"] = page.NextPageToken
+			continue
+		}
+		if len(page.Issues) == 0 || startAt >= page.Total {
+			return out, false, nil
+		}
+	}
+}
+
+// jqlString quotes s for JQL
+func jqlString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func (j *Jira) ListIssues(ctx context.Context, repo string, opts ListOptions) (*IssueList, error) {
+	if !jiraProjectPattern.MatchString(repo) {
+		return nil, fmt.Errorf("jira: repository must be a project key such as PROJ, got %q", repo)
+	}
+	clauses := []string{"project = " + jqlString(repo)}
+	switch opts.State {
+	case "", "open":
+		clauses = append(clauses, "statusCategory != Done")
+	case "closed":
+		clauses = append(clauses, "statusCategory = Done")
+	}
+	for _, l := range opts.Labels {
+		clauses = append(clauses, "labels = "+jqlString(l))
+	}
+	if opts.Assignee != "" {
+		clauses = append(clauses, "assignee = "+jqlString(opts.Assignee))
+	}
+	if opts.Milestone != "" {
+		clauses = append(clauses, "fixVersion = "+jqlString(opts.Milestone))
+	}
+	if !opts.Since.IsZero() {
+		clauses = append(clauses, "updated >= "+jqlString(opts.Since.UTC().Format("2006-01-02 15:04")))
+	}
+
+	issues, truncated, err := j.search(ctx, strings.Join(clauses, " AND ")+" ORDER BY created DESC", opts.Max)
+	if err != nil {
+		return nil, err
+	}
+	return &IssueList{Issues: issues, Truncated: truncated}, nil
+}
+
+func (j *Jira) SearchIssues(ctx context.Context, repo, phrase string) ([]Issue, error) {
+	if !jiraProjectPattern.MatchString(repo) {
+		return nil, fmt.Errorf("jira: repository must be a project key such as PROJ, got %q", repo)
+	}
+	// The inner quotes make Jira match the words as a phrase
+	jql := fmt.Sprintf("project = %s AND text ~ %s", jqlString(repo), jqlString(`"`+phrase+`"`))
+	issues, _, err := j.search(ctx, jql, 100)
+	return issues, err
+}
+
+// fields builds the fields of a create or edit
+func (j *Jira) fields(req IssueRequest) map[string]any {
+	fields := map[string]any{}
+	if req.Title != nil {
+		fields["summary"] = *req.Title
+	}
+	if req.Body != nil {
+		fields["description"] = toJiraText(*req.Body)
+	}
+	if req.Labels != nil {
+		fields["labels"] = *req.Labels
+	}
+	if req.Milestone != nil {
+		fields["fixVersions"] = []jiraName{{ID: strconv.Itoa(*req.Milestone)}}
+	}
+	if req.Type != nil {
+		fields["issuetype"] = jiraName{Name: *req.Type}
+	}
+	return fields
+}
+
+// user is the reference to a user in a request
+func (j *Jira) user(login string) jiraUser {
+	if j.cloud {
+		return jiraUser{AccountID: login}
+	}
+	return jiraUser{Name: login}
+}
+
+func (j *Jira) CreateIssue(ctx context.Context, repo string, req IssueRequest) (*Issue, error) {
+	if req.Title == nil || *req.Title == "" {
+		return nil, fmt.Errorf("jira: an issue needs a title")
+	}
+	if !jiraProjectPattern.MatchString(repo) {
+		return nil, fmt.Errorf("jira: repository must be a project key such as PROJ, got %q", repo)
+	}
+	fields := j.fields(req)
+	fields["project"] = map[string]string{"key": repo}
+	if req.Type == nil {
+		fields["issuetype"] = jiraName{Name: jiraDefaultType}
+	}
+	if req.Assignees != nil && len(*req.Assignees) > 0 {
+		if len(*req.Assignees) > 1 {
+			return nil, fmt.Errorf("jira: an issue has a single assignee")
+		}
+		fields["assignee"] = j.user((*req.Assignees)[0])
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	if _, err := j.rest.do(ctx, http.MethodPost, "/issue", map[string]any{"fields": fields}, &created); err != nil {
+		return nil, err
+	}
+	_, n, _ := strings.Cut(created.Key, "-")
+	number, err := strconv.Atoi(n)
+	if err != nil {
+		return nil, fmt.Errorf("jira: unexpected issue key %q", created.Key)
+	}
+	return j.GetIssue(ctx, repo, number)
+}
+
+func (j *Jira) UpdateIssue(ctx context.Context, repo string, number int, req IssueRequest) (*Issue, error) {
+	key, err := j.issueKey(repo, number)
+	if err != nil {
+		return nil, err
+	}
+	if fields := j.fields(req); len(fields) > 0 {
+		if _, err := j.rest.do(ctx, http.MethodPut, "/issue/"+key, map[string]any{"fields": fields}, nil); err != nil {
+			return nil, err
+		}
+	}
+	return j.GetIssue(ctx, repo, number)
+}
+
+// notPlannedTransitions are words in the names of transitions that drop an issue
+var notPlannedTransitions = []string{"won't", "wont", "not planned", "cancel", "reject", "decline", "obsolete"}
+
+// CloseIssue performs a transition into the Done status category. For
+// ReasonNotPlanned a transition named like "Won't Do" or "Cancel" is
+// preferred, otherwise the first one is used.
+func (j *Jira) CloseIssue(ctx context.Context, repo string, number int, reason string) (*Issue, error) {
+	key, err := j.issueKey(repo, number)
+	if err != nil {
+		return nil, err
+	}
+	var transitions struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				Name           string `json:"name"`
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if _, err := j.rest.do(ctx, http.MethodGet, "/issue/"+key+"/transitions", nil, &transitions); err != nil {
+		return nil, err
+	}
+	id := ""
+	for _, t := range transitions.Transitions {
+		if t.To.StatusCategory.Key != "done" {
+			continue
+		}
+		if id == "" {
+			id = t.ID
+		}
+		if reason == ReasonNotPlanned && containsAny(strings.ToLower(t.Name+" "+t.To.Name), notPlannedTransitions) {
+			id = t.ID
+			break
+		}
+	}
+	if id == "" {
+		return nil, fmt.Errorf("jira: %s has no transition to a done status", key)
+	}
+	body := map[string]any{"transition": map[string]string{"id": id}}
+	if _, err := j.rest.do(ctx, http.MethodPost, "/issue/"+key+"/transitions", body, nil); err != nil {
+		return nil, err
+	}
+	return j.GetIssue(ctx, repo, number)
+}
+
+func containsAny(s string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *Jira) AddComment(ctx context.Context, repo string, number int, body string) (*Comment, error) {
+	key, err := j.issueKey(repo, number)
+	if err != nil {
+		return nil, err
+	}
+	var c jiraComment
+	if _, err := j.rest.do(ctx, http.MethodPost, "/issue/"+key+"/comment", map[string]string{"body": toJiraText(body)}, &c); err != nil {
+		return nil, err
+	}
+	comment := j.toComment(key, c)
+	return &comment, nil
+}
+
+func (j *Jira) ListComments(ctx context.Context, repo string, number int) ([]Comment, error) {
+	key, err := j.issueKey(repo, number)
+	if err != nil {
+		return nil, err
+	}
+	var out []Comment
+	for startAt := 0; ; {
+		var page struct {
+			Comments []jiraComment `json:"comments"`
+			Total    int           `json:"total"`
+		}
+		if _, err := j.rest.do(ctx, http.MethodGet, fmt.Sprintf("/issue/%s/comment?startAt=%d&maxResults=100", key, startAt), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, c := range page.Comments {
+			out = append(out, j.toComment(key, c))
+		}
+		startAt += len(page.Comments)
+		if len(page.Comments) == 0 || startAt >= page.Total {
+			return out, nil
+		}
+	}
+}
+
+// ListLabels returns the labels in use on the site; Jira labels are not per project
+func (j *Jira) ListLabels(ctx context.Context, repo string) ([]string, error) {
+	var labels []string
+	for startAt := 0; ; {
+		var page struct {
+			Values []string `json:"values"`
+			IsLast bool     `json:"isLast"`
+		}
+		if _, err := j.rest.do(ctx, http.MethodGet, fmt.Sprintf("/label?startAt=%d&maxResults=1000", startAt), nil, &page); err != nil {
+			return nil, err
+		}
+		labels = append(labels, page.Values...)
+		startAt += len(page.Values)
+		if page.IsLast || len(page.Values) == 0 {
+			return labels, nil
+		}
+	}
+}
+
+func (j *Jira) AddLabels(ctx context.Context, repo string, number int, labels []string) error {
+	key, err := j.issueKey(repo, number)
+	if err != nil {
+		return err
+	}
+	var ops []map[string]string
+	for _, l := range labels {
+		ops = append(ops, map[string]string{"add": l})
+	}
+	_, err = j.rest.do(ctx, http.MethodPut, "/issue/"+key, map[string]any{"update": map[string]any{"labels": ops}}, nil)
+	return err
+}
+
+// AddAssignees sets the assignee. Jira issues have one, so only one login is accepted.
+func (j *Jira) AddAssignees(ctx context.Context, repo string, number int, logins []string) error {
+	if len(logins) != 1 {
+		return fmt.Errorf("jira: an issue has a single assignee, got %d", len(logins))
+	}
+	key, err := j.issueKey(repo, number)
+	if err != nil {
+		return err
+	}
+	_, err = j.rest.do(ctx, http.MethodPut, "/issue/"+key+"/assignee", j.user(logins[0]), nil)
+	return err
+}
+
+// ListAssignees returns the account IDs (Cloud) or user names (Data Center) assignable in the project
+func (j *Jira) ListAssignees(ctx context.Context, repo string) ([]string, error) {
+	var users []jiraUser
+	if _, err := j.rest.do(ctx, http.MethodGet, "/user/assignable/search?maxResults=1000&project="+url.QueryEscape(repo), nil, &users); err != nil {
+		return nil, err
+	}
+	var logins []string
+	for i := range users {
+		logins = append(logins, users[i].login())
+	}
+	return logins, nil
+}
+
+// ListMilestones returns the unreleased versions of the project, used as fix versions
+func (j *Jira) ListMilestones(ctx context.Context, repo string) ([]Milestone, error) {
+	var versions []struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Released bool   `json:"released"`
+		Archived bool   `json:"archived"`
+	}
+	if _, err := j.rest.do(ctx, http.MethodGet, "/project/"+url.PathEscape(repo)+"/versions", nil, &versions); err != nil {
+		return nil, err
+	}
+	var out []Milestone
+	for _, v := range versions {
+		id, err := strconv.Atoi(v.ID)
+		if err != nil || v.Released || v.Archived {
+			continue
+		}
+		out = append(out, Milestone{ID: id, Title: v.Name})
+	}
+	return out, nil
+}
+
+// ListIssueTypes returns the standard issue types of the project, without sub-tasks
+func (j *Jira) ListIssueTypes(ctx context.Context, repo string) ([]string, error) {
+	var project struct {
+		IssueTypes []struct {
+			Name    string `json:"name"`
+			Subtask bool   `json:"subtask"`
+		} `json:"issueTypes"`
+	}
+	if _, err := j.rest.do(ctx, http.MethodGet, "/project/"+url.PathEscape(repo), nil, &project); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, t := range project.IssueTypes {
+		if !t.Subtask {
+			names = append(names, t.Name)
+		}
+	}
+	return names, nil
+}
+
+func (j *Jira) toIssue(i *jiraIssue) *Issue {
+	_, n, _ := strings.Cut(i.Key, "-")
+	number, _ := strconv.Atoi(n)
+	out := &Issue{
+		Number: number,
+		Title:  i.Fields.Summary,
+		Body:   fromJiraText(i.Fields.Description),
+		State:  "open",
+		URL:    j.siteURL + "/browse/" + i.Key,
+		Author: i.Fields.Reporter.login(),
+		Labels: i.Fields.Labels,
+		Type:   i.Fields.IssueType.Name,
+	}
+	if i.Fields.Status.StatusCategory.Key == "done" {
+		out.State = "closed"
+	}
+	if login := i.Fields.Assignee.login(); login != "" {
+		out.Assignees = []string{login}
+	}
+	if len(i.Fields.FixVersions) > 0 {
+		out.Milestone = i.Fields.FixVersions[0].Name
+	}
+	out.CreatedAt, _ = time.Parse(jiraTimeLayout, i.Fields.Created)
+	out.UpdatedAt, _ = time.Parse(jiraTimeLayout, i.Fields.Updated)
+	return out
+}
+
+func (j *Jira) toComment(key string, c jiraComment) Comment {
+	created, _ := time.Parse(jiraTimeLayout, c.Created)
+	return Comment{
+		ID:        c.ID,
+		Body:      fromJiraText(c.Body),
+		URL:       fmt.Sprintf("%s/browse/%s?focusedCommentId=%s", j.siteURL, key, c.ID),
+		Author:    c.Author.login(),
+		CreatedAt: created,
+	}
+}
@@ -0,0 +1,144 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// restClient sends JSON requests to the GitLab, Gitea and Jira APIs
+type restClient struct {
+	kind       string
+	baseURL    string
+	httpClient *http.Client
+	// auth sets the authentication header of a request
+	auth func(*http.Request)
+}
+
+func newRESTClient(kind, baseURL string, httpClient *http.Client, auth func(*http.Request)) *restClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &restClient{kind: kind, baseURL: strings.TrimRight(baseURL, "/"), httpClient: httpClient, auth: auth}
+}
+
+// do sends in as JSON and decodes the response into out. path is relative to
+// the base URL, or an absolute URL within it (for pagination links).
+func (c *restClient) do(ctx context.Context, method, path string, in, out any) (*http.Response, error) {
+	target := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		target = c.baseURL + path
+	} else if !strings.HasPrefix(path, c.baseURL+"/") {
+		// Never send the token to a host other than the API
+		return nil, fmt.Errorf("%s: refusing to follow link to %s", c.kind, path)
+	}
+
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to marshal request: %v", c.kind, err)
+		}
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "james-agent")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.auth(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, &APIError{Kind: c.kind, StatusCode: resp.StatusCode, Message: errorMessage(data)}
+	}
+	if out != nil && len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp, fmt.Errorf("%s: failed to decode response: %v", c.kind, err)
+		}
+	}
+	return resp, nil
+}
+
+// errorMessage extracts the message of an error body in the formats of GitLab, Gitea and Jira
+func errorMessage(data []byte) string {
+	var body struct {
+		Message       any               `json:"message"`
+		Error         string            `json:"error"`
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+	}
+	if json.Unmarshal(data, &body) == nil {
+		var parts []string
+		if body.Message != nil {
+			parts = append(parts, fmt.Sprint(body.Message))
+		}
+		if body.Error != "" {
+			parts = append(parts, body.Error)
+		}
+		parts = append(parts, body.ErrorMessages...)
+		for field, msg := range body.Errors {
+			parts = append(parts, field+": "+msg)
+		}
+		if len(parts) > 0 {
+			return strings.Join(parts, "; ")
+		}
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// listPages follows the Link header through every page of a list endpoint,
+// stopping after max items when max is positive
+func listPages[T any](ctx context.Context, c *restClient, path string, max int) ([]T, bool, error) {
+	var all []T
+	next := path
+	for next != "" {
+		var page []T
+		resp, err := c.do(ctx, http.MethodGet, next, nil, &page)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, item := range page {
+			if max > 0 && len(all) >= max {
+				return all, true, nil
+			}
+			all = append(all, item)
+		}
+		next = nextPageURL(resp.Header.Get("Link"))
+	}
+	return all, false, nil
+}
+
+// nextPageURL returns the rel="next" target of a Link header, or ""
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
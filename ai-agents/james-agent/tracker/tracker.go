@@ -0,0 +1,197 @@
+// Package tracker abstracts the issue trackers james-agent files meeting
+// action items in: GitHub, GitLab, Gitea and Jira.
+//
+// Every backend addresses issues by a repository and a number:
+//
+//   - GitHub and Gitea: "owner/repo" and the issue number
+//   - GitLab: the project path "group/project" and the issue IID
+//   - Jira: the project key "PROJ" and the number of the issue key PROJ-123
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"james-agent/main/github"
+)
+
+// Tracker kinds accepted by Config.Kind
+const (
+	KindGitHub = "github"
+	KindGitLab = "gitlab"
+	KindGitea  = "gitea"
+	KindJira   = "jira"
+)
+
+// Close reasons of CloseIssue
+const (
+	ReasonCompleted  = "completed"
+	ReasonNotPlanned = "not_planned"
+)
+
+// ErrNotSupported is returned for features a tracker does not have, such as issue types on Gitea.
+var ErrNotSupported = errors.New("tracker: not supported")
+
+// Issue is an issue in any tracker.
+type Issue struct {
+	Number int
+	Title  string
+	Body   string
+	// State is open or closed
+	State     string
+	URL       string
+	Author    string
+	Labels    []string
+	Assignees []string
+	Milestone string
+	Type      string
+	// PullRequest is set for GitHub and Gitea pull requests, which share the issue numbers
+	PullRequest bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Comment is a comment on an issue.
+type Comment struct {
+	ID        string
+	Body      string
+	URL       string
+	Author    string
+	CreatedAt time.Time
+}
+
+// Milestone is a milestone, or a fix version in Jira.
+type Milestone struct {
+	ID    int
+	Title string
+}
+
+// IssueRequest is a create or update. Nil fields are left unchanged on update.
+type IssueRequest struct {
+	Title *string
+	Body  *string
+	// Labels and Assignees are only used on create; use AddLabels and AddAssignees after that
+	Labels    *[]string
+	Assignees *[]string
+	// Milestone is a Milestone.ID
+	Milestone *int
+	Type      *string
+}
+
+// ListOptions filters ListIssues.
+type ListOptions struct {
+	// State is open, closed or all. Defaults to open.
+	State  string
+	Labels []string
+	// Assignee is a login
+	Assignee string
+	// Milestone is a milestone number on GitHub and a title elsewhere
+	Milestone string
+	// Since only returns issues updated at or after this time
+	Since time.Time
+	// Max stops listing after this many issues. Zero means no limit.
+	Max int
+}
+
+// IssueList is the result of ListIssues.
+type IssueList struct {
+	Issues []Issue
+	// Truncated is set when more issues matched than Max
+	Truncated bool
+}
+
+// IssueTracker is the set of issue operations james-agent needs.
+type IssueTracker interface {
+	// Kind is the backend, one of the Kind constants
+	Kind() string
+	GetIssue(ctx context.Context, repo string, number int) (*Issue, error)
+	// ListIssues returns issues, never pull requests, most recently created first
+	ListIssues(ctx context.Context, repo string, opts ListOptions) (*IssueList, error)
+	// SearchIssues returns up to 100 issues in any state whose text contains the phrase
+	SearchIssues(ctx context.Context, repo, phrase string) ([]Issue, error)
+	CreateIssue(ctx context.Context, repo string, req IssueRequest) (*Issue, error)
+	UpdateIssue(ctx context.Context, repo string, number int, req IssueRequest) (*Issue, error)
+	// CloseIssue closes with ReasonCompleted or ReasonNotPlanned. GitHub records
+	// the reason, Jira picks a matching transition, GitLab and Gitea ignore it.
+	CloseIssue(ctx context.Context, repo string, number int, reason string) (*Issue, error)
+	AddComment(ctx context.Context, repo string, number int, body string) (*Comment, error)
+	ListComments(ctx context.Context, repo string, number int) ([]Comment, error)
+	ListLabels(ctx context.Context, repo string) ([]string, error)
+	AddLabels(ctx context.Context, repo string, number int, labels []string) error
+	AddAssignees(ctx context.Context, repo string, number int, logins []string) error
+	ListAssignees(ctx context.Context, repo string) ([]string, error)
+	ListMilestones(ctx context.Context, repo string) ([]Milestone, error)
+	// ListIssueTypes returns ErrNotSupported when the repository has no issue types
+	ListIssueTypes(ctx context.Context, repo string) ([]string, error)
+}
+
+// Config selects and configures a tracker.
+type Config struct {
+	// Kind is github, gitlab, gitea or jira. Defaults to github.
+	Kind string `yaml:"kind"`
+	// URL is the API base URL. Defaults to the public GitHub, GitLab or Gitea API; required for Jira.
+	URL   string `yaml:"url"`
+	Token string `yaml:"-"`
+	// Email switches Jira to basic authentication with Email and Token, as Jira Cloud requires
+	Email string `yaml:"email"`
+	// HTTPClient defaults to a client with a 30s timeout
+	HTTPClient *http.Client `yaml:"-"`
+}
+
+// New returns the tracker for cfg.
+func New(cfg Config) (IssueTracker, error) {
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("tracker: no token for %s", cfg.Kind)
+	}
+	switch cfg.Kind {
+	case KindGitHub, "":
+		return NewGitHub(github.NewClient(github.Config{BaseURL: cfg.URL, Token: cfg.Token, HTTPClient: cfg.HTTPClient})), nil
+	case KindGitLab:
+		return NewGitLab(cfg), nil
+	case KindGitea:
+		return NewGitea(cfg), nil
+	case KindJira:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("tracker: jira needs the url of the site")
+		}
+		return NewJira(cfg), nil
+	}
+	return nil, fmt.Errorf("tracker: unknown kind %q, use github, gitlab, gitea or jira", cfg.Kind)
+}
+
+// APIError is a non-2xx response from a GitLab, Gitea or Jira API.
+type APIError struct {
+	Kind       string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %d %s", e.Kind, e.StatusCode, e.Message)
+}
+
+// StatusCode returns the HTTP status of an API error from any backend, or 0.
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	var ghErr *github.APIError
+	if errors.As(err, &ghErr) {
+		return ghErr.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound reports whether err is a 404 from any backend.
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// String returns a pointer to s, for IssueRequest fields.
+func String(s string) *string {
+	return &s
+}
@@ -0,0 +1,182 @@
+package trackertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"james-agent/main/tracker"
+)
+
+// Contract runs every IssueTracker operation against a fake seeded by this
+// package and returns the first behaviour that differs from what james-agent
+// relies on.
+func Contract(ctx context.Context, tr tracker.IssueTracker, repo string) error {
+	labels, err := tr.ListLabels(ctx, repo)
+	if err != nil {
+		return fmt.Errorf("ListLabels: %v", err)
+	}
+	if !slices.Contains(labels, "bug") {
+		return fmt.Errorf("ListLabels: want bug in %v", labels)
+	}
+	assignees, err := tr.ListAssignees(ctx, repo)
+	if err != nil {
+		return fmt.Errorf("ListAssignees: %v", err)
+	}
+	if !slices.Contains(assignees, "alice") || !slices.Contains(assignees, "bob") {
+		return fmt.Errorf("ListAssignees: want alice and bob in %v", assignees)
+	}
+	milestones, err := tr.ListMilestones(ctx, repo)
+	if err != nil {
+		return fmt.Errorf("ListMilestones: %v", err)
+	}
+	if len(milestones) != 1 || milestones[0].Title != "v1.0" {
+		return fmt.Errorf("ListMilestones: want only the open milestone v1.0, got %v", milestones)
+	}
+	types, err := tr.ListIssueTypes(ctx, repo)
+	if err != nil && !errors.Is(err, tracker.ErrNotSupported) {
+		return fmt.Errorf("ListIssueTypes: %v", err)
+	}
+	if slices.Contains(types, "Sub-task") {
+		return fmt.Errorf("ListIssueTypes: sub-tasks cannot be created directly, got %v", types)
+	}
+
+	if _, err := tr.GetIssue(ctx, repo, 999); !tracker.IsNotFound(err) {
+		return fmt.Errorf("GetIssue of a missing issue: want a not found error, got %v", err)
+	}
+
+	// Create with every field
+	const marker = "contract-marker-7f3a"
+	req := tracker.IssueRequest{
+		Title:     tracker.String("Contract issue"),
+		Body:      tracker.String("Created by the contract\n\n<!-- " + marker + " -->"),
+		Labels:    &[]string{"bug"},
+		Assignees: &[]string{"alice"},
+		Milestone: &milestones[0].ID,
+	}
+	if len(types) > 0 {
+		req.Type = &types[0]
+	}
+	created, err := tr.CreateIssue(ctx, repo, req)
+	if err != nil {
+		return fmt.Errorf("CreateIssue: %v", err)
+	}
+	if created.Number <= 1 || created.URL == "" {
+		return fmt.Errorf("CreateIssue: want a new number and URL, got #%d %q", created.Number, created.URL)
+	}
+	if _, err := tr.CreateIssue(ctx, repo, tracker.IssueRequest{Body: tracker.String("no title")}); err == nil {
+		return fmt.Errorf("CreateIssue without a title: want an error")
+	}
+
+	issue, err := tr.GetIssue(ctx, repo, created.Number)
+	if err != nil {
+		return fmt.Errorf("GetIssue: %v", err)
+	}
+	switch {
+	case issue.Title != "Contract issue" || !strings.Contains(issue.Body, marker):
+		return fmt.Errorf("GetIssue: title or body not stored: %q %q", issue.Title, issue.Body)
+	case issue.State != "open":
+		return fmt.Errorf("GetIssue: want state open, got %q", issue.State)
+	case !slices.Equal(issue.Labels, []string{"bug"}):
+		return fmt.Errorf("GetIssue: want labels [bug], got %v", issue.Labels)
+	case !slices.Equal(issue.Assignees, []string{"alice"}):
+		return fmt.Errorf("GetIssue: want assignees [alice], got %v", issue.Assignees)
+	case issue.Milestone != "v1.0":
+		return fmt.Errorf("GetIssue: want milestone v1.0, got %q", issue.Milestone)
+	case req.Type != nil && issue.Type != *req.Type:
+		return fmt.Errorf("GetIssue: want type %q, got %q", *req.Type, issue.Type)
+	case issue.PullRequest:
+		return fmt.Errorf("GetIssue: an issue is not a pull request")
+	}
+
+	list, err := tr.ListIssues(ctx, repo, tracker.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("ListIssues: %v", err)
+	}
+	if len(list.Issues) != 2 || list.Truncated || list.Issues[0].Number != created.Number {
+		return fmt.Errorf("ListIssues: want the 2 open issues newest first, got %d (truncated %v)", len(list.Issues), list.Truncated)
+	}
+	list, err = tr.ListIssues(ctx, repo, tracker.ListOptions{Max: 1})
+	if err != nil {
+		return fmt.Errorf("ListIssues with Max: %v", err)
+	}
+	if len(list.Issues) != 1 || !list.Truncated {
+		return fmt.Errorf("ListIssues with Max 1: want 1 issue and Truncated, got %d (truncated %v)", len(list.Issues), list.Truncated)
+	}
+	list, err = tr.ListIssues(ctx, repo, tracker.ListOptions{Labels: []string{"bug"}})
+	if err != nil {
+		return fmt.Errorf("ListIssues by label: %v", err)
+	}
+	if len(list.Issues) != 1 || list.Issues[0].Number != created.Number {
+		return fmt.Errorf("ListIssues by label: want only #%d, got %d issues", created.Number, len(list.Issues))
+	}
+
+	found, err := tr.SearchIssues(ctx, repo, marker)
+	if err != nil {
+		return fmt.Errorf("SearchIssues: %v", err)
+	}
+	if len(found) != 1 || found[0].Number != created.Number {
+		return fmt.Errorf("SearchIssues: want only #%d, got %d issues", created.Number, len(found))
+	}
+
+	comment, err := tr.AddComment(ctx, repo, created.Number, "Meeting notes "+marker)
+	if err != nil {
+		return fmt.Errorf("AddComment: %v", err)
+	}
+	if comment.URL == "" || comment.ID == "" {
+		return fmt.Errorf("AddComment: want an ID and URL, got %+v", comment)
+	}
+	comments, err := tr.ListComments(ctx, repo, created.Number)
+	if err != nil {
+		return fmt.Errorf("ListComments: %v", err)
+	}
+	if len(comments) != 1 || comments[0].Body != "Meeting notes "+marker {
+		return fmt.Errorf("ListComments: want only the added comment, got %d comments", len(comments))
+	}
+
+	if err := tr.AddLabels(ctx, repo, created.Number, []string{"P1"}); err != nil {
+		return fmt.Errorf("AddLabels: %v", err)
+	}
+	// Jira issues have a single assignee, so there the new one replaces alice
+	if err := tr.AddAssignees(ctx, repo, created.Number, []string{"bob"}); err != nil {
+		return fmt.Errorf("AddAssignees: %v", err)
+	}
+	updated, err := tr.UpdateIssue(ctx, repo, created.Number, tracker.IssueRequest{Title: tracker.String("Contract issue, renamed")})
+	if err != nil {
+		return fmt.Errorf("UpdateIssue: %v", err)
+	}
+	switch {
+	case updated.Title != "Contract issue, renamed":
+		return fmt.Errorf("UpdateIssue: title not changed, got %q", updated.Title)
+	case !strings.Contains(updated.Body, marker):
+		return fmt.Errorf("UpdateIssue: a title change must keep the body")
+	case !slices.Contains(updated.Labels, "bug") || !slices.Contains(updated.Labels, "P1"):
+		return fmt.Errorf("AddLabels: want bug and P1, got %v", updated.Labels)
+	case !slices.Contains(updated.Assignees, "bob"):
+		return fmt.Errorf("AddAssignees: want bob in %v", updated.Assignees)
+	case tr.Kind() != tracker.KindJira && !slices.Contains(updated.Assignees, "alice"):
+		return fmt.Errorf("AddAssignees: must keep alice, got %v", updated.Assignees)
+	}
+
+	closed, err := tr.CloseIssue(ctx, repo, created.Number, tracker.ReasonCompleted)
+	if err != nil {
+		return fmt.Errorf("CloseIssue: %v", err)
+	}
+	if closed.State != "closed" {
+		return fmt.Errorf("CloseIssue: want state closed, got %q", closed.State)
+	}
+	list, err = tr.ListIssues(ctx, repo, tracker.ListOptions{State: "closed"})
+	if err != nil {
+		return fmt.Errorf("ListIssues closed: %v", err)
+	}
+	if len(list.Issues) != 1 || list.Issues[0].Number != created.Number {
+		return fmt.Errorf("ListIssues closed: want only #%d, got %d issues", created.Number, len(list.Issues))
+	}
+	found, err = tr.SearchIssues(ctx, repo, marker)
+	if err != nil || len(found) != 1 {
+		return fmt.Errorf("SearchIssues: closed issues must be found too, got %d issues, %v", len(found), err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package trackertest_test
+
+import (
+	"context"
+	"testing"
+
+	"james-agent/main/tracker"
+	"james-agent/main/tracker/trackertest"
+)
+
+// TestContract runs the contract against every backend and its fake
+func TestContract(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		start func() *trackertest.Server
+	}{
+		{"github", trackertest.NewGitHub},
+		{"gitlab", trackertest.NewGitLab},
+		{"gitea", trackertest.NewGitea},
+		{"jira", trackertest.NewJira},
+		{"jira cloud", trackertest.NewJiraCloud},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := tc.start()
+			defer srv.Close()
+			tr, err := tracker.New(srv.Config())
+			if err != nil {
+				t.Fatalf("tracker.New: %v", err)
+			}
+			if err := trackertest.Contract(context.Background(), tr, srv.Repo); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package trackertest
+
+import (
+	"fmt"
+	"net/http"
+
+	"james-agent/main/tracker"
+)
+
+// NewGitea starts a fake of the Gitea REST API v1 serving octo/widgets.
+func NewGitea() *Server {
+	authorized := func(r *http.Request) bool { return r.Header.Get("Authorization") == "token "+Token }
+	return newServer(tracker.KindGitea, "octo/widgets", "/api/v1", nil, authorized, giteaRoutes)
+}
+
+func giteaRoutes(s *Server, handle handleFunc) {
+	issueOf := func(w http.ResponseWriter, r *http.Request) *tracker.Issue {
+		n, ok := pathNumber(r, "index")
+		var issue *tracker.Issue
+		if ok && r.PathValue("owner")+"/"+r.PathValue("repo") == s.Repo {
+			issue = s.issue(n)
+		}
+		if issue == nil {
+			notFound(w)
+		}
+		return issue
+	}
+	repo := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.PathValue("owner")+"/"+r.PathValue("repo") != s.Repo {
+			notFound(w)
+			return false
+		}
+		return true
+	}
+
+	handle("GET /repos/{owner}/{repo}/issues/{index}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if issue := issueOf(w, r); issue != nil {
+			writeJSON(w, http.StatusOK, s.giteaIssue(issue))
+		}
+	})
+	handle("GET /repos/{owner}/{repo}/issues", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !repo(w, r) {
+			return
+		}
+		q := r.URL.Query()
+		state := q.Get("state")
+		if state == "" {
+			state = "open"
+		}
+		var out []map[string]any
+		for _, issue := range page(w, r, s.filter(state, splitList(q.Get("labels")), q.Get("q")), "limit") {
+			out = append(out, s.giteaIssue(issue))
+		}
+		writeJSON(w, http.StatusOK, orEmpty(out))
+	})
+	handle("POST /repos/{owner}/{repo}/issues", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !repo(w, r) {
+			return
+		}
+		var req giteaRequest
+		if err := readJSON(r, &req); err != nil || req.Title == nil || *req.Title == "" {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "[Title]: Required"})
+			return
+		}
+		issue := s.create(tracker.Issue{})
+		if err := s.applyGitea(issue, req); err != nil {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.giteaIssue(issue))
+	})
+	handle("PATCH /repos/{owner}/{repo}/issues/{index}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := issueOf(w, r)
+		if issue == nil {
+			return
+		}
+		var req giteaRequest
+		if err := readJSON(r, &req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		if req.Labels != nil {
+			badRequest(w, "labels are edited through the labels endpoint")
+			return
+		}
+		if err := s.applyGitea(issue, req); err != nil {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.giteaIssue(issue))
+	})
+	handle("GET /repos/{owner}/{repo}/issues/{index}/comments", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := issueOf(w, r)
+		if issue == nil {
+			return
+		}
+		var out []map[string]any
+		for _, c := range s.comments[issue.Number] {
+			out = append(out, giteaComment(c))
+		}
+		writeJSON(w, http.StatusOK, orEmpty(out))
+	})
+	handle("POST /repos/{owner}/{repo}/issues/{index}/comments", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := issueOf(w, r)
+		if issue == nil {
+			return
+		}
+		var req struct {
+			Body string `json:"body"`
+		}
+		if err := readJSON(r, &req); err != nil || req.Body == "" {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "[Body]: Required"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, giteaComment(s.comment(issue.Number, req.Body)))
+	})
+	handle("POST /repos/{owner}/{repo}/issues/{index}/labels", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := issueOf(w, r)
+		if issue == nil {
+			return
+		}
+		var req struct {
+			Labels []int `json:"labels"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		for _, id := range req.Labels {
+			if id < 1 || id > len(s.labels) {
+				writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": fmt.Sprintf("label %d does not exist", id)})
+				return
+			}
+			if l := s.labels[id-1]; !hasString(issue.Labels, l) {
+				issue.Labels = append(issue.Labels, l)
+			}
+		}
+		writeJSON(w, http.StatusOK, s.giteaLabels(issue.Labels))
+	})
+	handle("GET /repos/{owner}/{repo}/labels", func(w http.ResponseWriter, r *http.Request) {
+		if repo(w, r) {
+			writeJSON(w, http.StatusOK, page(w, r, s.giteaLabels(s.labels), "limit"))
+		}
+	})
+	handle("GET /repos/{owner}/{repo}/assignees", func(w http.ResponseWriter, r *http.Request) {
+		if repo(w, r) {
+			writeJSON(w, http.StatusOK, githubNames(s.assignees, "login"))
+		}
+	})
+	handle("GET /repos/{owner}/{repo}/milestones", func(w http.ResponseWriter, r *http.Request) {
+		if !repo(w, r) {
+			return
+		}
+		var out []map[string]any
+		for _, m := range s.milestones {
+			out = append(out, map[string]any{"id": m.ID, "title": m.Title, "state": "open"})
+		}
+		writeJSON(w, http.StatusOK, page(w, r, out, "limit"))
+	})
+}
+
+// giteaRequest is the body of a create or edit
+type giteaRequest struct {
+	Title     *string   `json:"title"`
+	Body      *string   `json:"body"`
+	State     *string   `json:"state"`
+	Assignees *[]string `json:"assignees"`
+	Labels    *[]int    `json:"labels"`
+	Milestone *int      `json:"milestone"`
+}
+
+// applyGitea changes an issue as Gitea does for a create or edit. The caller holds s.mu.
+func (s *Server) applyGitea(issue *tracker.Issue, req giteaRequest) error {
+	if req.Title != nil {
+		issue.Title = *req.Title
+	}
+	if req.Body != nil {
+		issue.Body = *req.Body
+	}
+	if req.State != nil {
+		issue.State = *req.State
+	}
+	if req.Assignees != nil {
+		for _, a := range *req.Assignees {
+			if !hasString(s.assignees, a) {
+				return fmt.Errorf("user %s does not exist", a)
+			}
+		}
+		issue.Assignees = *req.Assignees
+	}
+	if req.Labels != nil {
+		issue.Labels = nil
+		for _, id := range *req.Labels {
+			if id < 1 || id > len(s.labels) {
+				return fmt.Errorf("label %d does not exist", id)
+			}
+			issue.Labels = append(issue.Labels, s.labels[id-1])
+		}
+	}
+	if req.Milestone != nil {
+		issue.Milestone = s.milestoneTitle(*req.Milestone)
+	}
+	return nil
+}
+
+// giteaLabels renders labels with their IDs, the position in s.labels
+func (s *Server) giteaLabels(names []string) []map[string]any {
+	out := []map[string]any{}
+	for _, n := range names {
+		for i, l := range s.labels {
+			if l == n {
+				out = append(out, map[string]any{"id": i + 1, "name": l})
+			}
+		}
+	}
+	return out
+}
+
+func (s *Server) giteaIssue(issue *tracker.Issue) map[string]any {
+	out := map[string]any{
+		"number":       issue.Number,
+		"title":        issue.Title,
+		"body":         issue.Body,
+		"state":        issue.State,
+		"html_url":     issue.URL,
+		"user":         map[string]string{"login": issue.Author},
+		"labels":       s.giteaLabels(issue.Labels),
+		"assignees":    githubNames(issue.Assignees, "login"),
+		"pull_request": nil,
+		"created_at":   issue.CreatedAt,
+		"updated_at":   issue.UpdatedAt,
+	}
+	if issue.Milestone != "" {
+		out["milestone"] = map[string]string{"title": issue.Milestone}
+	}
+	return out
+}
+
+func giteaComment(c tracker.Comment) map[string]any {
+	return map[string]any{
+		"id":         numericID(c.ID),
+		"body":       c.Body,
+		"html_url":   c.URL,
+		"user":       map[string]string{"login": c.Author},
+		"created_at": c.CreatedAt,
+	}
+}
@@ -0,0 +1,303 @@
+package trackertest
+
+import (
+	"net/http"
+	"strings"
+
+	"james-agent/main/tracker"
+)
+
+// NewGitHub starts a fake of the GitHub REST API serving octo/widgets.
+func NewGitHub() *Server {
+	authorized := func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer "+Token }
+	return newServer(tracker.KindGitHub, "octo/widgets", "", []string{"Bug", "Feature", "Task"}, authorized, githubRoutes)
+}
+
+func githubRoutes(s *Server, handle handleFunc) {
+	// repoIssue resolves the repository and issue of a request, writing a 404 if either is unknown
+	repoIssue := func(w http.ResponseWriter, r *http.Request) *tracker.Issue {
+		n, ok := pathNumber(r, "number")
+		var issue *tracker.Issue
+		if ok && r.PathValue("owner")+"/"+r.PathValue("repo") == s.Repo {
+			issue = s.issue(n)
+		}
+		if issue == nil {
+			notFound(w)
+		}
+		return issue
+	}
+	repo := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.PathValue("owner")+"/"+r.PathValue("repo") != s.Repo {
+			notFound(w)
+			return false
+		}
+		return true
+	}
+
+	handle("GET /repos/{owner}/{repo}/issues/{number}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if issue := repoIssue(w, r); issue != nil {
+			writeJSON(w, http.StatusOK, s.githubIssue(issue))
+		}
+	})
+	handle("GET /repos/{owner}/{repo}/issues", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !repo(w, r) {
+			return
+		}
+		q := r.URL.Query()
+		state := q.Get("state")
+		if state == "" {
+			state = "open"
+		}
+		var out []map[string]any
+		for _, issue := range page(w, r, s.filter(state, splitList(q.Get("labels")), ""), "per_page") {
+			out = append(out, s.githubIssue(issue))
+		}
+		writeJSON(w, http.StatusOK, orEmpty(out))
+	})
+	handle("GET /search/issues", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// Only the repo: qualifier and one quoted phrase are understood
+		query := r.URL.Query().Get("q")
+		if !strings.Contains(query, "repo:"+s.Repo) {
+			writeJSON(w, http.StatusOK, map[string]any{"items": []any{}})
+			return
+		}
+		phrase := ""
+		if parts := strings.Split(query, `"`); len(parts) >= 3 {
+			phrase = parts[1]
+		}
+		var items []map[string]any
+		for _, issue := range s.filter("all", nil, phrase) {
+			items = append(items, s.githubIssue(issue))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"total_count": len(items), "items": orEmpty(items)})
+	})
+	handle("POST /repos/{owner}/{repo}/issues", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !repo(w, r) {
+			return
+		}
+		var req struct {
+			Title     string   `json:"title"`
+			Body      string   `json:"body"`
+			Labels    []string `json:"labels"`
+			Assignees []string `json:"assignees"`
+			Milestone *int     `json:"milestone"`
+			Type      string   `json:"type"`
+		}
+		if err := readJSON(r, &req); err != nil || req.Title == "" {
+			badRequest(w, "title is required")
+			return
+		}
+		issue := tracker.Issue{Title: req.Title, Body: req.Body, Labels: req.Labels, Assignees: req.Assignees, Type: req.Type}
+		if req.Milestone != nil {
+			issue.Milestone = s.milestoneTitle(*req.Milestone)
+		}
+		writeJSON(w, http.StatusCreated, s.githubIssue(s.create(issue)))
+	})
+	handle("PATCH /repos/{owner}/{repo}/issues/{number}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := repoIssue(w, r)
+		if issue == nil {
+			return
+		}
+		var req struct {
+			Title       *string `json:"title"`
+			Body        *string `json:"body"`
+			State       *string `json:"state"`
+			StateReason *string `json:"state_reason"`
+			Milestone   *int    `json:"milestone"`
+			Type        *string `json:"type"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		if req.StateReason != nil && *req.StateReason != "completed" && *req.StateReason != "not_planned" && *req.StateReason != "reopened" {
+			badRequest(w, "invalid state_reason "+*req.StateReason)
+			return
+		}
+		if req.Title != nil {
+			issue.Title = *req.Title
+		}
+		if req.Body != nil {
+			issue.Body = *req.Body
+		}
+		if req.State != nil {
+			issue.State = *req.State
+		}
+		if req.Milestone != nil {
+			issue.Milestone = s.milestoneTitle(*req.Milestone)
+		}
+		if req.Type != nil {
+			issue.Type = *req.Type
+		}
+		writeJSON(w, http.StatusOK, s.githubIssue(issue))
+	})
+	handle("GET /repos/{owner}/{repo}/issues/{number}/comments", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := repoIssue(w, r)
+		if issue == nil {
+			return
+		}
+		var out []map[string]any
+		for _, c := range page(w, r, s.comments[issue.Number], "per_page") {
+			out = append(out, githubComment(c))
+		}
+		writeJSON(w, http.StatusOK, orEmpty(out))
+	})
+	handle("POST /repos/{owner}/{repo}/issues/{number}/comments", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := repoIssue(w, r)
+		if issue == nil {
+			return
+		}
+		var req struct {
+			Body string `json:"body"`
+		}
+		if err := readJSON(r, &req); err != nil || req.Body == "" {
+			badRequest(w, "body is required")
+			return
+		}
+		writeJSON(w, http.StatusCreated, githubComment(s.comment(issue.Number, req.Body)))
+	})
+	handle("POST /repos/{owner}/{repo}/issues/{number}/labels", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := repoIssue(w, r)
+		if issue == nil {
+			return
+		}
+		var req struct {
+			Labels []string `json:"labels"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		for _, l := range req.Labels {
+			if !hasString(issue.Labels, l) {
+				issue.Labels = append(issue.Labels, l)
+			}
+		}
+		writeJSON(w, http.StatusOK, githubNames(issue.Labels, "name"))
+	})
+	handle("POST /repos/{owner}/{repo}/issues/{number}/assignees", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := repoIssue(w, r)
+		if issue == nil {
+			return
+		}
+		var req struct {
+			Assignees []string `json:"assignees"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		for _, a := range req.Assignees {
+			if hasString(s.assignees, a) && !hasString(issue.Assignees, a) {
+				issue.Assignees = append(issue.Assignees, a)
+			}
+		}
+		writeJSON(w, http.StatusCreated, s.githubIssue(issue))
+	})
+	handle("GET /repos/{owner}/{repo}/labels", func(w http.ResponseWriter, r *http.Request) {
+		if repo(w, r) {
+			writeJSON(w, http.StatusOK, page(w, r, githubNames(s.labels, "name"), "per_page"))
+		}
+	})
+	handle("GET /repos/{owner}/{repo}/assignees", func(w http.ResponseWriter, r *http.Request) {
+		if repo(w, r) {
+			writeJSON(w, http.StatusOK, page(w, r, githubNames(s.assignees, "login"), "per_page"))
+		}
+	})
+	handle("GET /repos/{owner}/{repo}/milestones", func(w http.ResponseWriter, r *http.Request) {
+		if !repo(w, r) {
+			return
+		}
+		var out []map[string]any
+		for _, m := range s.milestones {
+			out = append(out, map[string]any{"number": m.ID, "title": m.Title, "state": "open"})
+		}
+		writeJSON(w, http.StatusOK, page(w, r, out, "per_page"))
+	})
+	handle("GET /orgs/{org}/issue-types", func(w http.ResponseWriter, r *http.Request) {
+		if owner, _, _ := strings.Cut(s.Repo, "/"); r.PathValue("org") != owner {
+			notFound(w)
+			return
+		}
+		writeJSON(w, http.StatusOK, githubNames(s.issueTypes, "name"))
+	})
+}
+
+func (s *Server) githubIssue(issue *tracker.Issue) map[string]any {
+	out := map[string]any{
+		"number":     issue.Number,
+		"title":      issue.Title,
+		"body":       issue.Body,
+		"state":      issue.State,
+		"html_url":   issue.URL,
+		"user":       map[string]string{"login": issue.Author},
+		"labels":     githubNames(issue.Labels, "name"),
+		"assignees":  githubNames(issue.Assignees, "login"),
+		"comments":   len(s.comments[issue.Number]),
+		"created_at": issue.CreatedAt,
+		"updated_at": issue.UpdatedAt,
+	}
+	for _, m := range s.milestones {
+		if m.Title == issue.Milestone {
+			out["milestone"] = map[string]any{"number": m.ID, "title": m.Title, "state": "open"}
+		}
+	}
+	if issue.Type != "" {
+		out["type"] = map[string]string{"name": issue.Type}
+	}
+	return out
+}
+
+func githubComment(c tracker.Comment) map[string]any {
+	return map[string]any{
+		"id":         numericID(c.ID),
+		"body":       c.Body,
+		"html_url":   c.URL,
+		"user":       map[string]string{"login": c.Author},
+		"created_at": c.CreatedAt,
+	}
+}
+
+// githubNames renders names as a list of objects with one field
+func githubNames(names []string, field string) []map[string]string {
+	out := []map[string]string{}
+	for _, n := range names {
+		out = append(out, map[string]string{field: n})
+	}
+	return out
+}
+
+func (s *Server) milestoneTitle(id int) string {
+	for _, m := range s.milestones {
+		if m.ID == id {
+			return m.Title
+		}
+	}
+	return ""
+}
+
+// orEmpty keeps an empty list from being encoded as null
+func orEmpty[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,258 @@
+package trackertest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"james-agent/main/tracker"
+)
+
+// NewGitLab starts a fake of the GitLab REST API v4 serving the project octo/widgets.
+func NewGitLab() *Server {
+	authorized := func(r *http.Request) bool { return r.Header.Get("PRIVATE-TOKEN") == Token }
+	return newServer(tracker.KindGitLab, "octo/widgets", "/api/v4", nil, authorized, gitlabRoutes)
+}
+
+func gitlabRoutes(s *Server, handle handleFunc) {
+	issueOf := func(w http.ResponseWriter, r *http.Request) *tracker.Issue {
+		n, ok := pathNumber(r, "iid")
+		var issue *tracker.Issue
+		if ok && unescaped(r, "id") == s.Repo {
+			issue = s.issue(n)
+		}
+		if issue == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "404 Issue Not Found"})
+		}
+		return issue
+	}
+	project := func(w http.ResponseWriter, r *http.Request) bool {
+		if unescaped(r, "id") != s.Repo {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "404 Project Not Found"})
+			return false
+		}
+		return true
+	}
+
+	handle("GET /projects/{id}/issues/{iid}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if issue := issueOf(w, r); issue != nil {
+			writeJSON(w, http.StatusOK, s.gitlabIssue(issue))
+		}
+	})
+	handle("GET /projects/{id}/issues", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !project(w, r) {
+			return
+		}
+		q := r.URL.Query()
+		state := "all"
+		switch q.Get("state") {
+		case "opened":
+			state = "open"
+		case "closed":
+			state = "closed"
+		}
+		var out []map[string]any
+		for _, issue := range page(w, r, s.filter(state, splitList(q.Get("labels")), q.Get("search")), "per_page") {
+			out = append(out, s.gitlabIssue(issue))
+		}
+		writeJSON(w, http.StatusOK, orEmpty(out))
+	})
+	handle("POST /projects/{id}/issues", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !project(w, r) {
+			return
+		}
+		var req gitlabRequest
+		if err := readJSON(r, &req); err != nil || req.Title == nil || *req.Title == "" {
+			badRequest(w, "title is missing")
+			return
+		}
+		issue := s.create(tracker.Issue{Title: *req.Title, Type: "issue"})
+		if err := s.applyGitLab(issue, req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.gitlabIssue(issue))
+	})
+	handle("PUT /projects/{id}/issues/{iid}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := issueOf(w, r)
+		if issue == nil {
+			return
+		}
+		var req gitlabRequest
+		if err := readJSON(r, &req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		if err := s.applyGitLab(issue, req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, s.gitlabIssue(issue))
+	})
+	handle("GET /projects/{id}/issues/{iid}/notes", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := issueOf(w, r)
+		if issue == nil {
+			return
+		}
+		// A system note, as GitLab records label changes, which clients must skip
+		notes := []map[string]any{{"id": 1000, "body": "added ~bug label", "system": true, "author": map[string]string{"username": "james"}}}
+		for _, c := range s.comments[issue.Number] {
+			notes = append(notes, gitlabNote(c))
+		}
+		writeJSON(w, http.StatusOK, page(w, r, notes, "per_page"))
+	})
+	handle("POST /projects/{id}/issues/{iid}/notes", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := issueOf(w, r)
+		if issue == nil {
+			return
+		}
+		var req struct {
+			Body string `json:"body"`
+		}
+		if err := readJSON(r, &req); err != nil || req.Body == "" {
+			badRequest(w, "body is missing")
+			return
+		}
+		writeJSON(w, http.StatusCreated, gitlabNote(s.comment(issue.Number, req.Body)))
+	})
+	handle("GET /projects/{id}/labels", func(w http.ResponseWriter, r *http.Request) {
+		if project(w, r) {
+			writeJSON(w, http.StatusOK, page(w, r, githubNames(s.labels, "name"), "per_page"))
+		}
+	})
+	handle("GET /projects/{id}/members/all", func(w http.ResponseWriter, r *http.Request) {
+		if !project(w, r) {
+			return
+		}
+		var out []map[string]any
+		for i, a := range s.assignees {
+			out = append(out, map[string]any{"id": i + 1, "username": a})
+		}
+		writeJSON(w, http.StatusOK, page(w, r, out, "per_page"))
+	})
+	handle("GET /projects/{id}/milestones", func(w http.ResponseWriter, r *http.Request) {
+		if !project(w, r) {
+			return
+		}
+		var out []map[string]any
+		for _, m := range s.milestones {
+			out = append(out, map[string]any{"id": m.ID, "iid": 1, "title": m.Title, "state": "active"})
+		}
+		writeJSON(w, http.StatusOK, page(w, r, out, "per_page"))
+	})
+	handle("GET /users", func(w http.ResponseWriter, r *http.Request) {
+		out := []map[string]any{}
+		if i := slices.Index(s.assignees, r.URL.Query().Get("username")); i >= 0 {
+			out = append(out, map[string]any{"id": i + 1, "username": s.assignees[i]})
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+}
+
+// gitlabRequest is the body of a create or edit
+type gitlabRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Labels      *string `json:"labels"`
+	AddLabels   *string `json:"add_labels"`
+	AssigneeIDs *[]int  `json:"assignee_ids"`
+	MilestoneID *int    `json:"milestone_id"`
+	IssueType   *string `json:"issue_type"`
+	StateEvent  *string `json:"state_event"`
+}
+
+// applyGitLab changes an issue as GitLab does for a create or edit. The caller holds s.mu.
+func (s *Server) applyGitLab(issue *tracker.Issue, req gitlabRequest) error {
+	if req.Title != nil {
+		issue.Title = *req.Title
+	}
+	if req.Description != nil {
+		issue.Body = *req.Description
+	}
+	if req.Labels != nil {
+		issue.Labels = splitList(*req.Labels)
+	}
+	if req.AddLabels != nil {
+		for _, l := range splitList(*req.AddLabels) {
+			if !hasString(issue.Labels, l) {
+				issue.Labels = append(issue.Labels, l)
+			}
+		}
+	}
+	if req.AssigneeIDs != nil {
+		issue.Assignees = nil
+		for _, id := range *req.AssigneeIDs {
+			if id < 1 || id > len(s.assignees) {
+				return fmt.Errorf("unknown user %d", id)
+			}
+			issue.Assignees = append(issue.Assignees, s.assignees[id-1])
+		}
+	}
+	if req.MilestoneID != nil {
+		issue.Milestone = s.milestoneTitle(*req.MilestoneID)
+	}
+	if req.IssueType != nil {
+		issue.Type = *req.IssueType
+	}
+	if req.StateEvent != nil {
+		switch *req.StateEvent {
+		case "close":
+			issue.State = "closed"
+		case "reopen":
+			issue.State = "open"
+		default:
+			return fmt.Errorf("state_event does not have a valid value")
+		}
+	}
+	return nil
+}
+
+func (s *Server) gitlabIssue(issue *tracker.Issue) map[string]any {
+	state := "opened"
+	if issue.State == "closed" {
+		state = "closed"
+	}
+	labels := issue.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	out := map[string]any{
+		"iid":         issue.Number,
+		"title":       issue.Title,
+		"description": issue.Body,
+		"state":       state,
+		"web_url":     issue.URL,
+		"author":      map[string]string{"username": issue.Author},
+		"labels":      labels,
+		"assignees":   githubNames(issue.Assignees, "username"),
+		"issue_type":  issue.Type,
+		"created_at":  issue.CreatedAt,
+		"updated_at":  issue.UpdatedAt,
+	}
+	if issue.Milestone != "" {
+		out["milestone"] = map[string]string{"title": issue.Milestone}
+	}
+	return out
+}
+
+func gitlabNote(c tracker.Comment) map[string]any {
+	return map[string]any{
+		"id":         numericID(c.ID),
+		"body":       c.Body,
+		"system":     false,
+		"author":     map[string]string{"username": c.Author},
+		"created_at": c.CreatedAt,
+	}
+}
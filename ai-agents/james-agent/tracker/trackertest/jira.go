@@ -0,0 +1,470 @@
+package trackertest
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"james-agent/main/tracker"
+)
+
+// NewJira starts a fake of the Jira REST API v2 serving the project WID,
+// authenticated like Jira Data Center with a bearer token.
+func NewJira() *Server {
+	authorized := func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer "+Token }
+	return newServer(tracker.KindJira, "WID", "/rest/api/2", []string{"Task", "Bug", "Story"}, authorized, jiraRoutes)
+}
+
+// NewJiraCloud starts a fake of Jira Cloud serving the project WID. Requests
+// authenticate with an email and API token, users are account IDs and issues
+// are searched on /search/jql with page tokens, as /search was removed.
+func NewJiraCloud() *Server {
+	authorized := func(r *http.Request) bool {
+		user, token, ok := r.BasicAuth()
+		return ok && user == jiraCloudEmail && token == Token
+	}
+	s := newServer(tracker.KindJira, "WID", "/rest/api/2", []string{"Task", "Bug", "Story"}, authorized, jiraRoutes)
+	s.config.Email = jiraCloudEmail
+	return s
+}
+
+const (
+	// jiraTime is the timestamp format of the Jira API
+	jiraTime = "2006-01-02T15:04:05.000-0700"
+	// jiraCloudEmail is the account NewJiraCloud accepts
+	jiraCloudEmail = "james@example.com"
+	// jiraPageSize caps search pages, so clients have to follow them
+	jiraPageSize = 2
+)
+
+var (
+	jiraLabelClause = regexp.MustCompile(`labels = "([^"]*)"`)
+	jiraTextClause  = regexp.MustCompile(`text ~ "\\"(.*)\\""`)
+)
+
+func jiraRoutes(s *Server, handle handleFunc) {
+	issueOf := func(w http.ResponseWriter, r *http.Request) *tracker.Issue {
+		project, n, _ := strings.Cut(r.PathValue("key"), "-")
+		number, err := strconv.Atoi(n)
+		var issue *tracker.Issue
+		if err == nil && project == s.Repo {
+			issue = s.issue(number)
+		}
+		if issue == nil {
+			writeJSON(w, http.StatusNotFound, map[string]any{"errorMessages": []string{"Issue does not exist or you do not have permission to see it."}})
+		}
+		return issue
+	}
+	project := func(w http.ResponseWriter, r *http.Request, key string) bool {
+		if key != s.Repo {
+			writeJSON(w, http.StatusNotFound, map[string]any{"errorMessages": []string{fmt.Sprintf("No project could be found with key '%s'.", key)}})
+			return false
+		}
+		return true
+	}
+
+	handle("GET /issue/{key}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if issue := issueOf(w, r); issue != nil {
+			writeJSON(w, http.StatusOK, s.jiraIssue(issue))
+		}
+	})
+	// Data Center pages a search by counting with startAt
+	handle("POST /search", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.jiraCloud() {
+			writeJSON(w, http.StatusGone, map[string]any{"errorMessages": []string{"The requested API has been removed. Please migrate to the /rest/api/3/search/jql API."}})
+			return
+		}
+		var req struct {
+			JQL        string `json:"jql"`
+			StartAt    int    `json:"startAt"`
+			MaxResults int    `json:"maxResults"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		matched, ok := s.jiraSearch(w, req.JQL)
+		if !ok {
+			return
+		}
+		start := min(req.StartAt, len(matched))
+		end := min(start+jiraPage(req.MaxResults), len(matched))
+		var issues []map[string]any
+		for _, issue := range matched[start:end] {
+			issues = append(issues, s.jiraIssue(issue))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"startAt": start, "maxResults": jiraPage(req.MaxResults), "total": len(matched), "issues": orEmpty(issues)})
+	})
+	// Cloud pages a search with an opaque token and reports no total
+	handle("POST /search/jql", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.jiraCloud() {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			JQL           string `json:"jql"`
+			NextPageToken string `json:"nextPageToken"`
+			MaxResults    int    `json:"maxResults"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		matched, ok := s.jiraSearch(w, req.JQL)
+		if !ok {
+			return
+		}
+		start := 0
+		if req.NextPageToken != "" {
+			n, err := strconv.Atoi(strings.TrimPrefix(req.NextPageToken, "page-"))
+			if err != nil || n > len(matched) {
+				badRequest(w, "Invalid nextPageToken")
+				return
+			}
+			start = n
+		}
+		end := min(start+jiraPage(req.MaxResults), len(matched))
+		var issues []map[string]any
+		for _, issue := range matched[start:end] {
+			issues = append(issues, s.jiraIssue(issue))
+		}
+		page := map[string]any{"issues": orEmpty(issues), "isLast": end == len(matched)}
+		if end < len(matched) {
+			page["nextPageToken"] = "page-" + strconv.Itoa(end)
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+	handle("POST /issue", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var req struct {
+			Fields map[string]any `json:"fields"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		key, _ := nested(req.Fields, "project", "key").(string)
+		if !project(w, r, key) {
+			return
+		}
+		if nested(req.Fields, "issuetype", "name") == nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"errors": map[string]string{"issuetype": "Specify an issue type"}})
+			return
+		}
+		if summary, _ := req.Fields["summary"].(string); summary == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"errors": map[string]string{"summary": "You must specify a summary of the issue."}})
+			return
+		}
+		issue := s.create(tracker.Issue{})
+		if err := s.applyJira(issue, req.Fields); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"errorMessages": []string{err.Error()}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]string{"id": strconv.Itoa(10000 + issue.Number), "key": fmt.Sprintf("%s-%d", s.Repo, issue.Number)})
+	})
+	handle("PUT /issue/{key}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := issueOf(w, r)
+		if issue == nil {
+			return
+		}
+		var req struct {
+			Fields map[string]any `json:"fields"`
+			Update struct {
+				Labels []struct {
+					Add    string `json:"add"`
+					Remove string `json:"remove"`
+				} `json:"labels"`
+			} `json:"update"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		if err := s.applyJira(issue, req.Fields); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"errorMessages": []string{err.Error()}})
+			return
+		}
+		for _, op := range req.Update.Labels {
+			if op.Add != "" && !hasString(issue.Labels, op.Add) {
+				issue.Labels = append(issue.Labels, op.Add)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handle("PUT /issue/{key}/assignee", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := issueOf(w, r)
+		if issue == nil {
+			return
+		}
+		var req map[string]any
+		if err := readJSON(r, &req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		if err := s.applyJira(issue, map[string]any{"assignee": req}); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"errorMessages": []string{err.Error()}})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handle("GET /issue/{key}/transitions", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if issueOf(w, r) == nil {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"transitions": []map[string]any{
+			{"id": "11", "name": "Start progress", "to": map[string]any{"name": "In Progress", "statusCategory": map[string]string{"key": "indeterminate"}}},
+			{"id": "31", "name": "Done", "to": map[string]any{"name": "Done", "statusCategory": map[string]string{"key": "done"}}},
+		}})
+	})
+	handle("POST /issue/{key}/transitions", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := issueOf(w, r)
+		if issue == nil {
+			return
+		}
+		var req struct {
+			Transition struct {
+				ID string `json:"id"`
+			} `json:"transition"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		switch req.Transition.ID {
+		case "11":
+			issue.State = "open"
+		case "31":
+			issue.State = "closed"
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]any{"errorMessages": []string{"It seems that you have tried to perform a workflow operation that is not valid."}})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handle("GET /issue/{key}/comment", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := issueOf(w, r)
+		if issue == nil {
+			return
+		}
+		var comments []map[string]any
+		for _, c := range s.comments[issue.Number] {
+			comments = append(comments, s.jiraComment(c))
+		}
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		start := min(startAt, len(comments))
+		writeJSON(w, http.StatusOK, map[string]any{"startAt": start, "total": len(comments), "comments": orEmpty(comments[start:])})
+	})
+	handle("POST /issue/{key}/comment", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issue := issueOf(w, r)
+		if issue == nil {
+			return
+		}
+		var req struct {
+			Body string `json:"body"`
+		}
+		if err := readJSON(r, &req); err != nil || req.Body == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"errors": map[string]string{"comment": "Comment body can not be empty!"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.jiraComment(s.comment(issue.Number, req.Body)))
+	})
+	handle("GET /label", func(w http.ResponseWriter, r *http.Request) {
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		start := min(startAt, len(s.labels))
+		writeJSON(w, http.StatusOK, map[string]any{"startAt": start, "total": len(s.labels), "isLast": true, "values": s.labels[start:]})
+	})
+	handle("GET /user/assignable/search", func(w http.ResponseWriter, r *http.Request) {
+		if !project(w, r, r.URL.Query().Get("project")) {
+			return
+		}
+		var users []map[string]string
+		for _, a := range s.assignees {
+			users = append(users, map[string]string{s.jiraUserField(): a, "displayName": strings.ToUpper(a[:1]) + a[1:]})
+		}
+		writeJSON(w, http.StatusOK, users)
+	})
+	handle("GET /project/{key}/versions", func(w http.ResponseWriter, r *http.Request) {
+		if !project(w, r, r.PathValue("key")) {
+			return
+		}
+		versions := []map[string]any{{"id": "1", "name": "v0.9", "released": true}}
+		for _, m := range s.milestones {
+			versions = append(versions, map[string]any{"id": strconv.Itoa(m.ID), "name": m.Title, "released": false})
+		}
+		writeJSON(w, http.StatusOK, versions)
+	})
+	handle("GET /project/{key}", func(w http.ResponseWriter, r *http.Request) {
+		if !project(w, r, r.PathValue("key")) {
+			return
+		}
+		types := []map[string]any{{"name": "Sub-task", "subtask": true}}
+		for _, t := range s.issueTypes {
+			types = append(types, map[string]any{"name": t, "subtask": false})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"key": s.Repo, "issueTypes": types})
+	})
+}
+
+// jiraCloud reports whether the fake serves Jira Cloud rather than Data Center
+func (s *Server) jiraCloud() bool {
+	return s.config.Email != ""
+}
+
+// jiraUserField is the field identifying users: account IDs on Cloud, names on Data Center
+func (s *Server) jiraUserField() string {
+	if s.jiraCloud() {
+		return "accountId"
+	}
+	return "name"
+}
+
+// jiraPage is the size of a search page for the maxResults asked for
+func jiraPage(maxResults int) int {
+	if maxResults <= 0 {
+		return jiraPageSize
+	}
+	return min(maxResults, jiraPageSize)
+}
+
+// jiraSearch returns the issues a JQL query matches, or writes the error. The caller holds s.mu.
+func (s *Server) jiraSearch(w http.ResponseWriter, jql string) ([]*tracker.Issue, bool) {
+	// Only the clauses the tracker sends are understood
+	if !strings.Contains(jql, `project = "`+s.Repo+`"`) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"errorMessages": []string{"The value does not exist for the field 'project'."}})
+		return nil, false
+	}
+	state := "all"
+	switch {
+	case strings.Contains(jql, "statusCategory != Done"):
+		state = "open"
+	case strings.Contains(jql, "statusCategory = Done"):
+		state = "closed"
+	}
+	var labels []string
+	for _, m := range jiraLabelClause.FindAllStringSubmatch(jql, -1) {
+		labels = append(labels, m[1])
+	}
+	text := ""
+	if m := jiraTextClause.FindStringSubmatch(jql); m != nil {
+		text = m[1]
+	}
+	return s.filter(state, labels, text), true
+}
+
+// applyJira sets the fields of a create or edit. The caller holds s.mu.
+func (s *Server) applyJira(issue *tracker.Issue, fields map[string]any) error {
+	for name, v := range fields {
+		switch name {
+		case "project":
+		case "summary":
+			issue.Title, _ = v.(string)
+		case "description":
+			issue.Body, _ = v.(string)
+		case "labels":
+			issue.Labels = nil
+			list, _ := v.([]any)
+			for _, l := range list {
+				if label, ok := l.(string); ok {
+					issue.Labels = append(issue.Labels, label)
+				}
+			}
+		case "assignee":
+			user, _ := nested(v, s.jiraUserField()).(string)
+			if !hasString(s.assignees, user) {
+				return fmt.Errorf("User '%s' cannot be assigned issues.", user)
+			}
+			issue.Assignees = []string{user}
+		case "fixVersions":
+			list, _ := v.([]any)
+			issue.Milestone = ""
+			for _, version := range list {
+				id, _ := strconv.Atoi(fmt.Sprint(nested(version, "id")))
+				if issue.Milestone = s.milestoneTitle(id); issue.Milestone == "" {
+					return fmt.Errorf("Version id '%d' is not valid", id)
+				}
+			}
+		case "issuetype":
+			t, _ := nested(v, "name").(string)
+			if !hasString(s.issueTypes, t) {
+				return fmt.Errorf("Specify a valid issue type")
+			}
+			issue.Type = t
+		default:
+			return fmt.Errorf("Field '%s' cannot be set. It is not on the appropriate screen, or unknown.", name)
+		}
+	}
+	return nil
+}
+
+func (s *Server) jiraIssue(issue *tracker.Issue) map[string]any {
+	category := "new"
+	if issue.State == "closed" {
+		category = "done"
+	}
+	labels := issue.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	fields := map[string]any{
+		"summary":     issue.Title,
+		"description": issue.Body,
+		"labels":      labels,
+		"reporter":    map[string]string{s.jiraUserField(): issue.Author},
+		"issuetype":   map[string]string{"name": issue.Type},
+		"status":      map[string]any{"statusCategory": map[string]string{"key": category}},
+		"fixVersions": []map[string]string{},
+		"created":     issue.CreatedAt.Format(jiraTime),
+		"updated":     issue.UpdatedAt.Format(jiraTime),
+	}
+	if len(issue.Assignees) > 0 {
+		fields["assignee"] = map[string]string{s.jiraUserField(): issue.Assignees[0]}
+	}
+	for _, m := range s.milestones {
+		if m.Title == issue.Milestone {
+			fields["fixVersions"] = []map[string]string{{"id": strconv.Itoa(m.ID), "name": m.Title}}
+		}
+	}
+	return map[string]any{"key": fmt.Sprintf("%s-%d", s.Repo, issue.Number), "fields": fields}
+}
+
+func (s *Server) jiraComment(c tracker.Comment) map[string]any {
+	return map[string]any{
+		"id":      c.ID,
+		"body":    c.Body,
+		"author":  map[string]string{s.jiraUserField(): c.Author},
+		"created": c.CreatedAt.Format(jiraTime),
+	}
+}
+
+// nested walks decoded JSON objects along keys
+func nested(v any, keys ...string) any {
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
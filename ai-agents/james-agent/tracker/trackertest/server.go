@@ -0,0 +1,242 @@
+// Package trackertest provides in-memory fakes of the GitHub, GitLab, Gitea,
+// Jira Data Center and Jira Cloud APIs, served with httptest, and a contract
+// that every tracker.IssueTracker must pass against them.
+//
+// Each fake serves one repository seeded with labels bug, enhancement and
+// P1, assignees alice and bob, milestone v1.0 and one open issue.
+package trackertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"james-agent/main/tracker"
+)
+
+// Token is the credential the fakes accept
+const Token = "test-token"
+
+// Server is a fake tracker API.
+type Server struct {
+	*httptest.Server
+	// Repo is the repository the fake serves, in the tracker's notation
+	Repo   string
+	config tracker.Config
+
+	mu         sync.Mutex
+	issues     []*tracker.Issue
+	comments   map[int][]tracker.Comment
+	labels     []string
+	assignees  []string
+	milestones []tracker.Milestone
+	issueTypes []string
+	// Requests records "METHOD path" of every request served
+	Requests []string
+}
+
+// handleFunc registers a handler for a "METHOD /path" pattern below the API prefix
+type handleFunc func(pattern string, h http.HandlerFunc)
+
+// newServer seeds a fake and serves the routes under the API prefix. authorized checks a request's credentials.
+func newServer(kind, repo, prefix string, issueTypes []string, authorized func(*http.Request) bool, routes func(*Server, handleFunc)) *Server {
+	s := &Server{
+		Repo:       repo,
+		comments:   map[int][]tracker.Comment{},
+		labels:     []string{"bug", "enhancement", "P1"},
+		assignees:  []string{"alice", "bob"},
+		milestones: []tracker.Milestone{{ID: 7, Title: "v1.0"}},
+		issueTypes: issueTypes,
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	s.issues = []*tracker.Issue{{
+		Number: 1, Title: "Existing issue", Body: "Seeded by the fake", State: "open",
+		Author: "alice", CreatedAt: now, UpdatedAt: now,
+	}}
+
+	mux := http.NewServeMux()
+	routes(s, func(pattern string, h http.HandlerFunc) {
+		method, path, _ := strings.Cut(pattern, " ")
+		mux.HandleFunc(method+" "+prefix+path, h)
+	})
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.Requests = append(s.Requests, r.Method+" "+r.URL.Path)
+		s.mu.Unlock()
+		if !authorized(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	s.config = tracker.Config{Kind: kind, URL: s.URL + prefix, Token: Token}
+	if kind == tracker.KindJira {
+		// Jira is configured with the site, the API path is fixed
+		s.config.URL = s.URL
+	}
+	s.setURLs()
+	return s
+}
+
+// Config returns the tracker configuration pointing at the fake.
+func (s *Server) Config() tracker.Config {
+	return s.config
+}
+
+// setURLs gives the seeded issues their web URLs, which depend on the server address
+func (s *Server) setURLs() {
+	for _, issue := range s.issues {
+		issue.URL = s.issueURL(issue.Number)
+	}
+}
+
+func (s *Server) issueURL(number int) string {
+	if s.config.Kind == tracker.KindJira {
+		return fmt.Sprintf("%s/browse/%s-%d", s.URL, s.Repo, number)
+	}
+	return fmt.Sprintf("%s/%s/issues/%d", s.URL, s.Repo, number)
+}
+
+// issue returns an issue by number, or nil. The caller holds s.mu.
+func (s *Server) issue(number int) *tracker.Issue {
+	for _, issue := range s.issues {
+		if issue.Number == number {
+			return issue
+		}
+	}
+	return nil
+}
+
+// create adds an issue. The caller holds s.mu.
+func (s *Server) create(issue tracker.Issue) *tracker.Issue {
+	issue.Number = len(s.issues) + 1
+	issue.State = "open"
+	issue.URL = s.issueURL(issue.Number)
+	issue.Author = "james"
+	issue.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	issue.UpdatedAt = issue.CreatedAt
+	s.issues = append(s.issues, &issue)
+	return &issue
+}
+
+// comment adds a comment. The caller holds s.mu.
+func (s *Server) comment(number int, body string) tracker.Comment {
+	id := 1
+	for _, list := range s.comments {
+		id += len(list)
+	}
+	c := tracker.Comment{ID: strconv.Itoa(id), Body: body, Author: "james", CreatedAt: time.Now().UTC().Truncate(time.Second)}
+	c.URL = fmt.Sprintf("%s#comment-%d", s.issueURL(number), id)
+	s.comments[number] = append(s.comments[number], c)
+	return c
+}
+
+// filter returns the issues in a state (open, closed or all) with all labels,
+// newest first. The caller holds s.mu.
+func (s *Server) filter(state string, labels []string, contains string) []*tracker.Issue {
+	var out []*tracker.Issue
+	for i := len(s.issues) - 1; i >= 0; i-- {
+		issue := s.issues[i]
+		if state != "all" && issue.State != state {
+			continue
+		}
+		if contains != "" && !strings.Contains(issue.Title+"\n"+issue.Body, contains) {
+			continue
+		}
+		ok := true
+		for _, l := range labels {
+			ok = ok && hasString(issue.Labels, l)
+		}
+		if ok {
+			out = append(out, issue)
+		}
+	}
+	return out
+}
+
+// page slices items for the page and per-page query parameters and sets the Link header
+func page[T any](w http.ResponseWriter, r *http.Request, items []T, perPageParam string) []T {
+	perPage, _ := strconv.Atoi(r.URL.Query().Get(perPageParam))
+	if perPage <= 0 {
+		perPage = 30
+	}
+	n, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if n <= 0 {
+		n = 1
+	}
+	start := min((n-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	if end < len(items) {
+		next := *r.URL
+		next.Scheme, next.Host = "http", r.Host
+		q := next.Query()
+		q.Set("page", strconv.Itoa(n+1))
+		next.RawQuery = q.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+	}
+	return items[start:end]
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func readJSON(r *http.Request, v any) error {
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+func notFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+}
+
+func badRequest(w http.ResponseWriter, msg string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"message": msg})
+}
+
+func hasString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// pathNumber parses a numeric path value
+func pathNumber(r *http.Request, name string) (int, bool) {
+	n, err := strconv.Atoi(r.PathValue(name))
+	return n, err == nil
+}
+
+// unescaped returns the path value of a wildcard, which may contain escaped slashes
+func unescaped(r *http.Request, name string) string {
+	v := r.PathValue(name)
+	if u, err := url.PathUnescape(v); err == nil {
+		return u
+	}
+	return v
+}
+
+// numericID renders a comment ID as the number most APIs use
+func numericID(id string) int {
+	n, _ := strconv.Atoi(id)
+	return n
+}
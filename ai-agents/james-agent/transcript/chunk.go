@@ -0,0 +1,75 @@
+package transcript
+
+import (
+	"strings"
+	"time"
+)
+
+// ChunkOptions limits the size of a chunk. Zero values use the defaults.
+type ChunkOptions struct {
+	// MaxDuration is the longest stretch of meeting in a chunk, for timed transcripts. Default 15 minutes.
+	MaxDuration time.Duration
+	// MaxChars is the most rendered text in a chunk. Default 12000.
+	MaxChars int
+}
+
+// Chunk is a contiguous part of a transcript
+type Chunk struct {
+	Index      int
+	Start      time.Duration
+	End        time.Duration
+	Timed      bool
+	Utterances []Utterance
+}
+
+// Chunks splits the transcript at utterance boundaries, starting a new chunk
+// when the next utterance would exceed the duration or size limit. A single
+// utterance longer than the limit gets a chunk of its own.
+func (t *Transcript) Chunks(opts ChunkOptions) []Chunk {
+	if opts.MaxDuration <= 0 {
+		opts.MaxDuration = 15 * time.Minute
+	}
+	if opts.MaxChars <= 0 {
+		opts.MaxChars = 12000
+	}
+
+	var chunks []Chunk
+	var cur *Chunk
+	size := 0
+	for _, u := range t.Utterances {
+		n := len(u.render(t.Timed)) + 1
+		if cur != nil && len(cur.Utterances) > 0 {
+			tooLong := t.Timed && u.End-cur.Start > opts.MaxDuration
+			if tooLong || size+n > opts.MaxChars {
+				cur = nil
+			}
+		}
+		if cur == nil {
+			chunks = append(chunks, Chunk{Index: len(chunks), Start: u.Start, Timed: t.Timed})
+			cur = &chunks[len(chunks)-1]
+			size = 0
+		}
+		cur.Utterances = append(cur.Utterances, u)
+		cur.End = u.End
+		size += n
+	}
+	return chunks
+}
+
+// Text renders the chunk like Transcript.Text
+func (c Chunk) Text() string {
+	var b strings.Builder
+	for _, u := range c.Utterances {
+		b.WriteString(u.render(c.Timed))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Span describes the part of the meeting a chunk covers, e.g. "00:15:00-00:30:00"
+func (c Chunk) Span() string {
+	if !c.Timed {
+		return ""
+	}
+	return FormatTimestamp(c.Start) + "-" + FormatTimestamp(c.End)
+}
@@ -0,0 +1,213 @@
+package transcript
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// cueTimingPattern matches "00:00:01.000 --> 00:00:04.000" with optional cue settings
+	cueTimingPattern = regexp.MustCompile(`^(\d+(?::\d+){1,2}(?:[.,]\d+)?)\s+-->\s+(\d+(?::\d+){1,2}(?:[.,]\d+)?)`)
+	// voicePattern matches a WebVTT voice tag such as <v Alice> or <v.loud Alice>
+	voicePattern = regexp.MustCompile(`<v(?:\.[^ >]*)?\s+([^>]+)>`)
+	tagPattern   = regexp.MustCompile(`<[^>]*>`)
+	// speakerPattern matches a "Name: text" line, with a name of at most five words
+	speakerPattern = regexp.MustCompile(`^(\p{L}[\p{L}\p{M}'.\-]*(?:\s+[\p{L}\p{M}'.\-]+){0,4})\s*:\s+(.+)$`)
+	// timestampLinePattern matches a line holding only a timestamp, as in Google Meet transcripts
+	timestampLinePattern = regexp.MustCompile(`^\(?(\d{1,2}:\d{2}(?::\d{2})?)\)?$`)
+	// otterHeaderPattern matches "Alice Smith  0:03" speaker headers
+	otterHeaderPattern = regexp.MustCompile(`^(\S.*?)\s{2,}(\d{1,2}:\d{2}(?::\d{2})?)$`)
+)
+
+// parseCues parses WebVTT and SRT, which are both blocks of timed cues
+func parseCues(text string, format Format) (*Transcript, error) {
+	t := &Transcript{Format: format, Timed: true}
+
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		// Skips the WEBVTT header and NOTE, STYLE and REGION blocks
+		if timing < 0 {
+			continue
+		}
+
+		m := cueTimingPattern.FindStringSubmatch(strings.TrimSpace(lines[timing]))
+		if m == nil {
+			return nil, fmt.Errorf("invalid cue timing %q", lines[timing])
+		}
+		start, err := parseTimestamp(m[1])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseTimestamp(m[2])
+		if err != nil {
+			return nil, err
+		}
+
+		speaker, body := "", strings.Join(lines[timing+1:], " ")
+		if v := voicePattern.FindStringSubmatch(body); v != nil {
+			speaker = strings.TrimSpace(v[1])
+		}
+		body = html.UnescapeString(tagPattern.ReplaceAllString(body, ""))
+		body = strings.Join(strings.Fields(body), " ")
+		if speaker == "" {
+			speaker, body = splitSpeaker(body)
+		}
+		if body == "" {
+			continue
+		}
+		t.add(Utterance{Speaker: speaker, Start: start, End: end, Text: body}, true)
+	}
+	return t, nil
+}
+
+// parseMeet parses Google Meet transcripts: timestamp lines followed by "Name: text" lines
+func parseMeet(text string) *Transcript {
+	t := &Transcript{Format: FormatMeet, Timed: true}
+	var current time.Duration
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if m := timestampLinePattern.FindStringSubmatch(line); m != nil {
+			if d, err := parseTimestamp(m[1]); err == nil {
+				current = d
+				continue
+			}
+		}
+		if speaker, body := splitSpeaker(line); speaker != "" {
+			t.add(Utterance{Speaker: speaker, Start: current, Text: body}, false)
+		} else if n := len(t.Utterances); n > 0 {
+			t.Utterances[n-1].Text += " " + line
+		}
+		// Lines before the first timestamp and speaker are the document title
+	}
+	t.fillEnds()
+	return t
+}
+
+// parseOtter parses Otter.ai exports: "Name  0:03" headers followed by paragraphs
+func parseOtter(text string) *Transcript {
+	t := &Transcript{Format: FormatOtter, Timed: true}
+	var cur *Utterance
+	flush := func() {
+		if cur != nil && cur.Text != "" {
+			t.add(*cur, false)
+		}
+		cur = nil
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if m := otterHeaderPattern.FindStringSubmatch(line); m != nil {
+			if d, err := parseTimestamp(m[2]); err == nil {
+				flush()
+				cur = &Utterance{Speaker: m[1], Start: d}
+				continue
+			}
+		}
+		if cur == nil {
+			cur = &Utterance{}
+		}
+		cur.Text = strings.TrimSpace(cur.Text + " " + line)
+	}
+	flush()
+	t.fillEnds()
+	return t
+}
+
+// parsePlain treats "Name: text" lines as utterances and other paragraphs as unattributed text
+func parsePlain(text string) *Transcript {
+	t := &Transcript{Format: FormatPlain}
+	for _, para := range strings.Split(text, "\n\n") {
+		var cur *Utterance
+		for _, line := range strings.Split(para, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			if speaker, body := splitSpeaker(line); speaker != "" {
+				if cur != nil {
+					t.add(*cur, false)
+				}
+				cur = &Utterance{Speaker: speaker, Text: body}
+			} else if cur != nil {
+				cur.Text += " " + line
+			} else {
+				cur = &Utterance{Text: line}
+			}
+		}
+		if cur != nil {
+			t.add(*cur, false)
+		}
+	}
+	return t
+}
+
+// add appends an utterance. With merge, a cue continuing the previous speaker's cue is joined to it.
+func (t *Transcript) add(u Utterance, merge bool) {
+	if n := len(t.Utterances); merge && n > 0 {
+		prev := &t.Utterances[n-1]
+		if u.Speaker != "" && u.Speaker == prev.Speaker {
+			prev.Text += " " + u.Text
+			prev.End = u.End
+			return
+		}
+	}
+	t.Utterances = append(t.Utterances, u)
+}
+
+// fillEnds ends every utterance where the next one starts
+func (t *Transcript) fillEnds() {
+	for i := range t.Utterances {
+		if i+1 < len(t.Utterances) {
+			t.Utterances[i].End = t.Utterances[i+1].Start
+		} else {
+			t.Utterances[i].End = t.Utterances[i].Start
+		}
+	}
+}
+
+// splitSpeaker splits "Name: text" into the speaker and the text
+func splitSpeaker(line string) (string, string) {
+	if m := speakerPattern.FindStringSubmatch(line); m != nil {
+		return strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+	}
+	return "", line
+}
+
+// parseTimestamp parses [[H:]M:]S[.fff] with a dot or comma before the fraction
+func parseTimestamp(s string) (time.Duration, error) {
+	parts := strings.Split(strings.ReplaceAll(s, ",", "."), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	secs, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	d := time.Duration(secs * float64(time.Second))
+	unit := time.Minute
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		d += time.Duration(n) * unit
+		unit *= 60
+	}
+	return d, nil
+}
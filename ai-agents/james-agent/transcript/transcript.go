@@ -0,0 +1,179 @@
+// Package transcript parses meeting transcripts into speaker attributed,
+// timestamped utterances.
+//
+// Supported formats:
+//
+//   - WebVTT, including the Zoom ("Name: text" cues) and Microsoft Teams
+//     ("<v Name>text</v>" voice tags) exports
+//   - SRT subtitles
+//   - Google Meet transcripts: timestamp lines followed by "Name: text" lines
+//   - Otter.ai text exports: "Name  0:03" headers followed by paragraphs
+//   - plain text, with optional "Name: text" lines
+package transcript
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format is a transcript file format
+type Format string
+
+const (
+	FormatVTT   Format = "vtt"
+	FormatSRT   Format = "srt"
+	FormatMeet  Format = "google-meet"
+	FormatOtter Format = "otter"
+	FormatPlain Format = "plain"
+)
+
+// Utterance is what one speaker said, from Start to End into the meeting.
+// Speaker is empty when the transcript does not say who spoke.
+type Utterance struct {
+	Speaker string        `json:"speaker,omitempty"`
+	Start   time.Duration `json:"start"`
+	End     time.Duration `json:"end"`
+	Text    string        `json:"text"`
+}
+
+// Transcript is a parsed transcript
+type Transcript struct {
+	Format Format `json:"format"`
+	// Timed is false when the source has no timestamps
+	Timed      bool        `json:"timed"`
+	Utterances []Utterance `json:"utterances"`
+}
+
+// ParseFile reads and parses a transcript, detecting its format.
+func ParseFile(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(filepath.Base(path), data)
+}
+
+// Parse parses a transcript. name is used to detect the format from its extension.
+func Parse(name string, data []byte) (*Transcript, error) {
+	text := normalize(string(data))
+	format := Detect(name, text)
+
+	var t *Transcript
+	var err error
+	switch format {
+	case FormatVTT, FormatSRT:
+		t, err = parseCues(text, format)
+	case FormatMeet:
+		t = parseMeet(text)
+	case FormatOtter:
+		t = parseOtter(text)
+	default:
+		t = parsePlain(text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(t.Utterances) == 0 {
+		return nil, fmt.Errorf("transcript %s (%s) contains no text", name, format)
+	}
+	return t, nil
+}
+
+// Detect guesses the format of a transcript from its name and normalized content.
+func Detect(name, text string) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".vtt":
+		return FormatVTT
+	case ".srt":
+		return FormatSRT
+	}
+
+	if strings.HasPrefix(text, "WEBVTT") {
+		return FormatVTT
+	}
+	lines := nonEmptyLines(text, 20)
+	for _, line := range lines {
+		if cueTimingPattern.MatchString(line) {
+			return FormatSRT
+		}
+	}
+	if len(lines) > 0 && otterHeaderPattern.MatchString(lines[0]) {
+		return FormatOtter
+	}
+	for i := 0; i+1 < len(lines); i++ {
+		if timestampLinePattern.MatchString(lines[i]) && speakerPattern.MatchString(lines[i+1]) {
+			return FormatMeet
+		}
+	}
+	return FormatPlain
+}
+
+// Speakers returns the distinct speakers in order of appearance.
+func (t *Transcript) Speakers() []string {
+	var speakers []string
+	seen := map[string]bool{}
+	for _, u := range t.Utterances {
+		if u.Speaker != "" && !seen[u.Speaker] {
+			seen[u.Speaker] = true
+			speakers = append(speakers, u.Speaker)
+		}
+	}
+	return speakers
+}
+
+// String renders an utterance as "[00:01:02] Alice: text".
+func (u Utterance) String() string {
+	return u.render(true)
+}
+
+func (u Utterance) render(timed bool) string {
+	var b strings.Builder
+	if timed {
+		fmt.Fprintf(&b, "[%s] ", FormatTimestamp(u.Start))
+	}
+	if u.Speaker != "" {
+		b.WriteString(u.Speaker + ": ")
+	}
+	b.WriteString(u.Text)
+	return b.String()
+}
+
+// Text renders the transcript one utterance per line, with timestamps when known.
+func (t *Transcript) Text() string {
+	var b strings.Builder
+	for _, u := range t.Utterances {
+		b.WriteString(u.render(t.Timed))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// FormatTimestamp formats an offset as HH:MM:SS.
+func FormatTimestamp(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+// normalize removes a byte order mark and carriage returns
+func normalize(s string) string {
+	s = strings.TrimPrefix(s, "\ufeff")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
+
+// nonEmptyLines returns up to n trimmed, non-empty lines
+func nonEmptyLines(s string, n int) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+			if len(lines) == n {
+				break
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"agentauth"
+
+	"github.com/labstack/echo/v4"
+)
+
+// requireAdmin only lets configured admins through. Without authentication
+// it falls back to requests from the local machine, using the peer address
+// rather than forwarded headers, which can be spoofed.
+func requireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if cfg.Auth.Enabled {
+			if !isAdmin(currentIdentity(c)) {
+				return c.String(http.StatusForbidden, "Forbidden")
+			}
+			return next(c)
+		}
+
+		host, _, err := net.SplitHostPort(c.Request().RemoteAddr)
+		if err != nil {
+			return c.String(http.StatusForbidden, "Forbidden")
+		}
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return c.String(http.StatusForbidden, "Admin endpoints are only available from localhost")
+		}
+		return next(c)
+	}
+}
+
+// isAdmin reports whether the identity is listed in auth.admins, by user or email
+func isAdmin(id agentauth.Identity) bool {
+	for _, admin := range cfg.Auth.Admins {
+		if admin == id.User || (id.Email != "" && admin == id.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+// currentIdentity returns the identity set by the auth middleware
+func currentIdentity(c echo.Context) agentauth.Identity {
+	id, _ := agentauth.FromContext(c.Request().Context())
+	return id
+}
+
+// canAccessJob reports whether the caller may see the job. Jobs are scoped
+// to their owner, admins see everything and without auth everyone does.
+func canAccessJob(c echo.Context, job *Job) bool {
+	if !cfg.Auth.Enabled {
+		return true
+	}
+	id := currentIdentity(c)
+	return job.Owner == id.User || isAdmin(id)
+}
+
+// listOutputs lists every job with its size and retention state
+func listOutputs(c echo.Context) error {
+	jobs, err := listJobs()
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	var total int64
+	for _, job := range jobs {
+		total += job.SizeBytes
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"jobs":       jobs,
+		"totalBytes": total,
+	})
+}
+
+// purgeOutput removes a single job, pinned or not. Running jobs are refused.
+func purgeOutput(c echo.Context) error {
+	id := c.Param("id")
+	if !validJobID(id) {
+		return c.String(http.StatusBadRequest, "Invalid job ID")
+	}
+
+	job, err := loadJob(id)
+	if os.IsNotExist(err) {
+		return c.String(http.StatusNotFound, "Job not found")
+	}
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if job.Status == JobRunning {
+		return c.String(http.StatusConflict, "Job is still running")
+	}
+
+	if err := removeJob(id); err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// collectOutputs runs the janitor immediately
+func collectOutputs(c echo.Context) error {
+	return c.JSON(http.StatusOK, runJanitor(time.Now()))
+}
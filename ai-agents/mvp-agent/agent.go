@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	// For GrepFile and SedTool
 	// For SedTool (strings.Join)
@@ -20,67 +21,65 @@ import (
 	"google.golang.org/genai"
 )
 
-// The agent instruction guides the LLM on its goal and tool usage strategy.
-const agentInstruction = `
-You are a **MVP creator agent**.
-You main job is to take requirements from the user and based on that create a working Minimum viable product.
-
-A folder with a starter template with required files will be given to you.
-Your main job is to modify the files in the starter template and modify the files to implement the features according to the requirements.
-
-<starter_template>
-It is a go lang web app with ui in index.html + tailwind + htmx
-
-<file_structure>
-	- backend/main.go
-	- backend/webapp.go --> add your APIs here
-	- backend/ui/index.html  --> tailwind + htmx
-	no database is used, use in memory structures to store the data
-</file_structure>
-
-</starter_template>
-
-Steps to follow for creating a working MVP from the users requirements
-1. **Understand:** 
- - List all files in the working directory
- - First think and come up with a list of changes required to implement the users requirement for creating a working MVP
- - for the changes think what REST, APIs and UI components are needed.
-
-2. **Modify:** Use SedTool for line-level changes or WriteFile for complete rewrites
- - Determine which files need to be modified in the Go backend (Echo framework) and HTML/HTMX frontend.
- - Use RenameFile or MoveFile if you need to reorganize files
- - Make sure your go code and ui code compiles
-
-3. do a go build to verify your code builds and works
-
-<coding_guidelines>
-- Go backend uses echo framework
-- We dont have a delete file tool, use rename file to soft delete a file
-- NEVER create, write or edit go.sum, its NOT needed the build process will generate it
-- You should never need to make changes to main.go, changes should be in webapp.go
-- All go code that you generate must be in webapp.go
-- Feel free to use go templates for returning direct html via APIs
-- use HTMX to directly rendered html from the backend and display it as required
-- use your judgement where you need a REST API and where you need direct HTML
-- Make sure your code compiles
-- Keep UI simple and minimal
-- use simple colors in UI
-</coding_guidelines>
-`
-
-func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) error {
-
-	// Set the global tool log channel
-	toolLogChannel = logChan
+// mvpPromptName is the prompt in the prompt store used as the agent instruction
+const mvpPromptName = "mvp_agent"
+
+// MVPPromptVars are the variables available to the mvp_agent prompt templates
+type MVPPromptVars struct {
+	// TemplateLayout lists the files of the starter template, relative to the working directory
+	TemplateLayout []string
+	// Rules are extra coding guidelines from the config
+	Rules []string
+}
+
+// mvpPromptVars builds the prompt variables from the starter template and config
+func mvpPromptVars() (MVPPromptVars, error) {
+	vars := MVPPromptVars{Rules: cfg.Prompts.Rules}
+	err := filepath.WalkDir(cfg.Data.StarterTemplateDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() == "go.sum" {
+			return nil
+		}
+		rel, err := filepath.Rel(cfg.Data.StarterTemplateDir, path)
+		if err != nil {
+			return err
+		}
+		vars.TemplateLayout = append(vars.TemplateLayout, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return vars, fmt.Errorf("failed to list starter template: %v", err)
+	}
+	return vars, nil
+}
+
+// toolDescriptions describe the tools to the model, in the agent and over MCP
+var toolDescriptions = map[string]string{
+	"ReadFile":           "Reads the entire content of a specified file.",
+	"WriteFile":          "Overwrites a file with new content. Use this primarily for NEW files. Prefer SedTool for modifications.",
+	"GrepFile":           "Searches for lines matching a regular expression pattern within a file. Useful for finding the exact location of code to modify.",
+	"SedTool":            "Performs surgical, line-based modification (replacement or insertion) in a file. Use this for code modifications to save tokens.",
+	"GoBuild":            "Executes 'go build ./...' in the specified working directory and returns build logs and status. Use this to verify that your Go code compiles successfully.",
+	"InsertInFileAtLine": "Inserts content at a specific line number in a file (1-based indexing). The content is inserted before the specified line number. Useful for adding new code at precise locations.",
+	"AppendToFile":       "Appends content to the end of a file. Automatically handles newlines. Creates the file if it doesn't exist. Useful for adding new functions or code blocks to the end of files.",
+	"RenameFile":         "Renames or moves a file. Provide the current file path (oldPath) and the desired new path (newPath). Works for both simple renames and moving to different directories.",
+	"MoveFile":           "Moves a file from one location to another. Creates parent directories if needed. Use this to relocate files to different directories.",
+	"ListFiles":          "Lists all files and directories in a specified directory. Supports recursive listing to explore entire directory trees. Directories are marked with a trailing slash.",
+}
+
+func RunMVPAgent(ctx context.Context, outputDir, userID, instruction string, logChan chan<- string) error {
 
 	// Check required environment variables
-	if os.Getenv("GOOGLE_API_KEY") == "" {
-		return fmt.Errorf("GOOGLE_API_KEY environment variable is required")
+	apiKey := os.Getenv(cfg.Model.APIKeyEnv)
+	if apiKey == "" {
+		return fmt.Errorf("%s environment variable is required", cfg.Model.APIKeyEnv)
 	}
 
 	// Create model
-	model, err := gemini.NewModel(ctx, "gemini-2.5-pro", &genai.ClientConfig{
-		APIKey: os.Getenv("GOOGLE_API_KEY"),
+	model, err := gemini.NewModel(ctx, cfg.Model.Name, &genai.ClientConfig{
+		APIKey: apiKey,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create model: %v", err)
@@ -90,7 +89,7 @@ func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) e
 
 	readTool, err := functiontool.New(functiontool.Config{
 		Name:        "ReadFile",
-		Description: "Reads the entire content of a specified file.",
+		Description: toolDescriptions["ReadFile"],
 	}, ReadFile)
 	if err != nil {
 		return fmt.Errorf("failed to create ReadFile tool: %v", err)
@@ -98,7 +97,7 @@ func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) e
 
 	writeTool, err := functiontool.New(functiontool.Config{
 		Name:        "WriteFile",
-		Description: "Overwrites a file with new content. Use this primarily for NEW files. Prefer SedTool for modifications.",
+		Description: toolDescriptions["WriteFile"],
 	}, WriteFile)
 	if err != nil {
 		return fmt.Errorf("failed to create WriteFile tool: %v", err)
@@ -106,7 +105,7 @@ func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) e
 
 	grepTool, err := functiontool.New(functiontool.Config{
 		Name:        "GrepFile",
-		Description: "Searches for lines matching a regular expression pattern within a file. Useful for finding the exact location of code to modify.",
+		Description: toolDescriptions["GrepFile"],
 	}, GrepFile)
 	if err != nil {
 		return fmt.Errorf("failed to create GrepFile tool: %v", err)
@@ -114,7 +113,7 @@ func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) e
 
 	sedTool, err := functiontool.New(functiontool.Config{
 		Name:        "SedTool",
-		Description: "Performs surgical, line-based modification (replacement or insertion) in a file. Use this for code modifications to save tokens.",
+		Description: toolDescriptions["SedTool"],
 	}, SedTool)
 	if err != nil {
 		return fmt.Errorf("failed to create SedTool tool: %v", err)
@@ -122,7 +121,7 @@ func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) e
 
 	goBuildTool, err := functiontool.New(functiontool.Config{
 		Name:        "GoBuild",
-		Description: "Executes 'go build ./...' in the specified working directory and returns build logs and status. Use this to verify that your Go code compiles successfully.",
+		Description: toolDescriptions["GoBuild"],
 	}, GoBuild)
 	if err != nil {
 		return fmt.Errorf("failed to create GoBuild tool: %v", err)
@@ -130,7 +129,7 @@ func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) e
 
 	insertInFileAtLineTool, err := functiontool.New(functiontool.Config{
 		Name:        "InsertInFileAtLine",
-		Description: "Inserts content at a specific line number in a file (1-based indexing). The content is inserted before the specified line number. Useful for adding new code at precise locations.",
+		Description: toolDescriptions["InsertInFileAtLine"],
 	}, InsertInFileAtLine)
 	if err != nil {
 		return fmt.Errorf("failed to create InsertInFileAtLine tool: %v", err)
@@ -138,7 +137,7 @@ func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) e
 
 	appendToFileTool, err := functiontool.New(functiontool.Config{
 		Name:        "AppendToFile",
-		Description: "Appends content to the end of a file. Automatically handles newlines. Creates the file if it doesn't exist. Useful for adding new functions or code blocks to the end of files.",
+		Description: toolDescriptions["AppendToFile"],
 	}, AppendToFile)
 	if err != nil {
 		return fmt.Errorf("failed to create AppendToFile tool: %v", err)
@@ -146,7 +145,7 @@ func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) e
 
 	renameFileTool, err := functiontool.New(functiontool.Config{
 		Name:        "RenameFile",
-		Description: "Renames or moves a file. Provide the current file path (oldPath) and the desired new path (newPath). Works for both simple renames and moving to different directories.",
+		Description: toolDescriptions["RenameFile"],
 	}, RenameFile)
 	if err != nil {
 		return fmt.Errorf("failed to create RenameFile tool: %v", err)
@@ -154,7 +153,7 @@ func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) e
 
 	moveFileTool, err := functiontool.New(functiontool.Config{
 		Name:        "MoveFile",
-		Description: "Moves a file from one location to another. Creates parent directories if needed. Use this to relocate files to different directories.",
+		Description: toolDescriptions["MoveFile"],
 	}, MoveFile)
 	if err != nil {
 		return fmt.Errorf("failed to create MoveFile tool: %v", err)
@@ -162,7 +161,7 @@ func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) e
 
 	listFilesTool, err := functiontool.New(functiontool.Config{
 		Name:        "ListFiles",
-		Description: "Lists all files and directories in a specified directory. Supports recursive listing to explore entire directory trees. Directories are marked with a trailing slash.",
+		Description: toolDescriptions["ListFiles"],
 	}, ListFiles)
 	if err != nil {
 		return fmt.Errorf("failed to create ListFiles tool: %v", err)
@@ -174,7 +173,7 @@ func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) e
 		Name:        "mvp_agent",
 		Model:       model,
 		Description: "Agent that modifies a Go/HTMX starter template based on a user's MVP request.",
-		Instruction: agentInstruction,
+		Instruction: instruction,
 		Tools:       []tool.Tool{readTool, writeTool, grepTool, sedTool, goBuildTool, insertInFileAtLineTool, appendToFileTool, renameFileTool, moveFileTool, listFilesTool},
 	})
 	if err != nil {
@@ -194,8 +193,7 @@ func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) e
 		return fmt.Errorf("failed to create runner: %v", err)
 	}
 
-	// Create session
-	userID := "web_user"
+	// Create session for the requesting user
 	appName := "mvp_agent"
 	sessResp, err := sessionService.Create(ctx, &session.CreateRequest{
 		AppName: appName,
@@ -204,6 +202,8 @@ func RunMVPAgent(ctx context.Context, outputDir string, logChan chan<- string) e
 	if err != nil {
 		return fmt.Errorf("error creating session: %v", err)
 	}
+	// The tools log to this job's channel
+	defer registerToolLog(sessResp.Session.ID(), logChan)()
 
 	// Create user message
 	userMessage := fmt.Sprintf(
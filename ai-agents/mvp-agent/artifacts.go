@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Artifact is a downloadable file registered for a job at build time.
+// Only artifacts in a job's index can be downloaded.
+type Artifact struct {
+	// Name is the download name and the lookup key, e.g. mvp-linux-amd64
+	Name string `json:"name"`
+	// Path is relative to the job's output directory, slash separated
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	SHA256  string    `json:"sha256"`
+	GOOS    string    `json:"goos,omitempty"`
+	GOARCH  string    `json:"goarch,omitempty"`
+	BuiltAt time.Time `json:"builtAt"`
+}
+
+// newArtifact hashes a freshly built file under the job directory
+func newArtifact(jobID, name, relPath string) (Artifact, error) {
+	f, err := os.Open(filepath.Join(jobDir(jobID), filepath.FromSlash(relPath)))
+	if err != nil {
+		return Artifact{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to hash %s: %v", relPath, err)
+	}
+
+	return Artifact{
+		Name:    name,
+		Path:    relPath,
+		Size:    size,
+		SHA256:  hex.EncodeToString(h.Sum(nil)),
+		BuiltAt: time.Now(),
+	}, nil
+}
+
+// findArtifact looks up an artifact by its exact name
+func (j *Job) findArtifact(name string) (Artifact, bool) {
+	for _, a := range j.Artifacts {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Artifact{}, false
+}
+
+// downloadURL returns the URL an artifact is served from
+func downloadURL(jobID, name string) string {
+	return fmt.Sprintf("/download/%s/%s", url.PathEscape(jobID), url.PathEscape(name))
+}
+
+// downloadMVP serves an artifact registered in the job's index. Range
+// requests and conditional requests are handled by http.ServeContent.
+func downloadMVP(c echo.Context) error {
+	jobID := c.Param("jobID")
+	name := c.Param("name")
+
+	// Only the owner of a job may download its builds
+	if !validJobID(jobID) {
+		return c.String(http.StatusNotFound, "File not found")
+	}
+	job, err := loadJob(jobID)
+	if err != nil || !canAccessJob(c, job) {
+		return c.String(http.StatusNotFound, "File not found")
+	}
+	artifact, ok := job.findArtifact(name)
+	if !ok {
+		return c.String(http.StatusNotFound, "File not found")
+	}
+
+	// The index is written by us, but never follow a path out of the job directory
+	root := jobDir(jobID)
+	filePath := filepath.Join(root, filepath.FromSlash(artifact.Path))
+	if rel, err := filepath.Rel(root, filePath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return c.String(http.StatusNotFound, "File not found")
+	}
+
+	// Refuse anything that is not the regular file recorded at build time
+	info, err := os.Lstat(filePath)
+	if err != nil || !info.Mode().IsRegular() {
+		return c.String(http.StatusNotFound, "File not found")
+	}
+	if info.Size() != artifact.Size {
+		fmt.Printf("Artifact %s/%s changed since it was built, refusing to serve it\n", jobID, name)
+		return c.String(http.StatusGone, "Artifact is no longer available")
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return c.String(http.StatusNotFound, "File not found")
+	}
+	defer f.Close()
+
+	h := c.Response().Header()
+	h.Set("Content-Type", "application/octet-stream")
+	h.Set("Content-Disposition", contentDisposition(artifact.Name))
+	h.Set("ETag", `"`+artifact.SHA256+`"`)
+	h.Set("Cache-Control", "private, no-cache")
+	h.Set("X-Content-Type-Options", "nosniff")
+
+	http.ServeContent(c.Response(), c.Request(), artifact.Name, info.ModTime(), f)
+	return nil
+}
+
+// listArtifacts lists the downloadable artifacts of a job
+func listArtifacts(c echo.Context) error {
+	jobID := c.Param("id")
+	if !validJobID(jobID) {
+		return c.String(http.StatusBadRequest, "Invalid job ID")
+	}
+	job, err := loadJob(jobID)
+	if err != nil || !canAccessJob(c, job) {
+		return c.String(http.StatusNotFound, "Job not found")
+	}
+
+	type artifactView struct {
+		Artifact
+		URL string `json:"url"`
+	}
+	views := []artifactView{}
+	for _, a := range job.Artifacts {
+		views = append(views, artifactView{Artifact: a, URL: downloadURL(jobID, a.Name)})
+	}
+	return c.JSON(http.StatusOK, views)
+}
+
+// contentDisposition builds an RFC 6266 attachment header with an ASCII
+// fallback filename and the exact name as an RFC 5987 encoded filename*.
+func contentDisposition(name string) string {
+	var fallback, encoded strings.Builder
+	for _, r := range name {
+		if r >= 0x20 && r < 0x7f && r != '"' && r != '\\' && r != '%' {
+			fallback.WriteRune(r)
+		} else {
+			fallback.WriteByte('_')
+		}
+	}
+	for _, b := range []byte(name) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, fallback.String(), encoded.String())
+}
+
+// isAttrChar reports whether b is an RFC 5987 attr-char
+func isAttrChar(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
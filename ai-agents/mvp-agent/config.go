@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"agentauth"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the typed configuration for the mvp-agent server.
+// Values are resolved in order: defaults, config file, environment variables, flags.
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Data      DataConfig      `yaml:"data"`
+	Model     ModelConfig     `yaml:"model"`
+	Build     BuildConfig     `yaml:"build"`
+	Limits    LimitsConfig    `yaml:"limits"`
+	Retention RetentionConfig `yaml:"retention"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Prompts   PromptsConfig   `yaml:"prompts"`
+}
+
+type ServerConfig struct {
+	// ListenAddr is the address the HTTP server listens on, e.g. ":8000".
+	ListenAddr string `yaml:"listen_addr"`
+	// UIDir is the directory holding index.html.
+	UIDir string `yaml:"ui_dir"`
+}
+
+type DataConfig struct {
+	// StarterTemplateDir is copied into every new output directory.
+	StarterTemplateDir string `yaml:"starter_template_dir"`
+	// OutputsDir holds one output_YYYYMMDD_HHMMSS_<random> directory per generation.
+	OutputsDir string `yaml:"outputs_dir"`
+	// TempDir holds the temporary requirements files. Empty means os.TempDir().
+	TempDir string `yaml:"temp_dir"`
+}
+
+type ModelConfig struct {
+	// Name is the Gemini model used by the MVP agent.
+	Name string `yaml:"name"`
+	// APIKeyEnv is the environment variable holding the API key.
+	APIKeyEnv string `yaml:"api_key_env"`
+}
+
+type BuildConfig struct {
+	// Targets are the platforms the generated MVP is cross-compiled for.
+	Targets []BuildTarget `yaml:"targets"`
+	// Timeout bounds a single go build invocation.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type BuildTarget struct {
+	GOOS   string `yaml:"goos"`
+	GOARCH string `yaml:"goarch"`
+}
+
+type LimitsConfig struct {
+	// MaxConcurrentJobs is the number of generations allowed to run at once.
+	MaxConcurrentJobs int `yaml:"max_concurrent_jobs"`
+	// MaxInputBytes caps the size of the user requirements.
+	MaxInputBytes int `yaml:"max_input_bytes"`
+}
+
+type RetentionConfig struct {
+	// Interval is how often the janitor runs. Zero disables it.
+	Interval time.Duration `yaml:"interval"`
+	// MaxAge removes outputs older than this. Zero means no age limit.
+	MaxAge time.Duration `yaml:"max_age"`
+	// MaxTotalSizeMB removes the oldest outputs once the total exceeds this. Zero means no limit.
+	MaxTotalSizeMB int64 `yaml:"max_total_size_mb"`
+	// MaxJobsPerUser keeps only the newest jobs of each user. Zero means no limit.
+	MaxJobsPerUser int `yaml:"max_jobs_per_user"`
+}
+
+type AuthConfig struct {
+	agentauth.Config `yaml:",inline"`
+	// Admins are the users allowed to use the admin endpoints.
+	Admins []string `yaml:"admins"`
+}
+
+type PromptsConfig struct {
+	// Dir holds the prompt templates, see the promptlib package.
+	Dir string `yaml:"dir"`
+	// Dev reloads prompts when their files change.
+	Dev bool `yaml:"dev"`
+	// Rules are extra coding guidelines appended to the agent instruction.
+	Rules []string `yaml:"rules"`
+}
+
+// defaultConfig returns the configuration used when nothing is overridden.
+// It matches the behaviour of the server before it became configurable.
+func defaultConfig() Config {
+	return Config{
+		Server: ServerConfig{
+			ListenAddr: ":8000",
+			UIDir:      "ui",
+		},
+		Data: DataConfig{
+			StarterTemplateDir: "./data/starter-template",
+			OutputsDir:         "./data/outputs",
+		},
+		Model: ModelConfig{
+			Name:      "gemini-2.5-pro",
+			APIKeyEnv: "GOOGLE_API_KEY",
+		},
+		Build: BuildConfig{
+			Targets: []BuildTarget{
+				{"linux", "amd64"},
+				{"darwin", "amd64"},
+				{"windows", "amd64"},
+			},
+			Timeout: 5 * time.Minute,
+		},
+		Limits: LimitsConfig{
+			// Builds are CPU heavy, so one job at a time by default
+			MaxConcurrentJobs: 1,
+			MaxInputBytes:     64 * 1024,
+		},
+		Auth: AuthConfig{
+			Config: agentauth.DefaultConfig(),
+		},
+		Prompts: PromptsConfig{
+			Dir: "prompts",
+		},
+	}
+}
+
+// loadConfig builds the configuration from the config file, environment and command line.
+// args are the command line arguments without the program name.
+func loadConfig(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("mvp-agent", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("MVP_AGENT_CONFIG"), "path to a YAML config file")
+	listenAddr := fs.String("listen", "", "address to listen on, e.g. :8000")
+	starterDir := fs.String("starter-template", "", "starter template directory")
+	outputsDir := fs.String("outputs-dir", "", "directory for generated outputs")
+	modelName := fs.String("model", "", "Gemini model name")
+	maxJobs := fs.Int("max-concurrent-jobs", 0, "number of generations allowed to run at once")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := defaultConfig()
+
+	// Config file
+	if *configPath != "" {
+		data, err := os.ReadFile(*configPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %v", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to parse config file %s: %v", *configPath, err)
+		}
+	}
+
+	// Environment variables
+	if err := applyEnv(&cfg); err != nil {
+		return nil, err
+	}
+
+	// Flags, only the ones explicitly set
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			cfg.Server.ListenAddr = *listenAddr
+		case "starter-template":
+			cfg.Data.StarterTemplateDir = *starterDir
+		case "outputs-dir":
+			cfg.Data.OutputsDir = *outputsDir
+		case "model":
+			cfg.Model.Name = *modelName
+		case "max-concurrent-jobs":
+			cfg.Limits.MaxConcurrentJobs = *maxJobs
+		}
+	})
+
+	warnings, err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		log.Printf("WARNING: %s", w)
+	}
+	return &cfg, nil
+}
+
+// applyEnv overrides the config with MVP_AGENT_* environment variables.
+func applyEnv(cfg *Config) error {
+	var errs []error
+
+	setString := func(name string, dst *string) {
+		if v, ok := os.LookupEnv(name); ok {
+			*dst = v
+		}
+	}
+	setInt := func(name string, dst *int) {
+		if v, ok := os.LookupEnv(name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a number", name, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	setDuration := func(name string, dst *time.Duration) {
+		if v, ok := os.LookupEnv(name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a duration", name, v))
+				return
+			}
+			*dst = d
+		}
+	}
+
+	setString("MVP_AGENT_LISTEN_ADDR", &cfg.Server.ListenAddr)
+	setString("MVP_AGENT_UI_DIR", &cfg.Server.UIDir)
+	setString("MVP_AGENT_STARTER_TEMPLATE_DIR", &cfg.Data.StarterTemplateDir)
+	setString("MVP_AGENT_OUTPUTS_DIR", &cfg.Data.OutputsDir)
+	setString("MVP_AGENT_TEMP_DIR", &cfg.Data.TempDir)
+	setString("MVP_AGENT_MODEL", &cfg.Model.Name)
+	setInt("MVP_AGENT_MAX_CONCURRENT_JOBS", &cfg.Limits.MaxConcurrentJobs)
+	setString("MVP_AGENT_PROMPTS_DIR", &cfg.Prompts.Dir)
+	setDuration("MVP_AGENT_RETENTION_MAX_AGE", &cfg.Retention.MaxAge)
+	setInt("MVP_AGENT_RETENTION_MAX_JOBS_PER_USER", &cfg.Retention.MaxJobsPerUser)
+
+	if v, ok := os.LookupEnv("MVP_AGENT_BUILD_TARGETS"); ok {
+		targets, err := parseBuildTargets(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("MVP_AGENT_BUILD_TARGETS: %v", err))
+		} else {
+			cfg.Build.Targets = targets
+		}
+	}
+	if v, ok := os.LookupEnv("MVP_AGENT_AUTH_ENABLED"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("MVP_AGENT_AUTH_ENABLED: %q is not a boolean", v))
+		} else {
+			cfg.Auth.Enabled = b
+		}
+	}
+	if v, ok := os.LookupEnv("MVP_AGENT_PROMPTS_DEV"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("MVP_AGENT_PROMPTS_DEV: %q is not a boolean", v))
+		} else {
+			cfg.Prompts.Dev = b
+		}
+	}
+	if v, ok := os.LookupEnv("MVP_AGENT_AUTH_TRUSTED_PROXIES"); ok {
+		cfg.Auth.TrustedProxies = nil
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				cfg.Auth.TrustedProxies = append(cfg.Auth.TrustedProxies, p)
+			}
+		}
+	}
+	setString("MVP_AGENT_AUTH_TOKENS_FILE", &cfg.Auth.TokensFile)
+	setInt("MVP_AGENT_AUTH_REQUESTS_PER_MINUTE", &cfg.Auth.RequestsPerMinute)
+
+	return errors.Join(errs...)
+}
+
+// parseBuildTargets parses a comma separated list of goos/goarch pairs.
+func parseBuildTargets(s string) ([]BuildTarget, error) {
+	var targets []BuildTarget
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		goos, goarch, ok := strings.Cut(part, "/")
+		if !ok {
+			return nil, fmt.Errorf("%q is not in goos/goarch form", part)
+		}
+		targets = append(targets, BuildTarget{GOOS: goos, GOARCH: goarch})
+	}
+	return targets, nil
+}
+
+// Validate reports every problem in the configuration at once, and the
+// settings that are valid but probably not what was meant as warnings.
+func (c *Config) Validate() (warnings []string, err error) {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.ListenAddr); err != nil {
+		add("server.listen_addr: %q is not a valid address: %v", c.Server.ListenAddr, err)
+	}
+	if info, err := os.Stat(c.Data.StarterTemplateDir); err != nil || !info.IsDir() {
+		add("data.starter_template_dir: %q is not a directory", c.Data.StarterTemplateDir)
+	}
+	if c.Data.OutputsDir == "" {
+		add("data.outputs_dir: must not be empty")
+	}
+	if c.Model.Name == "" {
+		add("model.name: must not be empty")
+	}
+	if c.Model.APIKeyEnv == "" {
+		add("model.api_key_env: must not be empty")
+	}
+
+	if len(c.Build.Targets) == 0 {
+		add("build.targets: at least one target is required")
+	}
+	for i, t := range c.Build.Targets {
+		if t.GOOS == "" || t.GOARCH == "" {
+			add("build.targets[%d]: goos and goarch are required", i)
+		}
+	}
+	if c.Build.Timeout <= 0 {
+		add("build.timeout: must be positive")
+	}
+
+	if c.Limits.MaxConcurrentJobs < 1 {
+		add("limits.max_concurrent_jobs: must be at least 1, got %d", c.Limits.MaxConcurrentJobs)
+	}
+	if c.Limits.MaxInputBytes < 1 {
+		add("limits.max_input_bytes: must be at least 1, got %d", c.Limits.MaxInputBytes)
+	}
+
+	if c.Retention.Interval < 0 || c.Retention.MaxAge < 0 {
+		add("retention: interval and max_age must not be negative")
+	}
+	if c.Retention.MaxTotalSizeMB < 0 {
+		add("retention.max_total_size_mb: must not be negative")
+	}
+	if c.Retention.MaxJobsPerUser < 0 {
+		add("retention.max_jobs_per_user: must not be negative")
+	}
+
+	if info, err := os.Stat(c.Prompts.Dir); err != nil || !info.IsDir() {
+		add("prompts.dir: %q is not a directory", c.Prompts.Dir)
+	}
+
+	if err := c.Auth.Config.Validate(); err != nil {
+		for _, e := range unwrapJoined(err) {
+			add("auth: %v", e)
+		}
+	}
+	if c.Auth.Enabled && len(c.Auth.Admins) == 0 {
+		warnings = append(warnings, "auth is enabled but auth.admins is empty, admin endpoints are unreachable")
+	}
+
+	if len(errs) > 0 {
+		return warnings, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return warnings, nil
+}
+
+// unwrapJoined splits an error created by errors.Join into its parts
+func unwrapJoined(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
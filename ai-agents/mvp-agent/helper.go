@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
@@ -8,24 +10,27 @@ import (
 	"time"
 )
 
-// copyStarterTemplate copies the starter template to a timestamped output directory
-func copyStarterTemplate() (string, error) {
-	// Create timestamp for unique folder name
-	timestamp := time.Now().Format("20060102_150405")
-	outputDirName := fmt.Sprintf("output_%s", timestamp)
+// newJobID returns the ID of a new job, which is also its output directory name
+func newJobID() (string, error) {
+	// Timestamp for sorting, random suffix so jobs started in the same second differ
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %v", err)
+	}
+	return fmt.Sprintf("output_%s_%s", time.Now().Format("20060102_150405"), hex.EncodeToString(suffix)), nil
+}
 
-	// Define paths
-	sourceDir := "./data/starter-template"
-	outputBaseDir := "./data/outputs"
-	outputDir := filepath.Join(outputBaseDir, outputDirName)
+// copyStarterTemplate creates a job's output directory with the starter template
+func copyStarterTemplate(id string) (string, error) {
+	outputDir := jobDir(id)
 
-	// Create outputs directory if it doesn't exist
-	if err := os.MkdirAll(outputBaseDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create outputs directory: %v", err)
+	// Claim the directory, it must not belong to another job
+	if err := os.Mkdir(outputDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %v", err)
 	}
 
 	// Copy the starter template
-	if err := copyDir(sourceDir, outputDir); err != nil {
+	if err := copyDir(cfg.Data.StarterTemplateDir, outputDir); err != nil {
 		return "", fmt.Errorf("failed to copy directory: %v", err)
 	}
 
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// staleTempFileAge is how old a leftover requirements file must be before the janitor removes it
+const staleTempFileAge = time.Hour
+
+// janitorMu serialises janitor runs triggered by the timer and the admin endpoint
+var janitorMu sync.Mutex
+
+// JanitorReport describes what one janitor run removed
+type JanitorReport struct {
+	Removed    []*Job   `json:"removed"`
+	FreedBytes int64    `json:"freedBytes"`
+	TempFiles  []string `json:"tempFiles"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// startJanitor runs the janitor every retention interval until ctx is done
+func startJanitor(ctx context.Context) {
+	if cfg.Retention.Interval <= 0 {
+		fmt.Println("Output janitor disabled (retention.interval is 0)")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.Retention.Interval)
+		defer ticker.Stop()
+		for {
+			report := runJanitor(time.Now())
+			if len(report.Removed) > 0 || len(report.TempFiles) > 0 || len(report.Errors) > 0 {
+				fmt.Printf("Janitor removed %d outputs (%d bytes) and %d temp files, %d errors\n",
+					len(report.Removed), report.FreedBytes, len(report.TempFiles), len(report.Errors))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// runJanitor applies the retention policy once. Running and pinned jobs are never removed.
+func runJanitor(now time.Time) *JanitorReport {
+	janitorMu.Lock()
+	defer janitorMu.Unlock()
+
+	report := &JanitorReport{}
+
+	jobs, err := listJobs()
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+
+	removed := make(map[string]bool)
+	remove := func(job *Job, reason string) {
+		if removed[job.ID] || job.Pinned || job.Status == JobRunning {
+			return
+		}
+		if err := removeJob(job.ID); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", job.ID, err))
+			return
+		}
+		fmt.Printf("Janitor removed %s (%s)\n", job.ID, reason)
+		removed[job.ID] = true
+		report.Removed = append(report.Removed, job)
+		report.FreedBytes += job.SizeBytes
+	}
+
+	// Max age
+	if cfg.Retention.MaxAge > 0 {
+		for _, job := range jobs {
+			if now.Sub(job.CreatedAt) > cfg.Retention.MaxAge {
+				remove(job, "older than max_age")
+			}
+		}
+	}
+
+	// Max jobs per user, keeping the newest ones. Pinned jobs do not count.
+	if cfg.Retention.MaxJobsPerUser > 0 {
+		kept := make(map[string]int)
+		for i := len(jobs) - 1; i >= 0; i-- {
+			job := jobs[i]
+			if removed[job.ID] || job.Pinned || job.Status == JobRunning {
+				continue
+			}
+			kept[job.Owner]++
+			if kept[job.Owner] > cfg.Retention.MaxJobsPerUser {
+				remove(job, "over max_jobs_per_user")
+			}
+		}
+	}
+
+	// Max total size, removing the oldest first
+	if cfg.Retention.MaxTotalSizeMB > 0 {
+		limit := cfg.Retention.MaxTotalSizeMB * 1024 * 1024
+		var total int64
+		for _, job := range jobs {
+			if !removed[job.ID] {
+				total += job.SizeBytes
+			}
+		}
+		for _, job := range jobs {
+			if total <= limit {
+				break
+			}
+			if removed[job.ID] {
+				continue
+			}
+			remove(job, "over max_total_size_mb")
+			if removed[job.ID] {
+				total -= job.SizeBytes
+			}
+		}
+	}
+
+	// Requirements files left behind by crashed runs
+	report.TempFiles = removeStaleTempFiles(now)
+
+	return report
+}
+
+// removeStaleTempFiles deletes requirements files older than staleTempFileAge
+func removeStaleTempFiles(now time.Time) []string {
+	dir := cfg.Data.TempDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "requirements_*.txt"))
+	if err != nil {
+		return nil
+	}
+
+	var removed []string
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || now.Sub(info.ModTime()) < staleTempFileAge {
+			continue
+		}
+		if err := os.Remove(path); err == nil {
+			removed = append(removed, path)
+		}
+	}
+	return removed
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Job statuses
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobUnknown   = "unknown"
+)
+
+// jobIDPattern matches the job IDs created by newJobID,
+// output_<timestamp>_<random>. Older outputs have no random suffix.
+var jobIDPattern = regexp.MustCompile(`^output_([0-9]{8}_[0-9]{6})(?:_[0-9a-f]{16})?$`)
+
+// Job is the metadata of one generation. It is stored next to the output
+// directory as <id>.job.json, outside the agent's working directory.
+type Job struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"owner"`
+	CreatedAt time.Time `json:"createdAt"`
+	Status    string    `json:"status"`
+	Pinned    bool      `json:"pinned"`
+
+	// PromptVersion is the agent instruction used, e.g. mvp_agent@v1
+	PromptVersion string `json:"promptVersion,omitempty"`
+
+	// Artifacts is the index of downloadable files, recorded at build time
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+
+	// SizeBytes is computed when listing and never stored.
+	SizeBytes int64 `json:"sizeBytes"`
+}
+
+// validJobID reports whether id is a well formed job ID
+func validJobID(id string) bool {
+	return jobIDPattern.MatchString(id)
+}
+
+// jobDir returns the output directory of a job
+func jobDir(id string) string {
+	return filepath.Join(cfg.Data.OutputsDir, id)
+}
+
+// jobMetaPath returns the path of a job's metadata file
+func jobMetaPath(id string) string {
+	return filepath.Join(cfg.Data.OutputsDir, id+".job.json")
+}
+
+// saveJob writes the job metadata atomically
+func saveJob(job *Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %v", err)
+	}
+	tmp := jobMetaPath(job.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write job metadata: %v", err)
+	}
+	if err := os.Rename(tmp, jobMetaPath(job.ID)); err != nil {
+		return fmt.Errorf("failed to save job metadata: %v", err)
+	}
+	return nil
+}
+
+// loadJob reads the job metadata. Output directories created before job
+// metadata existed are reported with their timestamp and an unknown status.
+func loadJob(id string) (*Job, error) {
+	if !validJobID(id) {
+		return nil, fmt.Errorf("invalid job ID: %q", id)
+	}
+	info, err := os.Stat(jobDir(id))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(jobMetaPath(id))
+	if os.IsNotExist(err) {
+		createdAt, perr := time.ParseInLocation("20060102_150405", jobIDPattern.FindStringSubmatch(id)[1], time.Local)
+		if perr != nil {
+			createdAt = info.ModTime()
+		}
+		// Legacy outputs have no known owner, only admins can see them when auth is enabled
+		return &Job{ID: id, CreatedAt: createdAt, Status: JobUnknown}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job metadata: %v", err)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to parse job metadata for %s: %v", id, err)
+	}
+	return &job, nil
+}
+
+// updateJob loads a job, applies fn and saves it again
+func updateJob(id string, fn func(job *Job)) (*Job, error) {
+	job, err := loadJob(id)
+	if err != nil {
+		return nil, err
+	}
+	fn(job)
+	if err := saveJob(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// listJobs returns every job under the outputs directory, oldest first, with sizes filled in
+func listJobs() ([]*Job, error) {
+	entries, err := os.ReadDir(cfg.Data.OutputsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outputs directory: %v", err)
+	}
+
+	var jobs []*Job
+	for _, entry := range entries {
+		if !entry.IsDir() || !validJobID(entry.Name()) {
+			continue
+		}
+		job, err := loadJob(entry.Name())
+		if err != nil {
+			fmt.Printf("Skipping job %s: %v\n", entry.Name(), err)
+			continue
+		}
+		job.SizeBytes, _ = dirSize(jobDir(job.ID))
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// failInterruptedJobs marks the jobs still running as failed. It is called at
+// startup, when no job of this process can be running yet, so they are jobs a
+// crash or restart interrupted and the janitor and admins can remove them.
+func failInterruptedJobs() ([]string, error) {
+	jobs, err := listJobs()
+	if err != nil {
+		return nil, err
+	}
+	var failed []string
+	for _, job := range jobs {
+		if job.Status != JobRunning {
+			continue
+		}
+		if _, err := updateJob(job.ID, func(j *Job) { j.Status = JobFailed }); err != nil {
+			return failed, fmt.Errorf("failed to mark job %s as failed: %v", job.ID, err)
+		}
+		failed = append(failed, job.ID)
+	}
+	return failed, nil
+}
+
+// removeJob deletes a job's output directory and metadata
+func removeJob(id string) error {
+	if !validJobID(id) {
+		return fmt.Errorf("invalid job ID: %q", id)
+	}
+	if err := os.RemoveAll(jobDir(id)); err != nil {
+		return fmt.Errorf("failed to remove output directory: %v", err)
+	}
+	if err := os.Remove(jobMetaPath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove job metadata: %v", err)
+	}
+	return nil
+}
+
+// dirSize returns the total size of the regular files under dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
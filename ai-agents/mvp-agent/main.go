@@ -1,24 +1,84 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"os"
+
+	"agentauth"
+	"promptlib"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
+// cfg is the server configuration, loaded once at startup
+var cfg *Config
+
+// prompts holds the versioned agent instructions
+var prompts *promptlib.Store
+
 func main() {
+	// mcp-serve publishes the tools instead of starting the web server
+	if len(os.Args) > 1 && os.Args[1] == "mcp-serve" {
+		os.Exit(runMCPServe(os.Args[2:]))
+	}
+
+	// Load configuration
+	var err error
+	cfg, err = loadConfig(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Load and check the prompt templates
+	prompts, err = promptlib.Open(cfg.Prompts.Dir, promptlib.Options{Dev: cfg.Prompts.Dev})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load prompts: %v\n", err)
+		os.Exit(2)
+	}
+	if err := prompts.Validate(mvpPromptName, MVPPromptVars{}); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid prompts: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Limit the number of generations running at once
+	jobSlots = make(chan struct{}, cfg.Limits.MaxConcurrentJobs)
+
+	// Authentication and per-user rate limits
+	authenticator, err := agentauth.New(cfg.Auth.Config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid auth configuration: %v\n", err)
+		os.Exit(2)
+	}
+	generateLimiter = agentauth.NewRateLimiter(cfg.Auth.RequestsPerMinute)
+
+	// Jobs left running by a crash or restart will never finish
+	interrupted, err := failInterruptedJobs()
+	if err != nil {
+		log.Printf("Error checking for interrupted jobs: %v", err)
+	}
+	for _, id := range interrupted {
+		log.Printf("Marked interrupted job %s as failed", id)
+	}
+
+	// Remove old outputs in the background
+	startJanitor(context.Background())
+
 	e := echo.New()
 
 	// Middleware
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORS())
+	e.Use(echo.WrapMiddleware(authenticator.Middleware))
 
 	// Routes
 	setupRoutes(e)
 
 	// Start server
-	log.Println("Server starting on :8000")
-	e.Logger.Fatal(e.Start(":8000"))
+	log.Printf("Server starting on %s", cfg.Server.ListenAddr)
+	e.Logger.Fatal(e.Start(cfg.Server.ListenAddr))
 }
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"agentauth"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"google.golang.org/adk/tool"
+)
+
+// runMCPServe publishes the file and build tools as an MCP server scoped to a
+// workspace, over stdio or, with -http, streamable HTTP. It returns the exit code.
+func runMCPServe(args []string) int {
+	fs := flag.NewFlagSet("mvp-agent mcp-serve", flag.ContinueOnError)
+	workspace := fs.String("workspace", ".", "directory the tools are confined to")
+	httpAddr := fs.String("http", "", "serve streamable HTTP on this address, e.g. 127.0.0.1:8090, instead of stdio")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ws, err := newWorkspace(*workspace)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	server := newMCPServer(ws)
+
+	if *httpAddr == "" {
+		// stdout carries the protocol, so tool logs go to stderr
+		toolLogOutput = os.Stderr
+		if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
+			fmt.Fprintf(os.Stderr, "mcp server stopped: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	// Same MVP_AGENT_AUTH_* settings as the web server
+	authCfg, err := agentauth.FromEnv("MVP_AGENT_")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid auth configuration: %v\n", err)
+		return 2
+	}
+	authenticator, err := agentauth.New(authCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid auth configuration: %v\n", err)
+		return 2
+	}
+	// The tools write files and run builds, never offer them to the network unauthenticated
+	if host, _, _ := net.SplitHostPort(*httpAddr); !authCfg.Enabled && !isLoopback(host) {
+		fmt.Fprintf(os.Stderr, "refusing to serve %s on %s without authentication: set MVP_AGENT_AUTH_ENABLED or listen on a loopback address such as 127.0.0.1\n", ws.root, *httpAddr)
+		return 2
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	log.Printf("MCP server for %s listening on http://%s/mcp", ws.root, *httpAddr)
+	if err := http.ListenAndServe(*httpAddr, authenticator.Middleware(mux)); err != nil {
+		fmt.Fprintf(os.Stderr, "mcp server stopped: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// newMCPServer registers every agent tool. The input schemas are inferred from
+// the same *Params structs and jsonschema tags the ADK function tools use.
+func newMCPServer(ws *workspace) *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "mvp-agent", Version: "v1.0.0"}, nil)
+
+	addMCPTool(server, "ReadFile", ReadFile, func(a *ReadFileParams) error {
+		return ws.resolve(&a.FilePath)
+	})
+	addMCPTool(server, "WriteFile", WriteFile, func(a *WriteFileParams) error {
+		return ws.resolve(&a.FilePath)
+	})
+	addMCPTool(server, "GrepFile", GrepFile, func(a *GrepFileParams) error {
+		return ws.resolve(&a.FilePath)
+	})
+	addMCPTool(server, "SedTool", SedTool, func(a *SedToolParams) error {
+		return ws.resolve(&a.FilePath)
+	})
+	addMCPTool(server, "GoBuild", GoBuild, func(a *GoBuildParams) error {
+		return ws.resolve(&a.WorkingDir)
+	})
+	addMCPTool(server, "InsertInFileAtLine", InsertInFileAtLine, func(a *InsertInFileAtLineParams) error {
+		return ws.resolve(&a.FilePath)
+	})
+	addMCPTool(server, "AppendToFile", AppendToFile, func(a *AppendToFileParams) error {
+		return ws.resolve(&a.FilePath)
+	})
+	addMCPTool(server, "RenameFile", RenameFile, func(a *RenameFileParams) error {
+		return errors.Join(ws.resolve(&a.OldPath), ws.resolve(&a.NewPath))
+	})
+	addMCPTool(server, "MoveFile", MoveFile, func(a *MoveFileParams) error {
+		return errors.Join(ws.resolve(&a.SourcePath), ws.resolve(&a.DestinationPath))
+	})
+	addMCPTool(server, "ListFiles", ListFiles, func(a *ListFilesParams) error {
+		return ws.resolve(&a.Directory)
+	})
+	return server
+}
+
+// addMCPTool adapts an agent tool to MCP. scope rewrites the path arguments
+// into the workspace and rejects paths outside it.
+func addMCPTool[In, Out any](server *mcp.Server, name string, fn func(tool.Context, In) Out, scope func(*In) error) {
+	mcp.AddTool(server, &mcp.Tool{Name: name, Description: toolDescriptions[name]},
+		func(ctx context.Context, req *mcp.CallToolRequest, args In) (*mcp.CallToolResult, Out, error) {
+			var zero Out
+			if err := scope(&args); err != nil {
+				return nil, zero, err
+			}
+			// The tools do not use the ADK context
+			return nil, fn(nil, args), nil
+		})
+}
+
+// workspace confines tool paths to one directory
+type workspace struct {
+	root string
+}
+
+func newWorkspace(dir string) (*workspace, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workspace %s: %v", dir, err)
+	}
+	root, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workspace %s: %v", dir, err)
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("workspace %s is not a directory", dir)
+	}
+	return &workspace{root: root}, nil
+}
+
+// resolve rewrites *p, relative to the workspace or absolute inside it, to an
+// absolute path. An empty path is the workspace itself. Symlinks pointing out
+// of the workspace are rejected like ".." is.
+func (w *workspace) resolve(p *string) error {
+	path := *p
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(w.root, path)
+	}
+	path = filepath.Clean(path)
+
+	// Resolve the longest existing prefix, the rest may not exist yet
+	existing, rest := path, ""
+	for {
+		real, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			path = filepath.Join(real, rest)
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("invalid path %s: %v", *p, err)
+		}
+		// A dangling symlink could still be written through
+		if _, err := os.Lstat(existing); err == nil {
+			return fmt.Errorf("path %s is a broken symlink", *p)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("path %s is outside the workspace", *p)
+	}
+	*p = path
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspaceResolve(t *testing.T) {
+	tmp := t.TempDir()
+	outside := filepath.Join(tmp, "outside")
+	for _, dir := range []string{"ws/sub", "outside"} {
+		if err := os.MkdirAll(filepath.Join(tmp, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "ws", "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{
+		"in-link":   filepath.Join(tmp, "ws", "sub"),
+		"out-link":  outside,
+		"dangling":  filepath.Join(outside, "missing.txt"),
+		"rel-up":    filepath.Join("..", "outside"),
+		"rel-local": "sub",
+	} {
+		if err := os.Symlink(target, filepath.Join(tmp, "ws", link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ws, err := newWorkspace(filepath.Join(tmp, "ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		path string
+		// want is the resolved path relative to the root, empty when the path is rejected
+		want string
+	}{
+		{"empty is the root", "", "."},
+		{"file", "a.txt", "a.txt"},
+		{"new file in a new directory", "new/dir/file.txt", "new/dir/file.txt"},
+		{"dot dot inside", "sub/../a.txt", "a.txt"},
+		{"absolute inside", filepath.Join(ws.root, "sub"), "sub"},
+		{"symlink inside", "in-link/new.txt", "sub/new.txt"},
+		{"relative symlink inside", "rel-local", "sub"},
+		{"parent", "..", ""},
+		{"dot dot out", "sub/../../outside", ""},
+		{"dot dot out to a new file", "../outside/new.txt", ""},
+		{"absolute outside", outside, ""},
+		{"absolute root of the filesystem", "/etc/passwd", ""},
+		{"symlink out", "out-link", ""},
+		{"through a symlink out", "out-link/new.txt", ""},
+		{"relative symlink out", "rel-up/new.txt", ""},
+		{"dangling symlink", "dangling", ""},
+		{"below a dangling symlink", "dangling/new.txt", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := tc.path
+			err := ws.resolve(&p)
+			if tc.want == "" {
+				if err == nil {
+					t.Fatalf("resolve(%q) = %q, want it rejected", tc.path, p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve(%q): %v", tc.path, err)
+			}
+			if want := filepath.Join(ws.root, tc.want); p != want {
+				t.Fatalf("resolve(%q) = %q, want %q", tc.path, p, want)
+			}
+		})
+	}
+}
@@ -4,31 +4,58 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 
 	"google.golang.org/adk/tool"
 )
 
-// Global log channel for tools
-var toolLogChannel chan<- string
+// toolLogChannels are the log channels of the running jobs, by agent session ID
+var (
+	toolLogMu       sync.Mutex
+	toolLogChannels = map[string]chan<- string{}
+)
+
+// toolLogOutput is where toolLog prints, stderr when stdout carries MCP
+var toolLogOutput io.Writer = os.Stdout
+
+// registerToolLog sends the logs of the tools called in a session to a job's
+// channel, until the returned function is called
+func registerToolLog(sessionID string, logChan chan<- string) func() {
+	toolLogMu.Lock()
+	defer toolLogMu.Unlock()
+	toolLogChannels[sessionID] = logChan
+	return func() {
+		toolLogMu.Lock()
+		defer toolLogMu.Unlock()
+		delete(toolLogChannels, sessionID)
+	}
+}
 
-// Helper function to send logs
-func toolLog(msg string) {
-	if toolLogChannel != nil {
-		select {
-		case toolLogChannel <- msg:
-		default: // Don't block if channel is full
+// Helper function to send logs to the job the tool runs for
+func toolLog(ctx tool.Context, msg string) {
+	// MCP calls have no ADK context and no job
+	if ctx != nil {
+		toolLogMu.Lock()
+		logChan := toolLogChannels[ctx.SessionID()]
+		toolLogMu.Unlock()
+		if logChan != nil {
+			select {
+			case logChan <- msg:
+			default: // Don't block if channel is full
+			}
 		}
 	}
-	fmt.Println(msg) // Still print to console
+	fmt.Fprintln(toolLogOutput, msg) // Still print to console
 }
 
 func ReadFile(ctx tool.Context, args ReadFileParams) ReadFileResult {
-	toolLog("Reading file: " + args.FilePath)
+	toolLog(ctx, "Reading file: "+args.FilePath)
 	content, err := os.ReadFile(args.FilePath)
 	if err != nil {
 		return ReadFileResult{Status: "error", Message: fmt.Sprintf("Error reading file %s: %v", args.FilePath, err)}
@@ -37,7 +64,7 @@ func ReadFile(ctx tool.Context, args ReadFileParams) ReadFileResult {
 }
 
 func GrepFile(ctx tool.Context, args GrepFileParams) GrepFileResult {
-	toolLog("Grepping file: " + args.FilePath)
+	toolLog(ctx, "Grepping file: "+args.FilePath)
 	file, err := os.Open(args.FilePath)
 	if err != nil {
 		return GrepFileResult{Status: "error", Message: fmt.Sprintf("Error opening file %s: %v", args.FilePath, err)}
@@ -69,7 +96,7 @@ func GrepFile(ctx tool.Context, args GrepFileParams) GrepFileResult {
 }
 
 func SedTool(ctx tool.Context, args SedToolParams) SedToolResult {
-	toolLog(fmt.Sprintf("SedTool: %+v", args))
+	toolLog(ctx, fmt.Sprintf("SedTool: %+v", args))
 	// 1. Read all lines from the file
 	input, err := os.ReadFile(args.FilePath)
 	if err != nil {
@@ -128,7 +155,7 @@ func SedTool(ctx tool.Context, args SedToolParams) SedToolResult {
 }
 
 func WriteFile(ctx tool.Context, args WriteFileParams) WriteFileResult {
-	toolLog("Writing file: " + args.FilePath)
+	toolLog(ctx, "Writing file: "+args.FilePath)
 	// Ensure the output folder exists before writing
 	dir := filepath.Dir(args.FilePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -143,7 +170,7 @@ func WriteFile(ctx tool.Context, args WriteFileParams) WriteFileResult {
 }
 
 func GoBuild(ctx tool.Context, args GoBuildParams) GoBuildResult {
-	toolLog("Running go build in: " + args.WorkingDir)
+	toolLog(ctx, "Running go build in: "+args.WorkingDir)
 
 	// Check if the working directory exists
 	if _, err := os.Stat(args.WorkingDir); os.IsNotExist(err) {
@@ -158,7 +185,7 @@ func GoBuild(ctx tool.Context, args GoBuildParams) GoBuildResult {
 	// Create the go build command
 	cmd := exec.Command("go", "build", "./...")
 	cmd.Dir = args.WorkingDir
-	toolLog(fmt.Sprintf("Executing command: %s (in directory: %s)", cmd.String(), args.WorkingDir))
+	toolLog(ctx, fmt.Sprintf("Executing command: %s (in directory: %s)", cmd.String(), args.WorkingDir))
 
 	// Capture stdout and stderr
 	var stdout, stderr bytes.Buffer
@@ -195,7 +222,7 @@ func GoBuild(ctx tool.Context, args GoBuildParams) GoBuildResult {
 }
 
 func InsertInFileAtLine(ctx tool.Context, args InsertInFileAtLineParams) InsertInFileAtLineResult {
-	toolLog(fmt.Sprintf("Inserting content at line %d in file: %s", args.LineNumber, args.FilePath))
+	toolLog(ctx, fmt.Sprintf("Inserting content at line %d in file: %s", args.LineNumber, args.FilePath))
 
 	// Read the file
 	content, err := os.ReadFile(args.FilePath)
@@ -245,7 +272,7 @@ func InsertInFileAtLine(ctx tool.Context, args InsertInFileAtLineParams) InsertI
 }
 
 func AppendToFile(ctx tool.Context, args AppendToFileParams) AppendToFileResult {
-	toolLog("Appending to file: " + args.FilePath)
+	toolLog(ctx, "Appending to file: "+args.FilePath)
 
 	// Open file in append mode, create if doesn't exist
 	file, err := os.OpenFile(args.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
@@ -306,7 +333,7 @@ func AppendToFile(ctx tool.Context, args AppendToFileParams) AppendToFileResult
 }
 
 func RenameFile(ctx tool.Context, args RenameFileParams) RenameFileResult {
-	toolLog(fmt.Sprintf("Renaming file from %s to %s", args.OldPath, args.NewPath))
+	toolLog(ctx, fmt.Sprintf("Renaming file from %s to %s", args.OldPath, args.NewPath))
 
 	// Ensure destination directory exists if the path contains a directory
 	destDir := filepath.Dir(args.NewPath)
@@ -335,7 +362,7 @@ func RenameFile(ctx tool.Context, args RenameFileParams) RenameFileResult {
 }
 
 func MoveFile(ctx tool.Context, args MoveFileParams) MoveFileResult {
-	toolLog(fmt.Sprintf("Moving file from %s to %s", args.SourcePath, args.DestinationPath))
+	toolLog(ctx, fmt.Sprintf("Moving file from %s to %s", args.SourcePath, args.DestinationPath))
 
 	// Check if source file exists
 	if _, err := os.Stat(args.SourcePath); os.IsNotExist(err) {
@@ -377,7 +404,7 @@ func MoveFile(ctx tool.Context, args MoveFileParams) MoveFileResult {
 }
 
 func ListFiles(ctx tool.Context, args ListFilesParams) ListFilesResult {
-	toolLog(fmt.Sprintf("Listing files in directory: %s (recursive: %t)", args.Directory, args.Recursive))
+	toolLog(ctx, fmt.Sprintf("Listing files in directory: %s (recursive: %t)", args.Directory, args.Recursive))
 
 	// Check if directory exists
 	dirInfo, err := os.Stat(args.Directory)
@@ -7,12 +7,18 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"time"
+
+	"agentauth"
 
 	"github.com/labstack/echo/v4"
 )
 
-// Global channel for streaming logs
-var logChannel chan string
+// jobSlots limits the number of generations running at once
+var jobSlots chan struct{}
+
+// generateLimiter applies the per-user rate limit to generations
+var generateLimiter *agentauth.RateLimiter
 
 // setupRoutes configures all the routes for the application
 func setupRoutes(e *echo.Echo) {
@@ -21,14 +27,27 @@ func setupRoutes(e *echo.Echo) {
 
 	e.GET("/generate-mvp", generateMVP)
 
-	// SSE endpoint for downloading builds
-	e.GET("/download/:outputDir/:filename", downloadMVP)
+	// The caller's own jobs
+	e.GET("/jobs", listMyJobs)
+
+	// Download builds registered in a job's artifact index
+	e.GET("/download/:jobID/:name", downloadMVP)
+	e.GET("/jobs/:id/artifacts", listArtifacts)
+
+	// Keep favourite jobs out of the janitor's reach
+	e.POST("/jobs/:id/pin", pinJob)
+	e.DELETE("/jobs/:id/pin", unpinJob)
 
+	// Admin endpoints for listing and purging outputs
+	admin := e.Group("/admin", requireAdmin)
+	admin.GET("/outputs", listOutputs)
+	admin.DELETE("/outputs/:id", purgeOutput)
+	admin.POST("/outputs/gc", collectOutputs)
 }
 
 // serveIndex serves the index.html file
 func serveIndex(c echo.Context) error {
-	return c.File("ui/index.html")
+	return c.File(filepath.Join(cfg.Server.UIDir, "index.html"))
 }
 
 func generateMVP(c echo.Context) error {
@@ -37,6 +56,23 @@ func generateMVP(c echo.Context) error {
 	if userInput == "" {
 		return c.String(http.StatusBadRequest, "Please provide user_input parameter")
 	}
+	if len(userInput) > cfg.Limits.MaxInputBytes {
+		return c.String(http.StatusRequestEntityTooLarge, fmt.Sprintf("user_input must be at most %d bytes", cfg.Limits.MaxInputBytes))
+	}
+
+	// Per-user rate limit
+	user := currentIdentity(c).User
+	if delay := generateLimiter.Reserve(user); delay > 0 {
+		c.Response().Header().Set("Retry-After", fmt.Sprint(int(delay.Seconds())+1))
+		return c.String(http.StatusTooManyRequests, "Rate limit exceeded, please try again later")
+	}
+
+	// Reserve a job slot
+	select {
+	case jobSlots <- struct{}{}:
+	default:
+		return c.String(http.StatusServiceUnavailable, "Too many MVP generations in progress, please try again later")
+	}
 
 	// Set SSE headers
 	c.Response().Header().Set("Content-Type", "text/event-stream")
@@ -44,51 +80,94 @@ func generateMVP(c echo.Context) error {
 	c.Response().Header().Set("Connection", "keep-alive")
 	c.Response().Header().Set("Access-Control-Allow-Origin", "*")
 
-	// Initialize log channel
-	logChannel = make(chan string, 100)
+	// Log channel of this job, closed by its goroutine when it is done
+	logChannel := make(chan string, 100)
 
 	// Start MVP generation in goroutine
 	go func() {
 		defer close(logChannel)
+		defer func() { <-jobSlots }()
 
-		// Create requirements file
+		// Create requirements file, removed on every exit path
 		requirementsFile, err := createRequirementsFile(userInput)
 		if err != nil {
-			fmt.Println("Error creating requirements file: %w", err)
+			logChannel <- fmt.Sprintf("Error creating requirements file: %v", err)
 			return
 		}
+		defer os.Remove(requirementsFile)
 
-		// Copy starter template
-		outputDir, err := copyStarterTemplate()
+		// Record the job before its output directory exists, so the janitor
+		// never sees the directory without its owner and running status
+		jobID, err := newJobID()
 		if err != nil {
-			fmt.Println("Error copying starter template: %w", err)
+			logChannel <- fmt.Sprintf("Error creating job: %v", err)
+			return
+		}
+		if err := os.MkdirAll(cfg.Data.OutputsDir, 0755); err != nil {
+			logChannel <- fmt.Sprintf("Error creating outputs directory: %v", err)
+			return
+		}
+		job := &Job{
+			ID:        jobID,
+			Owner:     user,
+			CreatedAt: time.Now(),
+			Status:    JobRunning,
+		}
+		if err := saveJob(job); err != nil {
+			logChannel <- fmt.Sprintf("Error saving job: %v", err)
 			return
 		}
+
+		// Copy starter template, there is nothing to keep when it fails
+		outputDir, err := copyStarterTemplate(job.ID)
+		if err != nil {
+			logChannel <- fmt.Sprintf("Error copying starter template: %v", err)
+			if err := removeJob(job.ID); err != nil {
+				fmt.Printf("Error removing job %s: %v\n", job.ID, err)
+			}
+			return
+		}
+		status := JobFailed
+		defer func() {
+			if _, err := updateJob(job.ID, func(j *Job) { j.Status = status }); err != nil {
+				fmt.Printf("Error updating job %s: %v\n", job.ID, err)
+			}
+		}()
 		logChannel <- fmt.Sprintf("✅ Copied starter template to: %s", outputDir)
 
+		// Pick the prompt version for this user and render the instruction
+		instruction, promptVersion, err := renderMVPInstruction(user)
+		if err != nil {
+			logChannel <- fmt.Sprintf("Error preparing agent instruction: %v", err)
+			return
+		}
+		if _, err := updateJob(job.ID, func(j *Job) { j.PromptVersion = promptVersion }); err != nil {
+			logChannel <- fmt.Sprintf("Error saving job: %v", err)
+			return
+		}
+
 		// Copy requirements file
 		if err := copyPRDToOutput(requirementsFile, outputDir); err != nil {
-			fmt.Println("Error copying requirements file: %w", err)
+			logChannel <- fmt.Sprintf("Error copying requirements file: %v", err)
 			return
 		}
 		logChannel <- "Copied requirements file to output directory"
 
 		// Run agent
 		ctx := context.Background()
-		if err := RunMVPAgent(ctx, outputDir, logChannel); err != nil {
+		logChannel <- fmt.Sprintf("Using prompt %s", promptVersion)
+		if err := RunMVPAgent(ctx, outputDir, user, instruction, logChannel); err != nil {
 			logChannel <- fmt.Sprintf("Error running MVP agent: %v", err)
 			return
 		}
-
-		// Clean up
-		os.Remove(requirementsFile)
 		logChannel <- "MVP generation completed successfully!"
 
 		// Build the MVP
 		if err := buildMVP(outputDir, logChannel); err != nil {
-			fmt.Println("Error building MVP: %w", err)
+			logChannel <- fmt.Sprintf("Error building MVP: %v", err)
 			return
 		}
+		status = JobSucceeded
 		logChannel <- "MVP built successfully!"
 	}()
 
@@ -101,10 +180,27 @@ func generateMVP(c echo.Context) error {
 	return nil
 }
 
+// renderMVPInstruction selects the mvp_agent prompt for the user and renders it
+func renderMVPInstruction(user string) (string, string, error) {
+	prompt, err := prompts.Select(mvpPromptName, user)
+	if err != nil {
+		return "", "", err
+	}
+	vars, err := mvpPromptVars()
+	if err != nil {
+		return "", "", err
+	}
+	instruction, err := prompt.Render(vars)
+	if err != nil {
+		return "", "", err
+	}
+	return instruction, prompt.ID(), nil
+}
+
 // createRequirementsFile creates a temporary file with user requirements
 func createRequirementsFile(userInput string) (string, error) {
 	// Create a temporary file
-	tmpFile, err := os.CreateTemp("", "requirements_*.txt")
+	tmpFile, err := os.CreateTemp(cfg.Data.TempDir, "requirements_*.txt")
 	if err != nil {
 		return "", fmt.Errorf("failed to create temp file: %v", err)
 	}
@@ -113,6 +209,7 @@ func createRequirementsFile(userInput string) (string, error) {
 	// Write user input to the file
 	_, err = tmpFile.WriteString(userInput)
 	if err != nil {
+		os.Remove(tmpFile.Name())
 		return "", fmt.Errorf("failed to write to temp file: %v", err)
 	}
 
@@ -128,20 +225,11 @@ func buildMVP(outputDir string, logChannel chan<- string) error {
 		return fmt.Errorf("failed to create builds directory: %v", err)
 	}
 
-	// Platforms to build for
-	platforms := []struct {
-		GOOS   string
-		GOARCH string
-	}{
-		{"linux", "amd64"},
-		{"darwin", "amd64"},
-		{"windows", "amd64"},
-	}
-
-	var builtFiles []string
+	jobID := filepath.Base(outputDir)
+	var artifacts []Artifact
 
-	// Build for each platform
-	for _, platform := range platforms {
+	// Build for each configured platform
+	for _, platform := range cfg.Build.Targets {
 		logChannel <- fmt.Sprintf("Building for %s/%s...", platform.GOOS, platform.GOARCH)
 
 		// Output filename
@@ -155,7 +243,8 @@ func buildMVP(outputDir string, logChannel chan<- string) error {
 		outputPath := filepath.Join(absoluteBuildDir, output)
 
 		// Build command
-		cmd := exec.Command("go", "build", "-o", outputPath, ".")
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Build.Timeout)
+		cmd := exec.CommandContext(ctx, "go", "build", "-o", outputPath, ".")
 		cmd.Dir = backendDir // Build from backend folder
 		cmd.Env = append(os.Environ(),
 			"GOOS="+platform.GOOS,
@@ -167,26 +256,36 @@ func buildMVP(outputDir string, logChannel chan<- string) error {
 
 		// Run build and capture output
 		output_bytes, err := cmd.CombinedOutput()
+		cancel()
 		if err != nil {
 			errorMsg := fmt.Sprintf("❌ Failed to build for %s/%s: %v\nOutput: %s", platform.GOOS, platform.GOARCH, err, string(output_bytes))
 			logChannel <- errorMsg
 			continue
 		}
 
+		// Register the build in the job's artifact index
+		artifact, err := newArtifact(jobID, output, "builds/"+output)
+		if err != nil {
+			logChannel <- fmt.Sprintf("❌ Failed to record %s: %v", output, err)
+			continue
+		}
+		artifact.GOOS, artifact.GOARCH = platform.GOOS, platform.GOARCH
+		artifacts = append(artifacts, artifact)
+
 		logChannel <- fmt.Sprintf("✅ Built: %s", output)
-		builtFiles = append(builtFiles, output)
+	}
+
+	if _, err := updateJob(jobID, func(j *Job) { j.Artifacts = artifacts }); err != nil {
+		return fmt.Errorf("failed to save artifact index: %v", err)
 	}
 
 	// Send download links via SSE
-	if len(builtFiles) > 0 {
+	if len(artifacts) > 0 {
 		logChannel <- "🎉 All builds completed!"
 		logChannel <- "📥 **DOWNLOAD_LINKS_START**"
 
-		for _, filename := range builtFiles {
-			// Extract the output directory name for the download URL
-			outputDirName := filepath.Base(outputDir)
-			downloadURL := fmt.Sprintf("/download/%s/%s", outputDirName, filename)
-			logChannel <- fmt.Sprintf("DOWNLOAD_LINK|%s|%s", filename, downloadURL)
+		for _, artifact := range artifacts {
+			logChannel <- fmt.Sprintf("DOWNLOAD_LINK|%s|%s", artifact.Name, downloadURL(jobID, artifact.Name))
 		}
 
 		logChannel <- "📥 **DOWNLOAD_LINKS_END**"
@@ -195,23 +294,49 @@ func buildMVP(outputDir string, logChannel chan<- string) error {
 	return nil
 }
 
-func downloadMVP(c echo.Context) error {
-	outputDir := c.Param("outputDir")
-	filename := c.Param("filename")
-	fmt.Println("downloadMVP called", outputDir, filename)
+// pinJob marks a job as a favourite so the janitor keeps it
+func pinJob(c echo.Context) error {
+	return setJobPinned(c, true)
+}
 
-	// Construct the file path
-	filePath := filepath.Join("data/outputs", outputDir, "builds", filename)
+// unpinJob makes a job subject to the retention policy again
+func unpinJob(c echo.Context) error {
+	return setJobPinned(c, false)
+}
 
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return c.String(http.StatusNotFound, "File not found")
+func setJobPinned(c echo.Context, pinned bool) error {
+	id := c.Param("id")
+	if !validJobID(id) {
+		return c.String(http.StatusBadRequest, "Invalid job ID")
 	}
 
-	// Set headers for download
-	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
-	c.Response().Header().Set("Content-Type", "application/octet-stream")
+	job, err := loadJob(id)
+	if err != nil || !canAccessJob(c, job) {
+		return c.String(http.StatusNotFound, "Job not found")
+	}
 
-	// Serve the file
-	return c.File(filePath)
+	job, err = updateJob(id, func(j *Job) { j.Pinned = pinned })
+	if os.IsNotExist(err) {
+		return c.String(http.StatusNotFound, "Job not found")
+	}
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, job)
+}
+
+// listMyJobs lists the jobs owned by the caller
+func listMyJobs(c echo.Context) error {
+	jobs, err := listJobs()
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	mine := []*Job{}
+	for _, job := range jobs {
+		if job.Owner == currentIdentity(c).User || (!cfg.Auth.Enabled && job.Owner == "") {
+			mine = append(mine, job)
+		}
+	}
+	return c.JSON(http.StatusOK, mine)
 }
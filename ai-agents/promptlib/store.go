@@ -0,0 +1,339 @@
+// Package promptlib loads versioned agent prompts from files.
+//
+// A prompt directory holds one sub-directory per prompt name, with one Go
+// template per version:
+//
+//	prompts/
+//	  prompts.yaml          optional, default versions and A/B weights
+//	  mvp_agent/
+//	    v1.tmpl
+//	    v2.tmpl
+//
+// prompts.yaml looks like:
+//
+//	mvp_agent:
+//	  default: v1
+//	  weights: {v1: 50, v2: 50}
+//
+// Without a manifest entry the highest version is the default. Templates
+// are executed with missingkey=error against a typed variables struct, so a
+// template referring to an unknown variable fails at startup in Validate.
+package promptlib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	manifestFile = "prompts.yaml"
+	templateExt  = ".tmpl"
+	// reloadCheckInterval limits how often dev mode looks for changed files
+	reloadCheckInterval = time.Second
+)
+
+// Options configures a Store.
+type Options struct {
+	// Dev reloads the prompts when a file in the directory changes.
+	Dev bool
+}
+
+// Prompt is one version of a named prompt.
+type Prompt struct {
+	Name    string
+	Version string
+	Path    string
+
+	tmpl *template.Template
+}
+
+// ID identifies the prompt version, e.g. "mvp_agent@v2". Record it with every job or session.
+func (p *Prompt) ID() string {
+	return p.Name + "@" + p.Version
+}
+
+// Render executes the template with the typed variables.
+func (p *Prompt) Render(vars any) (string, error) {
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %v", p.ID(), err)
+	}
+	return buf.String(), nil
+}
+
+// manifestEntry is the prompts.yaml entry of one prompt
+type manifestEntry struct {
+	Default string         `yaml:"default"`
+	Weights map[string]int `yaml:"weights"`
+}
+
+// promptSet holds every version of one prompt
+type promptSet struct {
+	versions map[string]*Prompt
+	// order lists the versions from lowest to highest
+	order    []string
+	defaultV string
+	weights  map[string]int
+}
+
+// Store holds the prompts loaded from a directory.
+type Store struct {
+	dir  string
+	opts Options
+
+	mu          sync.RWMutex
+	sets        map[string]*promptSet
+	fingerprint string
+	lastCheck   time.Time
+}
+
+// Open loads every prompt in dir.
+func Open(dir string, opts Options) (*Store, error) {
+	s := &Store{dir: dir, opts: opts}
+	sets, fp, err := load(dir)
+	if err != nil {
+		return nil, err
+	}
+	s.sets, s.fingerprint, s.lastCheck = sets, fp, time.Now()
+	return s, nil
+}
+
+// Get returns a specific version of a prompt.
+func (s *Store) Get(name, version string) (*Prompt, error) {
+	s.maybeReload()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set, ok := s.sets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown prompt %q in %s", name, s.dir)
+	}
+	p, ok := set.versions[version]
+	if !ok {
+		return nil, fmt.Errorf("unknown version %q of prompt %q (have %s)", version, name, strings.Join(set.order, ", "))
+	}
+	return p, nil
+}
+
+// Select returns the version of a prompt to use for key. With A/B weights
+// the choice is a stable hash of key, so the same user or job always gets
+// the same version; otherwise it is the default version.
+func (s *Store) Select(name, key string) (*Prompt, error) {
+	s.maybeReload()
+	s.mu.RLock()
+	set, ok := s.sets[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown prompt %q in %s", name, s.dir)
+	}
+
+	version := set.defaultV
+	if len(set.weights) > 0 {
+		version = pickWeighted(set, key)
+	}
+	return s.Get(name, version)
+}
+
+// Validate renders every version of a prompt with vars, normally the zero
+// value of the variables struct, so template errors surface at startup.
+func (s *Store) Validate(name string, vars any) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set, ok := s.sets[name]
+	if !ok {
+		return fmt.Errorf("unknown prompt %q in %s", name, s.dir)
+	}
+	var errs []error
+	for _, v := range set.order {
+		if _, err := set.versions[v].Render(vars); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pickWeighted deterministically picks a version for key
+func pickWeighted(set *promptSet, key string) string {
+	total := 0
+	for _, v := range set.order {
+		total += set.weights[v]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	n := int(h.Sum32() % uint32(total))
+	for _, v := range set.order {
+		if n < set.weights[v] {
+			return v
+		}
+		n -= set.weights[v]
+	}
+	return set.defaultV
+}
+
+// maybeReload reloads the prompts in dev mode when a file changed.
+// A broken edit is logged and the previous prompts stay in use.
+func (s *Store) maybeReload() {
+	if !s.opts.Dev {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.lastCheck) < reloadCheckInterval {
+		return
+	}
+	s.lastCheck = time.Now()
+
+	fp, err := fingerprint(s.dir)
+	if err != nil || fp == s.fingerprint {
+		return
+	}
+	sets, fp, err := load(s.dir)
+	if err != nil {
+		log.Printf("promptlib: keeping previous prompts, reload failed: %v", err)
+		s.fingerprint = fp
+		return
+	}
+	s.sets, s.fingerprint = sets, fp
+	log.Printf("promptlib: reloaded prompts from %s", s.dir)
+}
+
+// load reads all prompts and the manifest from dir
+func load(dir string) (map[string]*promptSet, string, error) {
+	fp, err := fingerprint(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read prompt directory: %v", err)
+	}
+
+	manifest := map[string]manifestEntry{}
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fp, fmt.Errorf("failed to read %s: %v", manifestFile, err)
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, &manifest); err != nil {
+			return nil, fp, fmt.Errorf("failed to parse %s: %v", manifestFile, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fp, fmt.Errorf("failed to read prompt directory: %v", err)
+	}
+
+	sets := make(map[string]*promptSet)
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		set, err := loadSet(filepath.Join(dir, name), name, manifest[name])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sets[name] = set
+	}
+	for name := range manifest {
+		if _, ok := sets[name]; !ok {
+			errs = append(errs, fmt.Errorf("%s: prompt %q has no templates", manifestFile, name))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fp, errors.Join(errs...)
+	}
+	return sets, fp, nil
+}
+
+// loadSet parses the versions of one prompt
+func loadSet(dir, name string, entry manifestEntry) (*promptSet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+templateExt))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("prompt %q: no %s files in %s", name, templateExt, dir)
+	}
+
+	set := &promptSet{versions: make(map[string]*Prompt)}
+	for _, file := range files {
+		version := strings.TrimSuffix(filepath.Base(file), templateExt)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("prompt %s@%s: %v", name, version, err)
+		}
+		tmpl, err := template.New(name + "@" + version).Option("missingkey=error").Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("prompt %s@%s: %v", name, version, err)
+		}
+		set.versions[version] = &Prompt{Name: name, Version: version, Path: file, tmpl: tmpl}
+		set.order = append(set.order, version)
+	}
+	sort.Slice(set.order, func(i, j int) bool { return versionLess(set.order[i], set.order[j]) })
+
+	set.defaultV = set.order[len(set.order)-1]
+	if entry.Default != "" {
+		if _, ok := set.versions[entry.Default]; !ok {
+			return nil, fmt.Errorf("%s: default version %q of prompt %q does not exist", manifestFile, entry.Default, name)
+		}
+		set.defaultV = entry.Default
+	}
+
+	total := 0
+	for v, w := range entry.Weights {
+		if _, ok := set.versions[v]; !ok {
+			return nil, fmt.Errorf("%s: weighted version %q of prompt %q does not exist", manifestFile, v, name)
+		}
+		if w < 0 {
+			return nil, fmt.Errorf("%s: weight of %s@%s must not be negative", manifestFile, name, v)
+		}
+		total += w
+	}
+	if total > 0 {
+		set.weights = entry.Weights
+	}
+	return set, nil
+}
+
+// versionLess orders versions like v2 before v10, falling back to string order
+func versionLess(a, b string) bool {
+	na, errA := strconv.Atoi(strings.TrimPrefix(a, "v"))
+	nb, errB := strconv.Atoi(strings.TrimPrefix(b, "v"))
+	if errA == nil && errB == nil && na != nb {
+		return na < nb
+	}
+	return a < b
+}
+
+// fingerprint summarises the names, sizes and modification times under dir
+func fingerprint(dir string) (string, error) {
+	var b strings.Builder
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s|%d|%d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return b.String(), err
+}
@@ -2,10 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
+
+	"agentauth"
+	"promptlib"
 
 	adkagent "google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
@@ -20,6 +26,17 @@ import (
 func main() {
 	ctx := context.Background()
 
+	// Authentication and per-user rate limits
+	authConfig, err := agentauth.FromEnv("UI_AGENT_")
+	if err != nil {
+		log.Fatalf("Invalid auth configuration: %v", err)
+	}
+	authenticator, err := agentauth.New(authConfig)
+	if err != nil {
+		log.Fatalf("Invalid auth configuration: %v", err)
+	}
+	generateLimiter := agentauth.NewRateLimiter(authConfig.RequestsPerMinute)
+
 	// Create model
 	model, err := gemini.NewModel(ctx, "gemini-2.5-flash", &genai.ClientConfig{
 		APIKey: os.Getenv("GOOGLE_API_KEY"),
@@ -28,10 +45,22 @@ func main() {
 		log.Fatalf("Failed to create model: %v", err)
 	}
 
+	// Load the versioned agent instructions
+	promptStore, err := promptlib.Open(envOr("UI_AGENT_PROMPTS_DIR", "prompts"), promptlib.Options{Dev: os.Getenv("UI_AGENT_PROMPTS_DEV") == "true"})
+	if err != nil {
+		log.Fatalf("Failed to load prompts: %v", err)
+	}
+	if err := promptStore.Validate(uiPromptName, UIPromptVars{}); err != nil {
+		log.Fatalf("Invalid prompts: %v", err)
+	}
+
+	// Every request is stored as its own record
+	records := NewRecordStore(envOr("UI_AGENT_COMPONENTS_DIR", "components"))
+
 	// Create custom tool
 	customTool, err := functiontool.New(functiontool.Config{
 		Name:        "AddVariants",
-		Description: "Generate three different component variants using HTML and Tailwind CSS and write them to demo.html",
+		Description: "Store the variants of the requested component, each with a name, a description, its HTML with Tailwind CSS classes and the optional Alpine or HTMX behaviour it uses. Each variant is shown on its own.",
 	}, AddVariants)
 	if err != nil {
 		log.Fatalf("Failed to create custom tool: %v", err)
@@ -42,10 +71,18 @@ func main() {
 		Name:        "ui_component_agent",
 		Model:       model,
 		Description: "Generate tailwind components with three variants",
-		Instruction: `You MUST use the AddVariants tool for every component request. 
-		When the user describes a component, you MUST FIRST GENERATE three distinct variants of the component using **HTML and Tailwind CSS classes**, each variant separated by a clear HTML comment (e.g., ).
-		Then, you MUST call AddVariants with the ENTIRE GENERATED HTML for the three variants as the componentDescription parameter.
-		Never generate HTML outside of the tool call argument.`,
+		// The instruction is rendered per session from the prompt version stored in its state
+		InstructionProvider: func(ctx adkagent.ReadonlyContext) (string, error) {
+			version, err := ctx.ReadonlyState().Get(promptVersionKey)
+			if err != nil {
+				return "", fmt.Errorf("session has no prompt version: %v", err)
+			}
+			prompt, err := promptStore.Get(uiPromptName, strings.TrimPrefix(fmt.Sprint(version), uiPromptName+"@"))
+			if err != nil {
+				return "", err
+			}
+			return prompt.Render(UIPromptVars{VariantCount: variantCount})
+		},
 		Tools: []tool.Tool{customTool},
 	})
 	if err != nil {
@@ -67,16 +104,204 @@ func main() {
 
 	// Serve index.html on root path
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		email := r.Header.Get("X-Forwarded-Email")
-		user := r.Header.Get("X-Forwarded-User")
+		id, _ := agentauth.FromContext(r.Context())
 
-		log.Printf("Page access - User: %s, Email: %s", user, email)
+		log.Printf("Page access - User: %s, Email: %s, Method: %s", id.User, id.Email, id.Method)
 
 		http.ServeFile(w, r, "index.html")
 	})
 
+	// generate runs the agent for one request of a user and stores its variants
+	// as a new record. parent and parentVariant are set when the request asks
+	// for more variants like an earlier one.
+	generate := func(userID, input string, parent *Record, parentVariant int) (*Record, error) {
+		// Create a session for this request of the user, its ID is the request ID
+		requestID, err := newRecordID()
+		if err != nil {
+			return nil, err
+		}
+		appName := "ui_component_agent"
+		prompt, err := promptStore.Select(uiPromptName, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select prompt: %v", err)
+		}
+		sessResp, err := sessionService.Create(ctx, &session.CreateRequest{
+			AppName:   appName,
+			UserID:    userID,
+			SessionID: requestID,
+			State: map[string]any{
+				promptVersionKey: prompt.ID(),
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create session: %v", err)
+		}
+
+		// Run agent
+		msg := &genai.Content{
+			Role: "user",
+			Parts: []*genai.Part{
+				{Text: input},
+			},
+		}
+		fmt.Printf("Running request %s with prompt %s and input: %s\n", requestID, prompt.ID(), input)
+		events := agentRunner.Run(ctx, userID, sessResp.Session.ID(), msg, adkagent.RunConfig{})
+
+		// Process events and log any errors
+		for _, err := range events {
+			if err != nil {
+				fmt.Printf("ERROR in event stream: %v\n", err)
+				log.Printf("Error in event stream: %v", err)
+				continue
+			}
+
+		}
+
+		// The tool leaves the variants in the session state
+		finished, err := sessionService.Get(ctx, &session.GetRequest{AppName: appName, UserID: userID, SessionID: requestID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session: %v", err)
+		}
+		value, _ := finished.Session.State().Get(variantsKey)
+		variants, ok := value.([]Variant)
+		if !ok || len(variants) == 0 {
+			return nil, errNoVariants
+		}
+
+		record := &Record{
+			ID:            requestID,
+			User:          userID,
+			Input:         input,
+			PromptVersion: prompt.ID(),
+			Variants:      variants,
+			Created:       time.Now().UTC(),
+		}
+		if parent != nil {
+			// Keep the original request so later requests build on it
+			record.Input = parent.Input
+			record.Parent, record.ParentVariant = parent.ID, parentVariant
+		}
+		if err := records.Save(record); err != nil {
+			return nil, err
+		}
+		fmt.Printf("Request %s stored with %d variants.\n", requestID, len(variants))
+		return record, nil
+	}
+
+	// writeVariants responds with the variants of a record, as the whole page
+	// unless htmx asked for them
+	writeVariants := func(w http.ResponseWriter, r *http.Request, record *Record) {
+		content, err := renderVariants(record)
+		if err == nil && r.Header.Get("HX-Request") != "true" {
+			content, err = renderPage(content)
+		}
+		if err != nil {
+			fmt.Printf("Error rendering variants: %v\n", err)
+			http.Error(w, "Error rendering variants", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("X-Request-ID", record.ID)
+		w.Write([]byte(content))
+	}
+
+	// generateError responds to a failed generation
+	generateError := func(w http.ResponseWriter, err error) {
+		fmt.Printf("Error generating variants: %v\n", err)
+		if errors.Is(err, errNoVariants) {
+			http.Error(w, "The agent did not generate any variants, try describing the component again", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, "Failed to generate variants", http.StatusInternalServerError)
+	}
+
+	// loadRecord reads the record of the URL for the authenticated user
+	loadRecord := func(w http.ResponseWriter, r *http.Request) (*Record, bool) {
+		id, _ := agentauth.FromContext(r.Context())
+		record, err := records.Get(id.User, r.PathValue("id"))
+		if errors.Is(err, os.ErrNotExist) {
+			http.NotFound(w, r)
+			return nil, false
+		}
+		if err != nil {
+			fmt.Printf("Error reading record: %v\n", err)
+			http.Error(w, "Error reading generated HTML", http.StatusInternalServerError)
+			return nil, false
+		}
+		return record, true
+	}
+
+	// loadVariant reads the record and the variant number of the URL
+	loadVariant := func(w http.ResponseWriter, r *http.Request) (*Record, int, bool) {
+		record, ok := loadRecord(w, r)
+		if !ok {
+			return nil, 0, false
+		}
+		n, ok := record.variant(r.PathValue("n"))
+		if !ok {
+			http.NotFound(w, r)
+			return nil, 0, false
+		}
+		return record, n, true
+	}
+
+	// Reopen the variants of an earlier request
+	http.HandleFunc("GET /components/{id}", func(w http.ResponseWriter, r *http.Request) {
+		record, ok := loadRecord(w, r)
+		if !ok {
+			return
+		}
+		writeVariants(w, r, record)
+	})
+
+	// The HTML of a variant, for copying
+	http.HandleFunc("GET /components/{id}/variants/{n}", func(w http.ResponseWriter, r *http.Request) {
+		record, n, ok := loadVariant(w, r)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(record.Variants[n-1].HTML))
+	})
+
+	// Pick a variant of a request
+	http.HandleFunc("POST /components/{id}/variants/{n}/select", func(w http.ResponseWriter, r *http.Request) {
+		record, n, ok := loadVariant(w, r)
+		if !ok {
+			return
+		}
+		record.Selected = n
+		if err := records.Save(record); err != nil {
+			fmt.Printf("Error saving record: %v\n", err)
+			http.Error(w, "Error saving the picked variant", http.StatusInternalServerError)
+			return
+		}
+		fmt.Printf("Request %s: picked variant %d\n", record.ID, n)
+		writeVariants(w, r, record)
+	})
+
+	// Ask for more variants based on a variant, as a new request
+	http.Handle("POST /components/{id}/variants/{n}/more", generateLimiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record, n, ok := loadVariant(w, r)
+		if !ok {
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+
+		more, err := generate(record.User, moreVariantsInput(record, n, r.FormValue("user_input")), record, n)
+		if err != nil {
+			generateError(w, err)
+			return
+		}
+		w.Header().Set("HX-Push-Url", more.URL())
+		writeVariants(w, r, more)
+	})))
+
 	// Handle component generation
-	http.HandleFunc("/generate-component", func(w http.ResponseWriter, r *http.Request) {
+	http.Handle("/generate-component", generateLimiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Enable CORS
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
@@ -105,81 +330,66 @@ func main() {
 			return
 		}
 
-		// Create session
-		userID := "user123"
-		appName := "ui_component_agent"
-		sessResp, err := sessionService.Create(ctx, &session.CreateRequest{
-			AppName: appName,
-			UserID:  userID,
-		})
+		id, _ := agentauth.FromContext(r.Context())
+		record, err := generate(id.User, input, nil, 0)
 		if err != nil {
-			fmt.Printf("Error creating session: %v\n", err)
-			http.Error(w, "Failed to create session", http.StatusInternalServerError)
+			generateError(w, err)
 			return
 		}
 
-		// Run agent
-		msg := &genai.Content{
-			Role: "user",
-			Parts: []*genai.Part{
-				{Text: input},
-			},
-		}
-		fmt.Printf("Running agent with input: %s\n", input)
-		events := agentRunner.Run(ctx, userID, sessResp.Session.ID(), msg, adkagent.RunConfig{})
+		// Return the variants with the URL to reopen them, htmx puts it in the address bar
+		w.Header().Set("HX-Push-Url", record.URL())
+		writeVariants(w, r, record)
+	})))
 
-		// Process events and log any errors
-		for _, err := range events {
-			if err != nil {
-				fmt.Printf("ERROR in event stream: %v\n", err)
-				log.Printf("Error in event stream: %v", err)
-				continue
-			}
+	fmt.Println("Open http://localhost:8000 in your browser")
+	log.Fatal(http.ListenAndServe(":8000", authenticator.Middleware(http.DefaultServeMux)))
+}
 
-		}
+// uiPromptName is the prompt in the prompt store used as the agent instruction
+const uiPromptName = "ui_component_agent"
 
-		// Read the updated demo.html and return it
-		htmlContent, err := os.ReadFile("demo.html")
-		if err != nil {
-			fmt.Printf("Error reading demo.html: %v\n", err)
-			http.Error(w, "Error reading generated HTML", http.StatusInternalServerError)
-			return
-		}
+// promptVersionKey is the session state key recording the prompt version
+const promptVersionKey = "prompt_version"
 
-		fmt.Println("HTML Content successfully read, sending response.")
+// variantsKey is the session state key the AddVariants tool stores the variants in
+const variantsKey = "variants"
 
-		// Set content type to HTML
-		w.Header().Set("Content-Type", "text/html")
-		w.Write(htmlContent)
-	})
+// errNoVariants is returned when the agent did not call AddVariants
+var errNoVariants = errors.New("the agent did not generate any variants")
 
-	fmt.Println("Open http://localhost:8000 in your browser")
-	log.Fatal(http.ListenAndServe(":8000", nil))
-}
+// variantCount is the number of variants generated per request
+const variantCount = 3
 
-type AddVariantsParams struct {
-	ComponentDescription string `json:"componentDescription" jsonschema:"A detailed description of the UI component to generate, including its purpose, style, and any specific features or behaviors required"`
+// UIPromptVars are the variables available to the ui_component_agent prompt templates
+type UIPromptVars struct {
+	VariantCount int
 }
 
-type AddVariantsResult struct {
-	Result string `json:"result"`
+// envOr returns the environment variable or a default
+func envOr(name, defaultValue string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return defaultValue
 }
 
-func AddVariants(ctx tool.Context, args AddVariantsParams) AddVariantsResult {
-	fmt.Println("AddVariants tool called with args: ", args)
-	llmContent := args.ComponentDescription
-	fmt.Println("LLM Content: ", llmContent)
+// Markers in index.html around the variants
+const (
+	variantsStart = "<!-- COMPONENT_VARIANTS_START -->"
+	variantsEnd   = "<!-- COMPONENT_VARIANTS_END -->"
+)
 
-	// Write the complete HTML to demo.html
-	err := os.WriteFile("demo.html", []byte(llmContent), 0644)
+// renderPage returns index.html showing the given variants
+func renderPage(variants string) (string, error) {
+	page, err := os.ReadFile("index.html")
 	if err != nil {
-		log.Printf("ERROR: Failed to write HTML file: %v", err)
-		return AddVariantsResult{
-			Result: "Error writing HTML file: " + err.Error(),
-		}
+		return "", err
 	}
-
-	return AddVariantsResult{
-		Result: "Successfully created demo.html with components",
+	before, rest, ok := strings.Cut(string(page), variantsStart)
+	_, after, ok2 := strings.Cut(rest, variantsEnd)
+	if !ok || !ok2 {
+		return "", fmt.Errorf("index.html has no %s and %s markers", variantsStart, variantsEnd)
 	}
+	return before + variantsStart + "\n" + variants + "\n" + variantsEnd + after, nil
 }
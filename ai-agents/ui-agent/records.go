@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Record is the result of one component request
+type Record struct {
+	ID            string    `json:"id"`
+	User          string    `json:"user"`
+	Input         string    `json:"input"`
+	PromptVersion string    `json:"promptVersion"`
+	Variants      []Variant `json:"variants"`
+	// Selected is the picked variant, counted from 1, or 0 when none is picked
+	Selected int `json:"selected,omitempty"`
+	// Parent and ParentVariant are the record and variant this one was generated from
+	Parent        string    `json:"parent,omitempty"`
+	ParentVariant int       `json:"parentVariant,omitempty"`
+	Created       time.Time `json:"created"`
+}
+
+// URL is where the record can be reopened
+func (r *Record) URL() string {
+	return "/components/" + r.ID
+}
+
+// variantURL is the URL of a variant, counted from 1
+func (r *Record) variantURL(n int) string {
+	return r.URL() + "/variants/" + strconv.Itoa(n)
+}
+
+// variant returns a variant by its number in a URL, counted from 1
+func (r *Record) variant(number string) (int, bool) {
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 || n > len(r.Variants) {
+		return 0, false
+	}
+	return n, true
+}
+
+// recordIDRe matches the IDs made by newRecordID, so an ID is always a safe file name
+var recordIDRe = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// newRecordID returns a random request ID
+func newRecordID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate request ID: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// RecordStore keeps every record in its own file under a directory per user
+type RecordStore struct {
+	dir string
+}
+
+func NewRecordStore(dir string) *RecordStore {
+	return &RecordStore{dir: dir}
+}
+
+func (s *RecordStore) path(user, id string) string {
+	// Hash the user so any identity maps to a safe directory name
+	sum := sha256.Sum256([]byte(user))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:8]), id+".json")
+}
+
+// Save writes a record
+func (s *RecordStore) Save(r *Record) error {
+	if !recordIDRe.MatchString(r.ID) {
+		return fmt.Errorf("invalid request ID %q", r.ID)
+	}
+	path := s.path(r.User, r.ID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create record directory: %v", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %v", err)
+	}
+	// Write to a temporary file first so a reader never sees half a record
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write record: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write record: %v", err)
+	}
+	return nil
+}
+
+// Get reads a record of the user. Records of other users are not found.
+func (s *RecordStore) Get(user, id string) (*Record, error) {
+	if !recordIDRe.MatchString(id) {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(s.path(user, id))
+	if err != nil {
+		return nil, err
+	}
+	var r Record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode record %s: %v", id, err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"strings"
+
+	"google.golang.org/adk/tool"
+)
+
+// Behaviours a variant can rely on, with the script its document loads
+var behaviorScripts = map[string]string{
+	"":       "",
+	"alpine": "https://cdn.jsdelivr.net/npm/alpinejs@3.14.9/dist/cdn.min.js",
+	"htmx":   "https://cdn.jsdelivr.net/npm/htmx.org@2.0.8/dist/htmx.min.js",
+}
+
+// Variant is one design of a component
+type Variant struct {
+	Name        string `json:"name" jsonschema:"A short name for the variant, e.g. Minimal or Card with icon"`
+	Description string `json:"description" jsonschema:"One or two sentences on how this variant differs from the others"`
+	HTML        string `json:"html" jsonschema:"The HTML of the variant with Tailwind CSS classes, without html, head or body tags"`
+	Behavior    string `json:"behavior,omitempty" jsonschema:"Optional library the HTML uses for interactivity: alpine for x-data attributes or htmx for hx- attributes"`
+}
+
+// validate reports what the agent has to fix in a variant
+func (v Variant) validate() error {
+	switch {
+	case strings.TrimSpace(v.Name) == "":
+		return fmt.Errorf("missing 'name'")
+	case strings.TrimSpace(v.HTML) == "":
+		return fmt.Errorf("missing 'html'")
+	}
+	if _, ok := behaviorScripts[v.Behavior]; !ok {
+		return fmt.Errorf("unknown behavior %q, use alpine, htmx or leave it out", v.Behavior)
+	}
+	return nil
+}
+
+type AddVariantsParams struct {
+	Variants []Variant `json:"variants" jsonschema:"The variants of the component, each a complete and distinct design"`
+}
+
+type AddVariantsResult struct {
+	Result string `json:"result"`
+}
+
+func AddVariants(ctx tool.Context, args AddVariantsParams) AddVariantsResult {
+	fmt.Printf("AddVariants tool called for request %s with %d variants\n", ctx.SessionID(), len(args.Variants))
+
+	if len(args.Variants) == 0 {
+		return AddVariantsResult{Result: "Error: no variants, pass every variant in 'variants'"}
+	}
+	for i, v := range args.Variants {
+		if err := v.validate(); err != nil {
+			return AddVariantsResult{Result: fmt.Sprintf("Error in variant %d: %v. Fix it and call AddVariants again with all variants.", i+1, err)}
+		}
+	}
+
+	// Each request has its own session, so the variants are kept in its state
+	if err := ctx.State().Set(variantsKey, args.Variants); err != nil {
+		log.Printf("ERROR: Failed to store variants: %v", err)
+		return AddVariantsResult{
+			Result: "Error storing variants: " + err.Error(),
+		}
+	}
+
+	return AddVariantsResult{
+		Result: "Successfully stored the variants of request " + ctx.SessionID(),
+	}
+}
+
+// variantDocument is the page a variant is rendered in, on its own in a sandboxed iframe
+var variantDocument = template.Must(template.New("variant").Parse(`<!doctype html>
+<html>
+  <head>
+    <meta charset="UTF-8" />
+    <script src="https://cdn.jsdelivr.net/npm/@tailwindcss/browser@4"></script>
+    {{- with .Script}}
+    <script defer src="{{.}}"></script>
+    {{- end}}
+  </head>
+  <body class="p-4">
+{{.HTML}}
+  </body>
+</html>`))
+
+// document returns the standalone page of a variant
+func (v Variant) document() (string, error) {
+	var b bytes.Buffer
+	err := variantDocument.Execute(&b, struct {
+		Script string
+		HTML   template.HTML
+	}{behaviorScripts[v.Behavior], template.HTML(v.HTML)})
+	return b.String(), err
+}
+
+// variantsFragment shows the variants of a record side by side, each with the
+// actions to pick it, copy its HTML or ask for more variants like it
+var variantsFragment = template.Must(template.New("variants").Parse(`<div id="variants" class="grid gap-4 p-4 md:grid-cols-{{len .Variants}}">
+  {{- range .Variants}}
+  <section class="flex flex-col gap-2 rounded border p-2 {{if .Selected}}border-blue-500 ring-2 ring-blue-500{{else}}border-gray-300{{end}}">
+    <h2 class="font-semibold">{{.Number}}. {{.Name}}{{if .Selected}} (picked){{end}}</h2>
+    <p class="text-sm text-gray-600">{{.Description}}{{with .Behavior}} Uses {{.}}.{{end}}</p>
+    <iframe sandbox="allow-scripts" srcdoc="{{.Document}}" title="{{.Name}}" class="h-64 w-full rounded border border-gray-200"></iframe>
+    <div class="flex gap-2">
+      <button hx-post="{{.URL}}/select" hx-target="#llm-response" class="border border-black-100 px-2">Pick</button>
+      <button data-url="{{.URL}}" onclick="copyVariant(this)" class="border border-black-100 px-2">Copy</button>
+    </div>
+    <form hx-post="{{.URL}}/more" hx-target="#llm-response" class="flex gap-2">
+      <input name="user_input" type="text" placeholder="What to change (optional)" class="flex-1 border border-black-100 px-2">
+      <button type="submit" class="border border-black-100 px-2">More like this</button>
+    </form>
+  </section>
+  {{- end}}
+</div>`))
+
+// variantView is a variant as shown in the variants fragment
+type variantView struct {
+	Variant
+	Number   int
+	URL      string
+	Document string
+	Selected bool
+}
+
+// renderVariants returns the variants fragment of a record
+func renderVariants(r *Record) (string, error) {
+	views := make([]variantView, len(r.Variants))
+	for i, v := range r.Variants {
+		doc, err := v.document()
+		if err != nil {
+			return "", fmt.Errorf("failed to render variant %d: %v", i+1, err)
+		}
+		views[i] = variantView{Variant: v, Number: i + 1, URL: r.variantURL(i + 1), Document: doc, Selected: r.Selected == i+1}
+	}
+	var b bytes.Buffer
+	if err := variantsFragment.Execute(&b, struct{ Variants []variantView }{views}); err != nil {
+		return "", fmt.Errorf("failed to render variants: %v", err)
+	}
+	return b.String(), nil
+}
+
+// moreVariantsInput asks the agent for new variants based on a chosen one
+func moreVariantsInput(r *Record, n int, change string) string {
+	v := r.Variants[n-1]
+	var b strings.Builder
+	fmt.Fprintf(&b, "Generate %d new variants of this component based on the variant %q.\n", variantCount, v.Name)
+	fmt.Fprintf(&b, "The original request was: %s\n", r.Input)
+	if change = strings.TrimSpace(change); change != "" {
+		fmt.Fprintf(&b, "Change: %s\n", change)
+	}
+	fmt.Fprintf(&b, "Variant description: %s\n", v.Description)
+	if v.Behavior != "" {
+		fmt.Fprintf(&b, "It uses %s for interactivity.\n", v.Behavior)
+	}
+	fmt.Fprintf(&b, "Variant HTML:\n%s\n", v.HTML)
+	return b.String()
+}